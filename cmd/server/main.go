@@ -4,23 +4,41 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
+	"time"
 
 	"wa-server-go/internal/api"
 	"wa-server-go/internal/config"
+	"wa-server-go/internal/features/backup"
+	"wa-server-go/internal/features/blog"
+	"wa-server-go/internal/features/monitor"
+	"wa-server-go/internal/features/reminder"
 	"wa-server-go/internal/firestore"
+	"wa-server-go/internal/media"
+	"wa-server-go/internal/outbox"
+	"wa-server-go/internal/rules"
+	"wa-server-go/internal/search"
+	"wa-server-go/internal/templates"
+	"wa-server-go/internal/utils"
+	"wa-server-go/internal/webhook"
 	"wa-server-go/internal/whatsapp"
 )
 
+// shutdownTimeout bounds how long shutdown waits for requests, the current send and
+// pending Firestore writes (keep it below the container stop grace period)
+const shutdownTimeout = 25 * time.Second
+
 func main() {
 	fmt.Println("\n🚀 Initializing WhatsApp Server (Go)...")
 	fmt.Println("=========================================")
 	fmt.Printf("📌 Running as Go/whatsmeow (socket-based)\n")
 	fmt.Printf("📌 Session: SQLite (local)\n")
 	fmt.Printf("📌 Business Data: Firestore\n")
-	fmt.Println("=========================================\n")
+	fmt.Print("=========================================\n\n")
 
 	// Load configuration
 	cfg := config.Load()
@@ -28,6 +46,19 @@ func main() {
 	// Create context for app lifecycle
 	ctx := context.Background()
 
+	// Open local app database (outbox and other server-side state)
+	appDB, err := utils.OpenSQLite(filepath.Join(cfg.DataDir, "app.db"))
+	if err != nil {
+		log.Fatalf("Failed to open app database: %v", err)
+	}
+	defer appDB.Close()
+
+	// Full-text index of stored messages (GET /search)
+	searchIndex, err := search.NewIndex(appDB)
+	if err != nil {
+		log.Fatalf("Failed to initialize search index: %v", err)
+	}
+
 	// Initialize Firestore
 	fsClient, err := firestore.NewClient(ctx, cfg.GoogleCredentials, cfg.FirebaseProjectID)
 	if err != nil {
@@ -35,16 +66,33 @@ func main() {
 	}
 	defer fsClient.Close()
 
+	// Chat classification rules, applied to every stored message
+	var ruleStore rules.Store
+	if fsClient != nil {
+		ruleStore, err = firestore.NewRulesRepository(ctx, fsClient)
+		if err != nil {
+			log.Fatalf("Failed to initialize chat rules: %v", err)
+		}
+	} else {
+		log.Printf("⚠️ Chat rules kept in memory; changes are lost on restart")
+		ruleStore = rules.NewMemoryStore()
+	}
+	ruleEngine, err := rules.NewEngine(ruleStore)
+	if err != nil {
+		log.Fatalf("Failed to load chat rules: %v", err)
+	}
+
 	var chatsRepo *firestore.ChatsRepository
 	if fsClient != nil {
-		chatsRepo = firestore.NewChatsRepository(fsClient)
+		chatsRepo = firestore.NewChatsRepository(fsClient, ruleEngine)
+		chatsRepo.IndexMessages(searchIndex)
 	}
 
 	// Create WhatsApp manager
-	waManager := whatsapp.NewManager(chatsRepo)
+	waManager := whatsapp.NewManager(chatsRepo, cfg.DataDir, cfg.BotClientID)
 
 	// Create bot client
-	err = waManager.CreateClient(ctx, cfg.BotClientID, "session-bot.db")
+	err = waManager.CreateClient(ctx, cfg.BotClientID, waManager.SessionPath(cfg.BotClientID))
 	if err != nil {
 		log.Fatalf("Failed to create bot client: %v", err)
 	}
@@ -63,24 +111,210 @@ func main() {
 		}
 	}()
 
+	// Restore sessions created through the /sessions API (the leads client stays on-demand)
+	waManager.RestoreSessions(ctx, cfg.LeadsClientID)
+
+	// Chats flagged hasInvoice before tags existed would drop out of the invoice list
+	for _, id := range waManager.ClientIDs() {
+		repo := waManager.RepoFor(id)
+		if repo == nil {
+			continue
+		}
+		go func(id string) {
+			count, err := repo.BackfillInvoiceTags(ctx)
+			if err != nil {
+				log.Printf("⚠️ [%s] Failed to backfill invoice tags: %v", id, err)
+			} else if count > 0 {
+				log.Printf("🏷️ [%s] Tagged %d older invoice chats", id, count)
+			}
+		}(id)
+	}
+
+	// Media storage (local disk or S3-compatible bucket)
+	mediaStore, err := media.NewFromConfig(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize media storage: %v", err)
+	}
+
+	// Start outbound message queue
+	outboxStore, err := outbox.NewStore(appDB)
+	if err != nil {
+		log.Fatalf("Failed to initialize outbox: %v", err)
+	}
+	ob := outbox.New(outboxStore, waManager, mediaStore)
+	ob.Start()
+
+	// Start outbound webhook deliveries
+	webhookStore, err := webhook.NewStore(appDB)
+	if err != nil {
+		log.Fatalf("Failed to initialize webhooks: %v", err)
+	}
+	webhooks := webhook.NewDispatcher(webhookStore)
+	if err := webhooks.Start(); err != nil {
+		log.Fatalf("Failed to start webhook dispatcher: %v", err)
+	}
+
+	// Nightly backup (23:00) through the bot client
+	backupStore, err := backup.NewStore(appDB)
+	if err != nil {
+		log.Fatalf("Failed to initialize backup history: %v", err)
+	}
+	backupService := backup.NewBackupService(waManager, cfg.BotClientID, backup.Config{
+		WebURL:       cfg.WebURL,
+		BackupPhone:  cfg.BackupPhone,
+		Dir:          cfg.BackupDir,
+		KeepDaily:    cfg.BackupKeepDaily,
+		KeepWeekly:   cfg.BackupKeepWeekly,
+		Passphrase:   cfg.BackupPassphrase,
+		RestoreURL:   cfg.BackupRestoreURL,
+		RestoreToken: cfg.BackupRestoreToken,
+	}, backupStore)
+	if err := backupService.Start(); err != nil {
+		log.Fatalf("Failed to start backup scheduler: %v", err)
+	}
+
+	// Health monitor (every 5 minutes) alerting through the bot client. A bad target
+	// list disables the monitor instead of keeping the rest of the server from starting.
+	monitorStore, err := monitor.NewStore(appDB)
+	if err != nil {
+		log.Fatalf("Failed to initialize monitor history: %v", err)
+	}
+	monitorService, err := newMonitorService(cfg, waManager, monitorStore)
+	if err != nil {
+		log.Printf("⚠️ Health monitor disabled: %v", err)
+	}
+
+	// Blog automation (LLM article, Pexels cover, Contentful draft), only when the credentials are set
+	var blogService *blog.BlogService
+	if cfg.BlogConfigured() {
+		blogStore, err := blog.NewStore(appDB)
+		if err != nil {
+			log.Fatalf("Failed to initialize blog history: %v", err)
+		}
+		var images blog.ImageSearcher
+		if cfg.PexelsAPIKey != "" {
+			images = blog.NewPexelsClient("", cfg.PexelsAPIKey)
+		}
+		blogService = blog.NewBlogService(waManager, cfg.BotClientID, blog.Config{
+			Topics:        cfg.BlogTopics,
+			Schedule:      cfg.BlogSchedule,
+			AdminPhone:    cfg.BlogAdminPhone,
+			Language:      cfg.BlogLanguage,
+			ReviewTimeout: cfg.BlogReviewTimeout,
+			PostURL:       cfg.BlogPostURL,
+		},
+			blog.NewChatClient(cfg.BlogLLMURL, cfg.GroqAPIKey, cfg.BlogLLMModel),
+			images,
+			blog.NewContentfulClient("", cfg.ContentfulManagementToken, cfg.ContentfulSpaceID, cfg.ContentfulEnvironment, cfg.ContentfulContentType, cfg.ContentfulLocale),
+			blogStore)
+		if err := blogService.Start(); err != nil {
+			log.Fatalf("Failed to start blog automation: %v", err)
+		}
+	} else {
+		log.Println("⚠️ [BLOG] GROQ_API_KEY / CONTENTFUL_* not set: blog automation disabled")
+	}
+
+	// Message templates (built-in defaults until edited through /templates)
+	templateStore, err := templates.NewStore(appDB)
+	if err != nil {
+		log.Fatalf("Failed to initialize templates: %v", err)
+	}
+	templateService := templates.NewService(templateStore, templates.Company{
+		Name:    cfg.CompanyName,
+		Website: cfg.CompanyWebsite,
+		Email:   cfg.CompanyEmail,
+		Phone:   cfg.CompanyPhone,
+	})
+
+	// Invoice reminders (due tomorrow, then escalating while overdue) queued through the outbox
+	reminderStore, err := reminder.NewStore(appDB)
+	if err != nil {
+		log.Fatalf("Failed to initialize reminder ledger: %v", err)
+	}
+	reminderService := reminder.NewReminderService(ob, templateService, cfg.BotClientID, reminder.Config{
+		SourceURL:   cfg.ReminderSourceURL,
+		SourceToken: cfg.ReminderSourceToken,
+		Schedule:    cfg.ReminderSchedule,
+		OverdueDays: cfg.ReminderOverdueDays,
+		QuietStart:  cfg.ReminderQuietStart,
+		QuietEnd:    cfg.ReminderQuietEnd,
+		Locale:      cfg.ReminderLocale,
+	}, reminderStore)
+	if err := reminderService.Start(); err != nil {
+		log.Fatalf("Failed to start reminders: %v", err)
+	}
 
 	// Create and start HTTP server
-	server := api.NewServer(cfg, waManager, chatsRepo)
+	server := api.NewServer(cfg, waManager, chatsRepo, ob, webhooks, backupService, monitorService, blogService, templateService, reminderService, ruleEngine, searchIndex, mediaStore)
 
-	// Handle graceful shutdown
+	// Start server
 	go func() {
-		sigChan := make(chan os.Signal, 1)
-		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-		<-sigChan
-
-		fmt.Println("\n⚠️ Shutdown signal received...")
-		waManager.Close()
-		fmt.Println("✅ Cleanup complete. Goodbye!")
-		os.Exit(0)
+		if err := server.Start(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Failed to start server: %v", err)
+		}
 	}()
 
-	// Start server
-	if err := server.Start(); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	// Wait for shutdown signal
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	<-sigChan
+
+	fmt.Println("\n⚠️ Shutdown signal received...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	// 1. Stop accepting requests (no new sends get queued)
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("⚠️ HTTP server shutdown: %v", err)
+	}
+	// 2. Let the send in progress and a running backup finish; queued jobs stay in app.db for the next start
+	if monitorService != nil {
+		if err := monitorService.Stop(shutdownCtx); err != nil {
+			log.Printf("⚠️ Monitor shutdown: %v", err)
+		}
+	}
+	if err := backupService.Stop(shutdownCtx); err != nil {
+		log.Printf("⚠️ Backup shutdown: %v", err)
+	}
+	if blogService != nil {
+		if err := blogService.Stop(shutdownCtx); err != nil {
+			log.Printf("⚠️ Blog shutdown: %v", err)
+		}
+	}
+	if err := reminderService.Stop(shutdownCtx); err != nil {
+		log.Printf("⚠️ Reminder shutdown: %v", err)
+	}
+	if err := ob.Stop(shutdownCtx); err != nil {
+		log.Printf("⚠️ Outbox shutdown: %v", err)
+	}
+	// 3. Disconnect WhatsApp and wait for pending Firestore writes
+	if err := waManager.Close(shutdownCtx); err != nil {
+		log.Printf("⚠️ WhatsApp manager shutdown: %v", err)
+	}
+	// 4. Finish the webhook delivery in progress; the rest is retried after the next start
+	if err := webhooks.Stop(shutdownCtx); err != nil {
+		log.Printf("⚠️ Webhook dispatcher shutdown: %v", err)
+	}
+	fmt.Println("✅ Cleanup complete. Goodbye!")
+}
+
+// newMonitorService loads the monitor targets (MONITOR_TARGETS_FILE or the defaults) and starts the monitor
+func newMonitorService(cfg *config.Config, waManager *whatsapp.Manager, store *monitor.Store) (*monitor.MonitorService, error) {
+	targets := monitor.DefaultTargets(cfg.WebURL)
+	if cfg.MonitorTargetsFile != "" {
+		var err error
+		targets, err = monitor.LoadTargets(cfg.MonitorTargetsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load monitor targets: %w", err)
+		}
+	}
+
+	service, err := monitor.NewMonitorService(waManager, cfg.BotClientID, targets, cfg.MonitorAlertPhone, store)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize monitor: %w", err)
+	}
+	if err := service.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start monitor: %w", err)
 	}
+	return service, nil
 }
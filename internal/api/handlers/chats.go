@@ -2,8 +2,21 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"wa-server-go/internal/firestore"
+	"wa-server-go/internal/media"
+	"wa-server-go/internal/rules"
+	"wa-server-go/internal/whatsapp"
 
 	"github.com/gin-gonic/gin"
 	"go.mau.fi/whatsmeow"
@@ -12,7 +25,12 @@ import (
 
 // GetChats handles GET /get-chats
 func (h *Handler) GetChats(c *gin.Context) {
-	if h.Repo == nil {
+	session, ok := h.resolveSession(c, "")
+	if !ok {
+		return
+	}
+	repo := h.WAManager.RepoFor(session)
+	if repo == nil {
 		c.JSON(http.StatusServiceUnavailable, gin.H{
 			"success": false,
 			"error":   "Chat storage (Firestore) is not configured",
@@ -20,7 +38,24 @@ func (h *Handler) GetChats(c *gin.Context) {
 		return
 	}
 
-	chats, err := h.Repo.GetRecentChats(c.Request.Context(), 50)
+	page, ok := pageFromQuery(c)
+	if !ok {
+		return
+	}
+
+	// ?tag= lists the chats with that tag (including OTP chats for "otp")
+	var chats []firestore.WAChat
+	var err error
+	if tag := c.Query("tag"); tag != "" {
+		tag, err = rules.NormalizeTag(tag)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+		chats, err = repo.GetChatsByTag(c.Request.Context(), tag, page)
+	} else {
+		chats, err = repo.GetRecentChats(c.Request.Context(), page)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -34,7 +69,7 @@ func (h *Handler) GetChats(c *gin.Context) {
 	mappedChats := make([]map[string]interface{}, 0)
 
 	// Fetch profile pics for those missing them (Async)
-	botClient, _ := h.WAManager.GetClient("bot")
+	botClient, _ := h.WAManager.GetClient(session)
 	canFetch := botClient != nil && botClient.IsReady()
 
 	for _, chat := range chats {
@@ -56,7 +91,7 @@ func (h *Handler) GetChats(c *gin.Context) {
 				if err == nil && pic != nil && pic.URL != "" {
 					fmt.Printf("📸 Profile pic fetched for %s\n", jidStr)
 					// Update DB so next fetch has it
-					_ = h.Repo.UpdateChatProfilePic(context.Background(), jidStr, pic.URL)
+					_ = repo.UpdateChatProfilePic(context.Background(), jidStr, pic.URL)
 					
 					// Broadcast update to frontend for instant display
 					if h.WSHub != nil {
@@ -110,6 +145,8 @@ func (h *Handler) GetChats(c *gin.Context) {
 			"number":        chat.Number,
 			"unreadCount":   chat.UnreadCount,
 			"profilePicUrl": profilePic,
+			"tags":          chatTags(chat),
+			"muted":         chat.Muted,
 			"timestamp":     chat.LastMessageAt.Unix(),
 			"lastMessage": map[string]interface{}{
 				"body":      chat.LastMessageBody,
@@ -120,15 +157,21 @@ func (h *Handler) GetChats(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"chats":   mappedChats,
-		"total":   len(mappedChats),
+		"success":    true,
+		"chats":      mappedChats,
+		"total":      len(mappedChats),
+		"nextCursor": nextCursor(page, chats, firestore.ChatCursor),
 	})
 }
 
 // GetMessages handles GET /get-messages/:chatId
 func (h *Handler) GetMessages(c *gin.Context) {
-	if h.Repo == nil {
+	session, ok := h.resolveSession(c, "")
+	if !ok {
+		return
+	}
+	repo := h.WAManager.RepoFor(session)
+	if repo == nil {
 		c.JSON(http.StatusServiceUnavailable, gin.H{
 			"success": false,
 			"error":   "Chat storage (Firestore) is not configured",
@@ -136,8 +179,13 @@ func (h *Handler) GetMessages(c *gin.Context) {
 		return
 	}
 
+	page, ok := pageFromQuery(c)
+	if !ok {
+		return
+	}
+
 	chatId := c.Param("chatId")
-	messages, err := h.Repo.GetChatMessages(c.Request.Context(), chatId, 50)
+	messages, err := repo.GetChatMessages(c.Request.Context(), chatId, page)
 	if err != nil {
 		fmt.Printf("❌ Failed to fetch messages for %s: %v\n", chatId, err)
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -159,28 +207,223 @@ func (h *Handler) GetMessages(c *gin.Context) {
 			"type":      msg.Type,
 			"ack":       msg.Ack,
 			"hasMedia":  msg.HasMedia,
-			"mediaUrl":  msg.MediaURL,
+			"mediaUrl":  h.mediaURL(&msg, session),
 		})
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"success":  true,
-		"messages": mappedMessages,
+		"success":    true,
+		"messages":   mappedMessages,
+		"nextCursor": nextCursor(page, messages, firestore.MessageCursor),
 	})
 }
 
 // GetMedia handles GET /get-media/:messageId
+// Redirects to a signed, expiring URL for the attachment of a message. On first access the
+// attachment is downloaded and decrypted from WhatsApp and put in the media store.
 func (h *Handler) GetMedia(c *gin.Context) {
-	// ... (Media download logic needs whatsmeow helper not just firestore)
-	c.JSON(http.StatusNotImplemented, gin.H{
-		"success": false,
-		"error":   "Media download is being implemented",
-	})
+	messageID := c.Param("messageId")
+	if strings.ContainsAny(messageID, "/\\.") {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid message ID"})
+		return
+	}
+
+	session, ok := h.resolveSession(c, "")
+	if !ok {
+		return
+	}
+	repo := h.WAManager.RepoFor(session)
+	if repo == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"error":   "Chat storage (Firestore) is not configured",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	msg, err := repo.GetMessage(ctx, messageID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to fetch message",
+			"details": err.Error(),
+		})
+		return
+	}
+	if msg == nil || !msg.HasMedia {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Media not found"})
+		return
+	}
+
+	key := storedMediaKey(msg)
+	stored := false
+	if key != "" {
+		stored, err = h.Media.Exists(ctx, key)
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{
+				"success": false,
+				"error":   "Failed to reach media storage",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
+	if !stored {
+		if msg.MediaDirectPath == "" {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Media not found"})
+			return
+		}
+
+		client, ok := h.WAManager.GetClient(session)
+		if !ok || !client.IsReady() {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"success": false,
+				"error":   fmt.Sprintf("WhatsApp session %s is not ready", session),
+			})
+			return
+		}
+
+		info := mediaInfoFromMessage(msg)
+		fmt.Printf("🎬 Start downloading media for msg %s\n", messageID)
+		data, err := client.DownloadMedia(ctx, info)
+		if err != nil {
+			fmt.Printf("❌ Error downloading media %s: %v\n", messageID, err)
+			c.JSON(http.StatusBadGateway, gin.H{
+				"success": false,
+				"error":   "Failed to download media from WhatsApp",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		key = media.MessageKey(messageID, info.Extension())
+		if err := h.Media.Put(ctx, key, data, info.ContentType()); err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{
+				"success": false,
+				"error":   "Failed to store media",
+				"details": err.Error(),
+			})
+			return
+		}
+		if err := repo.SetMessageMediaPath(ctx, messageID, key); err != nil {
+			fmt.Printf("⚠️ Failed to record media path for %s: %v\n", messageID, err)
+		}
+		fmt.Printf("✅ Media downloaded successfully for %s\n", messageID)
+	}
+
+	url, err := h.Media.SignedURL(key, h.MediaURLTTL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to sign media URL",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusFound, url)
+}
+
+// ServeMedia handles GET /media/*key for the local media store.
+// Access is granted by the URL signature, so no API key is required.
+func (h *Handler) ServeMedia(c *gin.Context) {
+	local, ok := h.Media.(*media.LocalStore)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Not found"})
+		return
+	}
+
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	if !local.Verify(key, c.Query("expires"), c.Query("sig")) {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "error": "Invalid or expired media link"})
+		return
+	}
+
+	path, err := local.Path(key)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid media key"})
+		return
+	}
+	if _, err := os.Stat(path); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Media not found"})
+		return
+	}
+
+	// Links are public, so only media is shown inline; anything else is downloaded
+	// and browsers must not guess a type that could run script
+	ct := mime.TypeByExtension(filepath.Ext(path))
+	if ct == "" {
+		ct = "application/octet-stream"
+	}
+	c.Header("Content-Type", ct)
+	c.Header("X-Content-Type-Options", "nosniff")
+	if !isInlineMedia(ct) {
+		c.Header("Content-Disposition", "attachment; filename="+strconv.Quote(filepath.Base(path)))
+	}
+	c.Header("Cache-Control", "private, max-age=300")
+	c.File(path)
+}
+
+// isInlineMedia reports whether a content type may be displayed by the browser.
+// SVG is an image that can carry script, so it is always downloaded.
+func isInlineMedia(contentType string) bool {
+	if strings.HasPrefix(contentType, "image/svg") {
+		return false
+	}
+	return strings.HasPrefix(contentType, "image/") || strings.HasPrefix(contentType, "audio/") ||
+		strings.HasPrefix(contentType, "video/")
+}
+
+// mediaURL returns the URL the frontend should use for a message attachment:
+// a signed URL when the file is already stored, otherwise the lazy /get-media link
+func (h *Handler) mediaURL(msg *firestore.WAMessage, session string) string {
+	if !msg.HasMedia {
+		return ""
+	}
+	if key := storedMediaKey(msg); key != "" {
+		if url, err := h.Media.SignedURL(key, h.MediaURLTTL); err == nil {
+			return url
+		}
+	}
+	if session != h.WAManager.DefaultClientID {
+		return "/get-media/" + msg.MessageID + "?session=" + session
+	}
+	return "/get-media/" + msg.MessageID
+}
+
+// storedMediaKey returns the media store key of a message, if it was ever stored
+func storedMediaKey(msg *firestore.WAMessage) string {
+	if msg.MediaPath != "" {
+		return msg.MediaPath
+	}
+	// Messages saved before media stores existed point at /uploads/media/<file>
+	return media.LegacyKey(msg.MediaURL)
+}
+
+// mediaInfoFromMessage rebuilds the download details stored on a message
+func mediaInfoFromMessage(msg *firestore.WAMessage) *whatsapp.MediaInfo {
+	return &whatsapp.MediaInfo{
+		Type:          msg.Type,
+		MimeType:      msg.MediaType,
+		FileName:      msg.MediaFileName,
+		DirectPath:    msg.MediaDirectPath,
+		MediaKey:      msg.MediaKey,
+		FileSHA256:    msg.MediaFileSHA256,
+		FileEncSHA256: msg.MediaFileEncSHA256,
+		FileLength:    msg.MediaFileLength,
+	}
 }
 
 // GetInvoiceChats handles GET /get-invoice-chats
 func (h *Handler) GetInvoiceChats(c *gin.Context) {
-	if h.Repo == nil {
+	session, ok := h.resolveSession(c, "")
+	if !ok {
+		return
+	}
+	repo := h.WAManager.RepoFor(session)
+	if repo == nil {
 		c.JSON(http.StatusServiceUnavailable, gin.H{
 			"success": false,
 			"error":   "Chat storage (Firestore) is not configured",
@@ -188,9 +431,8 @@ func (h *Handler) GetInvoiceChats(c *gin.Context) {
 		return
 	}
 
-	// For now, reuse GetRecentChats (filtering should happen in Repo or here)
-	// Ideally we filter for chats containing "INV-"
-	chats, err := h.Repo.GetInvoiceChats(c.Request.Context(), 50)
+	// Chats tagged "invoice" (by the rules, by sending an invoice or by hand)
+	chats, err := repo.GetInvoiceChats(c.Request.Context(), 50)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -209,6 +451,8 @@ func (h *Handler) GetInvoiceChats(c *gin.Context) {
 			"number":        chat.Number,
 			"unreadCount":   chat.UnreadCount,
 			"profilePicUrl": chat.ProfilePicURL,
+			"tags":          chatTags(chat),
+			"muted":         chat.Muted,
 			"timestamp":     chat.LastMessageAt.Unix(),
 			"lastMessage": map[string]interface{}{
 				"body":      chat.LastMessageBody,
@@ -222,3 +466,256 @@ func (h *Handler) GetInvoiceChats(c *gin.Context) {
 		"chats":   mappedChats,
 	})
 }
+
+// ChatTagsRequest represents the request body for POST /chats/:id/tags
+type ChatTagsRequest struct {
+	Session string   `json:"session"`
+	Add     []string `json:"add"`
+	Remove  []string `json:"remove"`
+	Muted   *bool    `json:"muted"` // mute or unmute the chat (e.g. one muted by a rule)
+}
+
+// UpdateChatTags handles POST /chats/:id/tags
+// Adds or removes tags and, when muted is given, mutes or unmutes the chat.
+func (h *Handler) UpdateChatTags(c *gin.Context) {
+	var req ChatTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if len(req.Add) == 0 && len(req.Remove) == 0 && req.Muted == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "add, remove or muted is required"})
+		return
+	}
+
+	add, err := normalizeTags(req.Add)
+	if err == nil {
+		req.Remove, err = normalizeTags(req.Remove)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	session, ok := h.resolveSession(c, req.Session)
+	if !ok {
+		return
+	}
+	repo := h.WAManager.RepoFor(session)
+	if repo == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"error":   "Chat storage (Firestore) is not configured",
+		})
+		return
+	}
+
+	// Clients are told through the chat-update WebSocket event
+	response := gin.H{"success": true, "id": c.Param("id")}
+	if req.Muted != nil {
+		err = repo.SetChatMuted(c.Request.Context(), c.Param("id"), *req.Muted)
+		response["muted"] = *req.Muted
+	}
+	if err == nil && (len(add) > 0 || len(req.Remove) > 0) {
+		response["tags"], err = repo.UpdateChatTags(c.Request.Context(), c.Param("id"), add, req.Remove)
+	}
+	if err == firestore.ErrChatNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Chat not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to update chat",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ChatReadRequest is the optional body of POST /chats/:id/read
+type ChatReadRequest struct {
+	Session string `json:"session"`
+}
+
+// MarkChatRead handles POST /chats/:id/read
+// Zeroes the chat's unread count and sends WhatsApp read receipts for its unread messages.
+func (h *Handler) MarkChatRead(c *gin.Context) {
+	var req ChatReadRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	session, ok := h.resolveSession(c, req.Session)
+	if !ok {
+		return
+	}
+	repo := h.WAManager.RepoFor(session)
+	if repo == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"error":   "Chat storage (Firestore) is not configured",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	chatID := c.Param("id")
+	chat, err := repo.GetChat(ctx, chatID)
+	if err == firestore.ErrChatNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Chat not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to fetch chat",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// The unread messages are the chat's latest incoming ones
+	var unread []firestore.WAMessage
+	if chat.UnreadCount > 0 {
+		messages, err := repo.GetChatMessages(ctx, chatID, firestore.Page{Limit: maxReadReceipts})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   "Failed to fetch unread messages",
+				"details": err.Error(),
+			})
+			return
+		}
+		for _, msg := range messages {
+			if !msg.FromMe && len(unread) < chat.UnreadCount {
+				unread = append(unread, msg)
+			}
+		}
+	}
+
+	// Clients are told through the chat-update WebSocket event
+	if err := repo.MarkChatAsRead(ctx, chatID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to mark chat as read",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	response := gin.H{
+		"success":      true,
+		"id":           chatID,
+		"unreadCount":  0,
+		"readReceipts": 0,
+	}
+	if len(unread) > 0 {
+		sent, err := h.sendReadReceipts(ctx, session, chatID, unread)
+		response["readReceipts"] = sent
+		if err != nil {
+			// The chat stays read here; WhatsApp shows it unread until the phone catches up
+			fmt.Printf("⚠️ [%s] Failed to send read receipts for %s: %v\n", session, chatID, err)
+			response["warning"] = "Read receipts not sent: " + err.Error()
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// maxReadReceipts bounds how many recent messages POST /chats/:id/read looks at
+const maxReadReceipts = 100
+
+// sendReadReceipts marks messages as read on WhatsApp, one receipt per sender
+// (group messages from different members can't share a receipt). It returns how many
+// messages were marked.
+func (h *Handler) sendReadReceipts(ctx context.Context, session, chatID string, messages []firestore.WAMessage) (int, error) {
+	client, ok := h.WAManager.GetClient(session)
+	if !ok || !client.IsReady() {
+		return 0, fmt.Errorf("session %s is not connected", session)
+	}
+	chat, err := types.ParseJID(chatID)
+	if err != nil {
+		return 0, fmt.Errorf("invalid chat ID: %w", err)
+	}
+
+	bySender := make(map[string][]types.MessageID)
+	var senders []string
+	for _, msg := range messages {
+		if _, ok := bySender[msg.From]; !ok {
+			senders = append(senders, msg.From)
+		}
+		bySender[msg.From] = append(bySender[msg.From], types.MessageID(msg.MessageID))
+	}
+
+	sent := 0
+	for _, from := range senders {
+		sender, _ := types.ParseJID(from) // empty JID is fine outside groups
+		if err := client.WAClient.MarkRead(ctx, bySender[from], time.Now(), chat, sender); err != nil {
+			return sent, err
+		}
+		sent += len(bySender[from])
+	}
+	return sent, nil
+}
+
+func normalizeTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		t, err := rules.NormalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		normalized = append(normalized, t)
+	}
+	return normalized, nil
+}
+
+// chatTags returns the tags of a chat, never nil (so the frontend always gets a list)
+func chatTags(chat firestore.WAChat) []string {
+	if chat.Tags == nil {
+		return []string{}
+	}
+	return chat.Tags
+}
+
+// pageFromQuery reads ?limit= (default 50, at most 500) and one of the ?before= / ?after= cursors
+func pageFromQuery(c *gin.Context) (firestore.Page, bool) {
+	page := firestore.Page{Limit: 50}
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 && l <= 500 {
+		page.Limit = l
+	}
+
+	before, after := c.Query("before"), c.Query("after")
+	if before != "" && after != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "use either before or after, not both"})
+		return page, false
+	}
+
+	var err error
+	if before != "" {
+		page.Before, err = firestore.ParseCursor(before)
+	} else if after != "" {
+		page.After, err = firestore.ParseCursor(after)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return page, false
+	}
+	return page, true
+}
+
+// nextCursor returns the cursor that continues a full page in the same direction
+// (pass it back as the same before/after parameter), or nil after the last page
+func nextCursor[T any](page firestore.Page, items []T, cursor func(T) firestore.Cursor) interface{} {
+	if len(items) == 0 || len(items) < page.Limit {
+		return nil
+	}
+	if page.After != nil {
+		return cursor(items[0]).String() // newest item
+	}
+	return cursor(items[len(items)-1]).String() // oldest item
+}
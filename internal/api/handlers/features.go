@@ -1,15 +1,26 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"wa-server-go/internal/features/backup"
+	"wa-server-go/internal/features/blog"
+	"wa-server-go/internal/features/reminder"
 
 	"github.com/gin-gonic/gin"
 )
 
 // TriggerBackup handles POST /trigger-backup
+// Starts a backup in the background; poll GET /backups/:id for the outcome
 func (h *Handler) TriggerBackup(c *gin.Context) {
-	botClient, ok := h.WAManager.GetClient("bot")
-	if !ok || !botClient.IsReady() {
+	if !h.WAManager.IsReady(h.WAManager.DefaultClientID) {
 		c.JSON(http.StatusServiceUnavailable, gin.H{
 			"success": false,
 			"error":   "Bot not ready",
@@ -17,33 +28,455 @@ func (h *Handler) TriggerBackup(c *gin.Context) {
 		return
 	}
 
-	// TODO: Implement async backup trigger
-	// - Fetch data from WEB_URL/api/backup/data-dump
-	// - Generate Excel file using excelize
-	// - Send via WhatsApp to BACKUP_PHONE
+	run, err := h.Backup.TriggerManual()
+	if err == backup.ErrAlreadyRunning {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"error":   "A backup is already running",
+			"backup":  run,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to start backup",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"message": "Backup started",
+		"jobId":   run.ID,
+		"backup":  run,
+	})
+}
+
+// ListBackups handles GET /backups
+// Returns the backup run history, newest first (optional ?limit=, default 30)
+func (h *Handler) ListBackups(c *gin.Context) {
+	limit := 30
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 && l <= 365 {
+		limit = l
+	}
+
+	runs, err := h.Backup.History(c.Request.Context(), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to fetch backups",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"backups": runs,
+		"total":   len(runs),
+		"running": h.Backup.Running(),
+	})
+}
+
+// GetBackup handles GET /backups/:id
+func (h *Handler) GetBackup(c *gin.Context) {
+	run, err := h.Backup.Get(c.Request.Context(), c.Param("id"))
+	if err == backup.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Backup not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to fetch backup",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"backup":  run,
+	})
+}
+
+// RestoreRequest represents the JSON body of POST /backups/restore for a locally stored backup
+type RestoreRequest struct {
+	Name       string `json:"name" binding:"required"`
+	Passphrase string `json:"passphrase"`
+	DryRun     bool   `json:"dryRun"`
+}
+
+// maxRestoreUpload bounds the size of an uploaded restore file
+const maxRestoreUpload = 64 << 20
+
+// ListBackupFiles handles GET /backups/files
+// Lists the backup files kept in BACKUP_DIR (after retention)
+func (h *Handler) ListBackupFiles(c *gin.Context) {
+	files, err := h.Backup.StoredFiles()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to list backup files",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"files":   files,
+		"total":   len(files),
+	})
+}
+
+// RestoreBackup handles POST /backups/restore
+// Accepts an uploaded restore file (multipart "file", optional "passphrase" and "dryRun" fields)
+// or a JSON body naming a stored file. Validates it and, unless dryRun, replays it to BACKUP_RESTORE_URL.
+func (h *Handler) RestoreBackup(c *gin.Context) {
+	var (
+		data       []byte
+		passphrase string
+		dryRun     = c.Query("dryRun") == "true"
+	)
+
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxRestoreUpload)
+		header, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "file is required"})
+			return
+		}
+		f, err := header.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+		data, err = io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+		passphrase = c.PostForm("passphrase")
+		dryRun = dryRun || c.PostForm("dryRun") == "true"
+	} else {
+		var req RestoreRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+		stored, err := h.Backup.ReadStored(req.Name)
+		if os.IsNotExist(err) {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Backup file not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+		data = stored
+		passphrase = req.Passphrase
+		dryRun = dryRun || req.DryRun
+	}
+
+	result, err := h.Backup.Restore(c.Request.Context(), data, passphrase, dryRun)
+	var validationErr *backup.ValidationError
+	var replayErr *backup.ReplayError
+	switch {
+	case errors.As(err, &validationErr):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"success":  false,
+			"error":    "Invalid backup file",
+			"problems": validationErr.Problems,
+		})
+		return
+	case errors.As(err, &replayErr):
+		c.JSON(http.StatusBadGateway, gin.H{
+			"success": false,
+			"error":   "Restore endpoint rejected the backup",
+			"details": replayErr.Err.Error(),
+			"restore": replayErr.Result,
+		})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to restore backup",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	message := "Backup restored"
+	if dryRun {
+		message = "Backup is valid (dry run, nothing was restored)"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": message,
+		"restore": result,
+	})
+}
+
+// GetMonitorStatus handles GET /monitor/status
+// Returns every monitored target with its current status and recent checks
+func (h *Handler) GetMonitorStatus(c *gin.Context) {
+	if !h.monitorEnabled(c) {
+		return
+	}
+	status := h.Monitor.GetStatus()
+	status["success"] = true
+	c.JSON(http.StatusOK, status)
+}
+
+// GetMonitorReport handles GET /monitor/report
+// Returns uptime %, p50/p95 latency and incidents per target for ?from=&to= (RFC 3339 or
+// YYYY-MM-DD, where a date "to" includes that whole day). Defaults to the last 7 days.
+func (h *Handler) GetMonitorReport(c *gin.Context) {
+	if !h.monitorEnabled(c) {
+		return
+	}
+	to := time.Now()
+	if v := c.Query("to"); v != "" {
+		t, err := parseReportTime(v, true)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid to", "details": err.Error()})
+			return
+		}
+		to = t
+	}
+	from := to.AddDate(0, 0, -7)
+	if v := c.Query("from"); v != "" {
+		t, err := parseReportTime(v, false)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid from", "details": err.Error()})
+			return
+		}
+		from = t
+	}
+	if !from.Before(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "from must be before to"})
+		return
+	}
+
+	report, err := h.Monitor.Report(c.Request.Context(), from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to build monitor report",
+			"details": err.Error(),
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"message": "Backup triggered (implementation pending)",
+		"report":  report,
 	})
 }
 
+// monitorEnabled writes a 503 and returns false when the monitor failed to start
+func (h *Handler) monitorEnabled(c *gin.Context) bool {
+	if h.Monitor == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"error":   "Health monitor is disabled (check the server log)",
+		})
+		return false
+	}
+	return true
+}
+
+// parseReportTime parses an RFC 3339 time or a local YYYY-MM-DD date (the end of that day if endOfDay)
+func parseReportTime(v string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", v, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected RFC 3339 time or YYYY-MM-DD date")
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// BlogTriggerRequest is the optional body of POST /api/blog/manual-trigger
+type BlogTriggerRequest struct {
+	Topic string `json:"topic"` // default: the next topic from BLOG_TOPICS
+}
+
 // TriggerBlog handles POST /api/blog/manual-trigger
+// Starts generating a blog draft in the background; poll GET /blog/posts/:id for the outcome
 func (h *Handler) TriggerBlog(c *gin.Context) {
-	// TODO: Implement blog automation trigger
-	// - Generate content using Groq API
-	// - Fetch images from Pexels
-	// - Publish to Contentful
+	if h.Blog == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"error":   "Blog automation is not configured",
+		})
+		return
+	}
+
+	// The body is optional
+	var req BlogTriggerRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	post, err := h.Blog.Trigger(blog.TriggerManual, strings.TrimSpace(req.Topic))
+	if err == blog.ErrAlreadyRunning {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"error":   "A blog post is already being generated",
+			"post":    post,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to start blog generation",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"message": "Blog generation started",
+		"jobId":   post.ID,
+		"post":    post,
+	})
+}
+
+// ListBlogPosts handles GET /blog/posts
+// Returns the generated posts, newest first (optional ?limit=, default 30)
+func (h *Handler) ListBlogPosts(c *gin.Context) {
+	if h.Blog == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "Blog automation is not configured"})
+		return
+	}
+	limit := 30
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 && l <= 500 {
+		limit = l
+	}
+
+	posts, err := h.Blog.History(c.Request.Context(), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to fetch blog posts",
+			"details": err.Error(),
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"message": "Blog generation triggered (implementation pending)",
+		"posts":   posts,
+		"total":   len(posts),
+		"running": h.Blog.Running(),
+	})
+}
+
+// GetBlogPost handles GET /blog/posts/:id
+// Returns the post with its review audit trail
+func (h *Handler) GetBlogPost(c *gin.Context) {
+	if h.Blog == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "Blog automation is not configured"})
+		return
+	}
+	post, err := h.Blog.Get(c.Request.Context(), c.Param("id"))
+	if err == blog.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Blog post not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to fetch blog post",
+			"details": err.Error(),
+		})
+		return
+	}
+	events, err := h.Blog.Events(c.Request.Context(), post.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to fetch blog post history",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"post":    post,
+		"events":  events,
+	})
+}
+
+// RunReminders handles POST /reminders/run
+// Queues the invoice reminders that are due now; ?dryRun=true only lists them
+func (h *Handler) RunReminders(c *gin.Context) {
+	result, err := h.Reminders.Run(c.Request.Context(), c.Query("dryRun") == "true")
+	if err == reminder.ErrAlreadyRunning || err == reminder.ErrQuietHours {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Reminder run failed",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"result":  result,
+	})
+}
+
+// ListReminders handles GET /reminders
+// Returns the reminder ledger, newest first (optional ?invoice= and ?limit=, default 50)
+func (h *Handler) ListReminders(c *gin.Context) {
+	limit := 50
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 && l <= 500 {
+		limit = l
+	}
+
+	reminders, err := h.Reminders.List(c.Request.Context(), c.Query("invoice"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to fetch reminders",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"reminders": reminders,
+		"total":     len(reminders),
 	})
 }
 
 // SyncInvoices handles POST /sync-invoices
 func (h *Handler) SyncInvoices(c *gin.Context) {
-	if h.Repo == nil {
+	session, ok := h.resolveSession(c, "")
+	if !ok {
+		return
+	}
+	repo := h.WAManager.RepoFor(session)
+	if repo == nil {
 		c.JSON(http.StatusServiceUnavailable, gin.H{
 			"success": false,
 			"error":   "Chat storage (Firestore) is not configured",
@@ -53,7 +486,7 @@ func (h *Handler) SyncInvoices(c *gin.Context) {
 
 	// This handler is now "SyncChatMetadata" but we keep the endpoint /sync-invoices for compatibility
 	// or we can rename the handler. Let's redirect to ScanChatMetadata.
-	count, err := h.Repo.ScanChatMetadata(c.Request.Context())
+	count, err := repo.ScanChatMetadata(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
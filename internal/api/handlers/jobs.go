@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"wa-server-go/internal/outbox"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListJobs handles GET /jobs
+// Optional query params: status (queued, sending, sent, failed, cancelled), client, limit (default 50)
+func (h *Handler) ListJobs(c *gin.Context) {
+	limit := 50
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 && l <= 500 {
+		limit = l
+	}
+
+	jobs, err := h.Outbox.List(c.Request.Context(), outbox.Status(c.Query("status")), c.Query("client"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to fetch jobs",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	mappedJobs := make([]gin.H, 0, len(jobs))
+	for _, job := range jobs {
+		mappedJobs = append(mappedJobs, jobResponse(job))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"jobs":    mappedJobs,
+		"total":   len(mappedJobs),
+	})
+}
+
+// GetJob handles GET /jobs/:id
+func (h *Handler) GetJob(c *gin.Context) {
+	job, err := h.Outbox.Get(c.Request.Context(), c.Param("id"))
+	if err == outbox.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Job not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to fetch job",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"job":     jobResponse(job),
+	})
+}
+
+// CancelJob handles DELETE /jobs/:id
+func (h *Handler) CancelJob(c *gin.Context) {
+	job, err := h.Outbox.Cancel(c.Request.Context(), c.Param("id"))
+	switch {
+	case err == outbox.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Job not found"})
+		return
+	case err == outbox.ErrNotQueued:
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"error":   "Job can no longer be cancelled",
+			"job":     jobResponse(job),
+		})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to cancel job",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Job cancelled",
+		"job":     jobResponse(job),
+	})
+}
+
+// jobResponse maps a job to the frontend format
+func jobResponse(job *outbox.Job) gin.H {
+	// An invoice produces up to two messages (text, then PDF); messageId is the first one
+	messageID := ""
+	if len(job.MessageIDs) > 0 {
+		messageID = job.MessageIDs[0]
+	}
+
+	var sentAt interface{}
+	if job.SentAt != nil {
+		sentAt = job.SentAt.Unix()
+	}
+
+	return gin.H{
+		"id":            job.ID,
+		"client":        job.ClientID,
+		"kind":          job.Kind,
+		"status":        job.Status,
+		"attempts":      job.Attempts,
+		"maxAttempts":   outbox.MaxAttempts,
+		"lastError":     job.LastError,
+		"messageId":     messageID,
+		"messageIds":    job.MessageIDs,
+		"nextAttemptAt": job.NextAttemptAt.Unix(),
+		"createdAt":     job.CreatedAt.Unix(),
+		"updatedAt":     job.UpdatedAt.Unix(),
+		"sentAt":        sentAt,
+	}
+}
@@ -25,7 +25,7 @@ func (h *Handler) SyncContacts(c *gin.Context) {
 
 	if !exists {
 		// Create and Connect
-		err := h.WAManager.CreateClient(context.Background(), clientID, "session-leads.db")
+		err := h.WAManager.CreateClient(context.Background(), clientID, h.WAManager.SessionPath(clientID))
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
 			return
@@ -237,7 +237,7 @@ func (h *Handler) StartLeadsClient(c *gin.Context) {
 
 	// Create client
 	// Use a separate DB for leads session
-	err := h.WAManager.CreateClient(context.Background(), clientID, "session-leads.db")
+	err := h.WAManager.CreateClient(context.Background(), clientID, h.WAManager.SessionPath(clientID))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -321,7 +321,7 @@ func (h *Handler) SyncContactsStream(c *gin.Context) {
 
 	if !exists {
 		// Create and Connect
-		err := h.WAManager.CreateClient(context.Background(), clientID, "session-leads.db")
+		err := h.WAManager.CreateClient(context.Background(), clientID, h.WAManager.SessionPath(clientID))
 		if err != nil {
 			sendEvent("error", gin.H{"error": err.Error()})
 			return
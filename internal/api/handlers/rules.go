@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"wa-server-go/internal/rules"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RuleRequest represents the request body for POST /rules and PUT /rules/:id
+type RuleRequest struct {
+	Name       string            `json:"name" binding:"required"`
+	Enabled    *bool             `json:"enabled"` // default true
+	Priority   int               `json:"priority"`
+	Match      string            `json:"match"`
+	Conditions []rules.Condition `json:"conditions"`
+	Actions    []rules.Action    `json:"actions"`
+}
+
+func (req RuleRequest) rule(id string) *rules.Rule {
+	r := &rules.Rule{
+		ID:         id,
+		Name:       req.Name,
+		Enabled:    true,
+		Priority:   req.Priority,
+		Match:      req.Match,
+		Conditions: req.Conditions,
+		Actions:    req.Actions,
+	}
+	if req.Enabled != nil {
+		r.Enabled = *req.Enabled
+	}
+	return r
+}
+
+// ListRules handles GET /rules
+func (h *Handler) ListRules(c *gin.Context) {
+	list := h.Rules.List()
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"rules":   list,
+		"total":   len(list),
+		"flags":   rules.Flags,
+	})
+}
+
+// GetRule handles GET /rules/:id
+func (h *Handler) GetRule(c *gin.Context) {
+	r, err := h.Rules.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		ruleError(c, err, "Failed to fetch rule")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"rule":    r,
+	})
+}
+
+// CreateRule handles POST /rules
+func (h *Handler) CreateRule(c *gin.Context) {
+	var req RuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	r := req.rule("")
+	if err := h.Rules.Create(c.Request.Context(), r); err != nil {
+		ruleError(c, err, "Failed to create rule")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"rule":    r,
+	})
+}
+
+// UpdateRule handles PUT /rules/:id (replaces the whole rule)
+func (h *Handler) UpdateRule(c *gin.Context) {
+	var req RuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	r := req.rule(c.Param("id"))
+	if err := h.Rules.Update(c.Request.Context(), r); err != nil {
+		ruleError(c, err, "Failed to update rule")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"rule":    r,
+	})
+}
+
+// DeleteRule handles DELETE /rules/:id
+func (h *Handler) DeleteRule(c *gin.Context) {
+	if err := h.Rules.Delete(c.Request.Context(), c.Param("id")); err != nil {
+		ruleError(c, err, "Failed to delete rule")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Rule deleted",
+	})
+}
+
+// TestRules handles POST /rules/test
+// It shows what the current rules would do with a sample message, without changing any chat.
+func (h *Handler) TestRules(c *gin.Context) {
+	var in rules.Input
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"result":  h.Rules.Evaluate(in),
+	})
+}
+
+// ruleError maps rule engine errors to responses
+func ruleError(c *gin.Context, err error, msg string) {
+	switch {
+	case err == rules.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Rule not found"})
+	case errors.Is(err, rules.ErrInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   msg,
+			"details": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"wa-server-go/internal/firestore"
+	"wa-server-go/internal/search"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SearchMessages handles GET /search?q=&chat=&from=&to=&type=&limit=
+// Finds messages by their text; from/to take RFC 3339 times or YYYY-MM-DD dates.
+func (h *Handler) SearchMessages(c *gin.Context) {
+	q := search.Query{
+		Text:   c.Query("q"),
+		ChatID: c.Query("chat"),
+		Type:   c.Query("type"),
+		Limit:  20,
+	}
+	if q.Text == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "q is required"})
+		return
+	}
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 && l <= 100 {
+		q.Limit = l
+	}
+	if v := c.Query("from"); v != "" {
+		t, err := parseReportTime(v, false)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid from", "details": err.Error()})
+			return
+		}
+		q.From = t
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := parseReportTime(v, true)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid to", "details": err.Error()})
+			return
+		}
+		q.To = t
+	}
+
+	session, ok := h.resolveSession(c, "")
+	if !ok {
+		return
+	}
+	// The default session is indexed as "", like its Firestore collections have no suffix
+	if session != h.WAManager.DefaultClientID {
+		q.Session = session
+	}
+
+	results, err := h.Search.Search(c.Request.Context(), q)
+	if err == search.ErrEmptyQuery {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to search messages",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	mapped := make([]gin.H, 0, len(results))
+	for _, r := range results {
+		cursor := firestore.Cursor{Time: r.Timestamp, ID: r.MessageID}.String()
+		mapped = append(mapped, gin.H{
+			"messageId": r.MessageID,
+			"chatId":    r.ChatID,
+			"sender":    r.Sender,
+			"type":      r.Type,
+			"fromMe":    r.FromMe,
+			"timestamp": r.Timestamp.Unix(),
+			"snippet":   r.Snippet,
+			// The message's position in its chat: the history pages right before and after it
+			"cursor": cursor,
+			"older":  messagesLink(r.ChatID, "before", cursor, c.Query("session")),
+			"newer":  messagesLink(r.ChatID, "after", cursor, c.Query("session")),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"results": mapped,
+		"total":   len(mapped),
+		"query":   q.Text,
+	})
+}
+
+// ReindexRequest represents the optional request body for POST /search/reindex
+type ReindexRequest struct {
+	Session string `json:"session"`
+}
+
+// ReindexMessages handles POST /search/reindex
+// Adds the messages already stored in Firestore to the search index, e.g. those saved before
+// search existed. Indexed messages are updated in place, so it is safe to run again.
+func (h *Handler) ReindexMessages(c *gin.Context) {
+	var req ReindexRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	session, ok := h.resolveSession(c, req.Session)
+	if !ok {
+		return
+	}
+	repo := h.WAManager.RepoFor(session)
+	if repo == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"error":   "Chat storage (Firestore) is not configured",
+		})
+		return
+	}
+	indexSession := ""
+	if session != h.WAManager.DefaultClientID {
+		indexSession = session
+	}
+
+	count, err := h.Search.Reindex(c.Request.Context(), indexSession, repo)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to reindex messages",
+			"details": err.Error(),
+			"indexed": count,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Messages reindexed successfully",
+		"indexed": count,
+	})
+}
+
+// messagesLink builds a /get-messages/:chatId URL continuing from cursor
+func messagesLink(chatID, direction, cursor, session string) string {
+	params := url.Values{direction: {cursor}}
+	if session != "" {
+		params.Set("session", session)
+	}
+	return "/get-messages/" + url.PathEscape(chatID) + "?" + params.Encode()
+}
@@ -0,0 +1,305 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"wa-server-go/internal/utils"
+	"wa-server-go/internal/whatsapp"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateSessionRequest represents the request body for POST /sessions
+type CreateSessionRequest struct {
+	ID string `json:"id" binding:"required"`
+}
+
+// PairCodeRequest represents the request body for POST /sessions/:id/pair-code
+type PairCodeRequest struct {
+	Phone string `json:"phone" binding:"required"`
+}
+
+// ListSessions handles GET /sessions
+func (h *Handler) ListSessions(c *gin.Context) {
+	sessions := make([]gin.H, 0)
+	for _, id := range h.WAManager.ClientIDs() {
+		if client, ok := h.WAManager.GetClient(id); ok {
+			sessions = append(sessions, h.sessionResponse(id, client))
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"sessions": sessions,
+		"total":    len(sessions),
+	})
+}
+
+// CreateSession handles POST /sessions
+// Creates a session store and starts connecting; the QR code arrives via GET /sessions/:id or the qr-image event
+func (h *Handler) CreateSession(c *gin.Context) {
+	var req CreateSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if !whatsapp.ValidSessionID(req.ID) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Session ID must be 1-32 letters, digits, '-' or '_'",
+		})
+		return
+	}
+	if req.ID == h.WAManager.DefaultClientID || req.ID == h.LeadsClientID {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "Session ID is reserved for a built-in client"})
+		return
+	}
+	if _, exists := h.WAManager.GetClient(req.ID); exists {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "Session already exists"})
+		return
+	}
+
+	if err := h.WAManager.StartSession(context.Background(), req.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to create session",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	client, _ := h.WAManager.GetClient(req.ID)
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Session created",
+		"session": h.sessionResponse(req.ID, client),
+	})
+}
+
+// GetSession handles GET /sessions/:id
+func (h *Handler) GetSession(c *gin.Context) {
+	id := c.Param("id")
+	client, ok := h.WAManager.GetClient(id)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Session not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"session": h.sessionResponse(id, client),
+	})
+}
+
+// ConnectSession handles POST /sessions/:id/connect
+func (h *Handler) ConnectSession(c *gin.Context) {
+	id := c.Param("id")
+	client, ok := h.WAManager.GetClient(id)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Session not found"})
+		return
+	}
+
+	if client.WAClient.IsConnected() {
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "Session already connected",
+			"session": h.sessionResponse(id, client),
+		})
+		return
+	}
+
+	go func() {
+		if err := h.WAManager.Connect(context.Background(), id); err != nil {
+			fmt.Printf("❌ [%s] Failed to connect: %v\n", id, err)
+		}
+	}()
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"message": "Connecting",
+		"session": h.sessionResponse(id, client),
+	})
+}
+
+// PairSessionCode handles POST /sessions/:id/pair-code
+// Links an unpaired session by phone number instead of QR: returns the 8-character code to
+// enter on the phone and also broadcasts it as a pair-code event
+func (h *Handler) PairSessionCode(c *gin.Context) {
+	var req PairCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	id := c.Param("id")
+	if _, ok := h.WAManager.GetClient(id); !ok {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Session not found"})
+		return
+	}
+
+	phone := utils.FormatPhoneNumber(req.Phone)
+	if len(phone) < 8 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid phone number"})
+		return
+	}
+
+	code, err := h.WAManager.PairPhone(c.Request.Context(), id, phone)
+	if err == whatsapp.ErrAlreadyPaired {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "Session is already paired (log out first)"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"success": false,
+			"error":   "Failed to get pairing code",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"code":    code,
+		"phone":   phone,
+		"message": "Enter this code on the phone: WhatsApp > Linked devices > Link with phone number instead",
+	})
+}
+
+// DisconnectSession handles POST /sessions/:id/disconnect
+// Closes the connection but keeps the pairing, so the session can reconnect without a QR scan
+func (h *Handler) DisconnectSession(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.WAManager.Disconnect(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Session not found"})
+		return
+	}
+
+	client, _ := h.WAManager.GetClient(id)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Session disconnected",
+		"session": h.sessionResponse(id, client),
+	})
+}
+
+// LogoutSession handles POST /sessions/:id/logout
+// Unlinks the device from the WhatsApp account; the session must be paired again to be used
+func (h *Handler) LogoutSession(c *gin.Context) {
+	id := c.Param("id")
+	if _, ok := h.WAManager.GetClient(id); !ok {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Session not found"})
+		return
+	}
+
+	if err := h.WAManager.Logout(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to log out",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	client, _ := h.WAManager.GetClient(id)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Session logged out",
+		"session": h.sessionResponse(id, client),
+	})
+}
+
+// DeleteSession handles DELETE /sessions/:id
+// Disconnects the session, deletes its SQLite store and cancels its queued sends.
+// Chat history in Firestore is kept.
+func (h *Handler) DeleteSession(c *gin.Context) {
+	id := c.Param("id")
+	if id == h.WAManager.DefaultClientID {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"error":   "The default session cannot be deleted (use logout instead)",
+		})
+		return
+	}
+	if _, ok := h.WAManager.GetClient(id); !ok {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Session not found"})
+		return
+	}
+
+	if err := h.WAManager.DeleteSession(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to delete session",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	cancelled, err := h.Outbox.CancelClient(c.Request.Context(), id)
+	if err != nil {
+		fmt.Printf("⚠️ [%s] Failed to cancel queued jobs: %v\n", id, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":       true,
+		"message":       "Session deleted",
+		"cancelledJobs": cancelled,
+	})
+}
+
+// resolveSession returns the session a request targets: the one named in the body, then
+// ?session=, then the default session. It answers 404 itself when the session does not exist,
+// and 400 for the on-demand leads client, which only syncs contacts and keeps no chats.
+func (h *Handler) resolveSession(c *gin.Context, requested string) (string, bool) {
+	id := requested
+	if id == "" {
+		id = c.Query("session")
+	}
+	if id == "" {
+		id = h.WAManager.DefaultClientID
+	}
+
+	if id == h.LeadsClientID {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "The leads client is read-only and cannot be selected as a session"})
+		return "", false
+	}
+
+	if _, ok := h.WAManager.GetClient(id); !ok {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": fmt.Sprintf("Session %q not found", id)})
+		return "", false
+	}
+	return id, true
+}
+
+// sessionResponse maps a session to the frontend format
+func (h *Handler) sessionResponse(id string, client *whatsapp.Client) gin.H {
+	return gin.H{
+		"id":        id,
+		"default":   id == h.WAManager.DefaultClientID,
+		"state":     sessionState(client),
+		"ready":     client.IsReady(),
+		"connected": client.WAClient.IsConnected(),
+		"loggedIn":  client.IsLoggedIn(),
+		"phone":     client.PhoneNumber(),
+		"qr":        client.GetQRCode(),
+		"health":    client.Health(),
+	}
+}
+
+// sessionState summarizes the pairing/connection state of a session
+func sessionState(client *whatsapp.Client) string {
+	switch {
+	case client.IsReady():
+		return "ready"
+	case !client.IsLoggedIn() && client.GetQRCode() != "":
+		return "pairing"
+	case !client.IsLoggedIn():
+		return "unpaired"
+	case client.WAClient.IsConnected():
+		return "connecting"
+	default:
+		return "disconnected"
+	}
+}
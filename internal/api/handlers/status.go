@@ -5,7 +5,17 @@ import (
 	"time"
 
 	"wa-server-go/internal/api/websocket"
+	"wa-server-go/internal/features/backup"
+	"wa-server-go/internal/features/blog"
+	"wa-server-go/internal/features/monitor"
+	"wa-server-go/internal/features/reminder"
 	"wa-server-go/internal/firestore"
+	"wa-server-go/internal/media"
+	"wa-server-go/internal/outbox"
+	"wa-server-go/internal/rules"
+	"wa-server-go/internal/search"
+	"wa-server-go/internal/templates"
+	"wa-server-go/internal/webhook"
 	"wa-server-go/internal/whatsapp"
 
 	"github.com/gin-gonic/gin"
@@ -16,14 +26,41 @@ type Handler struct {
 	WAManager *whatsapp.Manager
 	Repo      *firestore.ChatsRepository
 	WSHub     *websocket.Hub
+	Outbox    *outbox.Outbox
+	Webhooks  *webhook.Dispatcher
+	Backup    *backup.BackupService
+	Monitor   *monitor.MonitorService // nil when the monitor failed to start
+	Blog      *blog.BlogService       // nil when blog automation is not configured
+	Templates *templates.Service
+	Reminders *reminder.ReminderService
+	Rules     *rules.Engine
+	Search    *search.Index
+
+	// LeadsClientID is the on-demand leads client, reserved like the default session
+	LeadsClientID string
+
+	Media       media.Store
+	MediaURLTTL time.Duration
 }
 
 // NewHandler creates a new handler with dependencies
-func NewHandler(waManager *whatsapp.Manager, repo *firestore.ChatsRepository, wsHub *websocket.Hub) *Handler {
+func NewHandler(waManager *whatsapp.Manager, repo *firestore.ChatsRepository, wsHub *websocket.Hub, ob *outbox.Outbox, webhooks *webhook.Dispatcher, backupService *backup.BackupService, monitorService *monitor.MonitorService, blogService *blog.BlogService, templateService *templates.Service, reminderService *reminder.ReminderService, ruleEngine *rules.Engine, searchIndex *search.Index, leadsClientID string, mediaStore media.Store, mediaURLTTL time.Duration) *Handler {
 	return &Handler{
-		WAManager: waManager,
-		Repo:      repo,
-		WSHub:     wsHub,
+		WAManager:     waManager,
+		Repo:          repo,
+		WSHub:         wsHub,
+		Outbox:        ob,
+		Webhooks:      webhooks,
+		Backup:        backupService,
+		Monitor:       monitorService,
+		Blog:          blogService,
+		Templates:     templateService,
+		Reminders:     reminderService,
+		Rules:         ruleEngine,
+		Search:        searchIndex,
+		LeadsClientID: leadsClientID,
+		Media:         mediaStore,
+		MediaURLTTL:   mediaURLTTL,
 	}
 }
 
@@ -45,6 +82,7 @@ func (h *Handler) GetStatus(c *gin.Context) {
 	if botExists {
 		botStatus["ready"] = botClient.IsReady()
 		botStatus["qr"] = botClient.GetQRCode()
+		botStatus["health"] = botClient.Health()
 	}
 
 	leadsStatus := map[string]interface{}{
@@ -57,15 +95,31 @@ func (h *Handler) GetStatus(c *gin.Context) {
 	if leadsExists {
 		leadsStatus["ready"] = leadsClient.IsReady()
 		leadsStatus["qr"] = leadsClient.GetQRCode()
+		leadsStatus["health"] = leadsClient.Health()
+	}
+
+	sessions := gin.H{
+		"bot":   botStatus,
+		"leads": leadsStatus,
+	}
+	// Sessions created through /sessions. This route is public, so QR codes of these
+	// sessions are only served by the authenticated GET /sessions/:id.
+	for _, id := range h.WAManager.ClientIDs() {
+		if _, builtIn := sessions[id]; builtIn {
+			continue
+		}
+		if client, ok := h.WAManager.GetClient(id); ok {
+			sessions[id] = gin.H{
+				"ready":  client.IsReady(),
+				"health": client.Health(),
+			}
+		}
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"status": "running",
-		"mode":   "low-ram-optimized",
-		"sessions": gin.H{
-			"bot":   botStatus,
-			"leads": leadsStatus,
-		},
+		"status":    "running",
+		"mode":      "low-ram-optimized",
+		"sessions":  sessions,
 		"timestamp": time.Now().Format(time.RFC3339),
 	})
 }
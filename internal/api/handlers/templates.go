@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"wa-server-go/internal/templates"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SaveTemplateRequest represents the request body for PUT /templates/:name
+type SaveTemplateRequest struct {
+	Locale      string               `json:"locale"`
+	Description string               `json:"description"`
+	Body        string               `json:"body" binding:"required"`
+	Variables   []templates.Variable `json:"variables"`
+	Note        string               `json:"note"`
+	Author      string               `json:"author"`
+}
+
+// RenderTemplateRequest represents the request body for POST /templates/:name/render.
+// Body previews unsaved changes; Variables then replaces the template's variables if given.
+type RenderTemplateRequest struct {
+	Locale    string                 `json:"locale"`
+	Vars      map[string]interface{} `json:"vars"`
+	Body      string                 `json:"body"`
+	Variables []templates.Variable   `json:"variables"`
+}
+
+// RollbackTemplateRequest represents the request body for POST /templates/:name/rollback
+type RollbackTemplateRequest struct {
+	Locale  string `json:"locale"`
+	Version *int   `json:"version" binding:"required"`
+	Author  string `json:"author"`
+}
+
+// ListTemplates handles GET /templates
+func (h *Handler) ListTemplates(c *gin.Context) {
+	list, err := h.Templates.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to fetch templates",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":         true,
+		"templates":       list,
+		"total":           len(list),
+		"locales":         templates.Locales,
+		"sharedVariables": templates.SharedVariables,
+	})
+}
+
+// GetTemplate handles GET /templates/:name (?locale=, default id)
+func (h *Handler) GetTemplate(c *gin.Context) {
+	t, err := h.Templates.Get(c.Request.Context(), c.Param("name"), templateLocale(c.Query("locale")))
+	if err != nil {
+		templateError(c, err, "Failed to fetch template")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"template": t,
+	})
+}
+
+// SaveTemplate handles PUT /templates/:name
+// Every save creates a new version; earlier versions stay available for rollback.
+func (h *Handler) SaveTemplate(c *gin.Context) {
+	var req SaveTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	t := &templates.Template{
+		Name:        c.Param("name"),
+		Locale:      templateLocale(req.Locale),
+		Description: req.Description,
+		Body:        req.Body,
+		Variables:   req.Variables,
+		Note:        req.Note,
+		UpdatedBy:   req.Author,
+	}
+	if t.Variables == nil {
+		t.Variables = []templates.Variable{}
+	}
+	if err := h.Templates.Save(c.Request.Context(), t); err != nil {
+		templateError(c, err, "Failed to save template")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"template": t,
+	})
+}
+
+// RenderTemplate handles POST /templates/:name/render
+// Variables that are not given are filled with their example value.
+func (h *Handler) RenderTemplate(c *gin.Context) {
+	// The body is optional
+	var req RenderTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	locale := templateLocale(req.Locale)
+	t, err := h.Templates.Get(c.Request.Context(), c.Param("name"), locale)
+	if err == templates.ErrNotFound && req.Body != "" {
+		t, err = &templates.Template{Name: c.Param("name"), Locale: locale}, nil
+	}
+	if err != nil {
+		templateError(c, err, "Failed to fetch template")
+		return
+	}
+	if req.Body != "" {
+		t.Body = req.Body
+		t.Version = 0
+		if req.Variables != nil {
+			t.Variables = req.Variables
+		}
+	}
+
+	message, err := h.Templates.Execute(t, req.Vars, true)
+	if err != nil {
+		templateError(c, err, "Failed to render template")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": message,
+		"locale":  t.Locale,
+		"version": t.Version,
+	})
+}
+
+// ListTemplateVersions handles GET /templates/:name/versions (?locale=, default id)
+func (h *Handler) ListTemplateVersions(c *gin.Context) {
+	versions, err := h.Templates.Versions(c.Request.Context(), c.Param("name"), templateLocale(c.Query("locale")))
+	if err != nil {
+		templateError(c, err, "Failed to fetch template versions")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"versions": versions,
+		"total":    len(versions),
+	})
+}
+
+// RollbackTemplate handles POST /templates/:name/rollback
+// The chosen version is saved again as the newest one; version 0 is the built-in default.
+func (h *Handler) RollbackTemplate(c *gin.Context) {
+	var req RollbackTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if *req.Version < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "version must be 0 or more"})
+		return
+	}
+
+	t, err := h.Templates.Rollback(c.Request.Context(), c.Param("name"), templateLocale(req.Locale), *req.Version, req.Author)
+	if err != nil {
+		templateError(c, err, "Failed to roll back template")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"template": t,
+		"message":  "Rolled back to version " + strconv.Itoa(*req.Version),
+	})
+}
+
+func templateLocale(locale string) string {
+	if locale == "" {
+		return templates.DefaultLocale
+	}
+	return locale
+}
+
+// templateError maps template service errors to responses
+func templateError(c *gin.Context, err error, msg string) {
+	switch {
+	case err == templates.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Template not found"})
+	case errors.Is(err, templates.ErrInvalidTemplate), errors.Is(err, templates.ErrMissingVariables):
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   msg,
+			"details": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"wa-server-go/internal/webhook"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookRequest represents the request body for POST /webhooks and PUT /webhooks/:id.
+// On update, omitted fields keep their current value.
+type WebhookRequest struct {
+	URL     *string  `json:"url"`
+	Events  []string `json:"events"`
+	Secret  *string  `json:"secret"`
+	Enabled *bool    `json:"enabled"`
+}
+
+// ListWebhooks handles GET /webhooks
+func (h *Handler) ListWebhooks(c *gin.Context) {
+	hooks, err := h.Webhooks.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to fetch webhooks",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"webhooks": hooks,
+		"total":    len(hooks),
+		"events":   webhook.Events,
+	})
+}
+
+// CreateWebhook handles POST /webhooks
+// The signing secret is generated unless one is given; it is only returned here and by GET /webhooks/:id
+func (h *Handler) CreateWebhook(c *gin.Context) {
+	var req WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if req.URL == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "url is required"})
+		return
+	}
+
+	w := &webhook.Webhook{Events: []string{webhook.EventAll}, Enabled: true}
+	if err := applyWebhookRequest(w, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if err := h.Webhooks.Create(c.Request.Context(), w); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to create webhook",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"webhook": w,
+		"secret":  w.Secret,
+	})
+}
+
+// GetWebhook handles GET /webhooks/:id
+func (h *Handler) GetWebhook(c *gin.Context) {
+	w, ok := h.findWebhook(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"webhook": w,
+		"secret":  w.Secret,
+	})
+}
+
+// UpdateWebhook handles PUT /webhooks/:id
+func (h *Handler) UpdateWebhook(c *gin.Context) {
+	var req WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	w, ok := h.findWebhook(c)
+	if !ok {
+		return
+	}
+	if err := applyWebhookRequest(w, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if err := h.Webhooks.Update(c.Request.Context(), w); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to update webhook",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"webhook": w,
+	})
+}
+
+// DeleteWebhook handles DELETE /webhooks/:id
+func (h *Handler) DeleteWebhook(c *gin.Context) {
+	err := h.Webhooks.Delete(c.Request.Context(), c.Param("id"))
+	if err == webhook.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Webhook not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to delete webhook",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Webhook deleted",
+	})
+}
+
+// TestWebhook handles POST /webhooks/:id/test
+// Sends a signed "test" event right away and reports the endpoint's response
+func (h *Handler) TestWebhook(c *gin.Context) {
+	w, ok := h.findWebhook(c)
+	if !ok {
+		return
+	}
+
+	status, err := h.Webhooks.Test(c.Request.Context(), w)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"success":    false,
+			"error":      "Test delivery failed",
+			"details":    err.Error(),
+			"statusCode": status,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"message":    "Test event delivered",
+		"statusCode": status,
+	})
+}
+
+// ListDeadLetters handles GET /webhooks/dead-letters
+// Optional query params: webhook, limit (default 50)
+func (h *Handler) ListDeadLetters(c *gin.Context) {
+	limit := 50
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 && l <= 500 {
+		limit = l
+	}
+
+	deliveries, err := h.Webhooks.DeadLetters(c.Request.Context(), c.Query("webhook"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to fetch dead letters",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"deliveries": deliveries,
+		"total":      len(deliveries),
+	})
+}
+
+// RetryDeadLetter handles POST /webhooks/dead-letters/:id/retry
+func (h *Handler) RetryDeadLetter(c *gin.Context) {
+	delivery, err := h.Webhooks.Retry(c.Request.Context(), c.Param("id"))
+	switch {
+	case err == webhook.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Delivery not found"})
+		return
+	case err == webhook.ErrNotDead:
+		c.JSON(http.StatusConflict, gin.H{
+			"success":  false,
+			"error":    "Delivery is not in the dead-letter list",
+			"delivery": delivery,
+		})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to retry delivery",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"message":  "Delivery requeued",
+		"delivery": delivery,
+	})
+}
+
+// findWebhook loads the webhook named in the path, answering 404/500 itself on failure
+func (h *Handler) findWebhook(c *gin.Context) (*webhook.Webhook, bool) {
+	w, err := h.Webhooks.Get(c.Request.Context(), c.Param("id"))
+	if err == webhook.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Webhook not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to fetch webhook",
+			"details": err.Error(),
+		})
+		return nil, false
+	}
+	return w, true
+}
+
+// applyWebhookRequest validates a create/update request and copies the given fields onto w
+func applyWebhookRequest(w *webhook.Webhook, req *WebhookRequest) error {
+	if req.URL != nil {
+		u, err := url.Parse(*req.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("url must be an absolute http(s) URL")
+		}
+		w.URL = u.String()
+	}
+
+	if req.Events != nil {
+		if len(req.Events) == 0 {
+			return fmt.Errorf("events must not be empty (use [\"*\"] for all events)")
+		}
+		for _, e := range req.Events {
+			if e != webhook.EventAll && !containsEvent(webhook.Events, e) {
+				return fmt.Errorf("unknown event %q (valid: %v or \"*\")", e, webhook.Events)
+			}
+		}
+		w.Events = req.Events
+	}
+
+	if req.Secret != nil {
+		if len(*req.Secret) < 16 {
+			return fmt.Errorf("secret must be at least 16 characters")
+		}
+		w.Secret = *req.Secret
+	}
+	if req.Enabled != nil {
+		w.Enabled = *req.Enabled
+	}
+	return nil
+}
+
+func containsEvent(events []string, event string) bool {
+	for _, e := range events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
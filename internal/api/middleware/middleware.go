@@ -36,7 +36,7 @@ func CORSMiddleware(allowedDomains []string) gin.HandlerFunc {
 
 		c.Header("Access-Control-Allow-Credentials", "true")
 		c.Header("Access-Control-Allow-Headers", "Content-Type, x-api-key, Origin, Referer, Authorization")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
 
 		// Handle preflight
 		if c.Request.Method == "OPTIONS" {
@@ -59,6 +59,12 @@ func SecurityMiddleware(apiKey string, allowedDomains []string) gin.HandlerFunc
 			return
 		}
 
+		// Signed media links carry their own signature (checked by the handler)
+		if strings.HasPrefix(path, "/media/") {
+			c.Next()
+			return
+		}
+
 		// Skip OPTIONS (preflight)
 		if c.Request.Method == "OPTIONS" {
 			c.Next()
@@ -1,14 +1,26 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net/http"
 
 	"wa-server-go/internal/api/handlers"
 	"wa-server-go/internal/api/middleware"
 	"wa-server-go/internal/api/websocket"
 	"wa-server-go/internal/config"
+	"wa-server-go/internal/features/backup"
+	"wa-server-go/internal/features/blog"
+	"wa-server-go/internal/features/monitor"
+	"wa-server-go/internal/features/reminder"
 	"wa-server-go/internal/firestore"
+	"wa-server-go/internal/media"
+	"wa-server-go/internal/outbox"
+	"wa-server-go/internal/rules"
+	"wa-server-go/internal/search"
+	"wa-server-go/internal/templates"
+	"wa-server-go/internal/webhook"
 	"wa-server-go/internal/whatsapp"
 
 	"github.com/gin-gonic/gin"
@@ -22,10 +34,14 @@ type Server struct {
 	WAManager *whatsapp.Manager
 	Handler   *handlers.Handler
 	Repo      *firestore.ChatsRepository
+	Outbox    *outbox.Outbox
+	Webhooks  *webhook.Dispatcher
+
+	httpServer *http.Server
 }
 
 // NewServer creates a new HTTP server
-func NewServer(cfg *config.Config, waManager *whatsapp.Manager, repo *firestore.ChatsRepository) *Server {
+func NewServer(cfg *config.Config, waManager *whatsapp.Manager, repo *firestore.ChatsRepository, ob *outbox.Outbox, webhooks *webhook.Dispatcher, backupService *backup.BackupService, monitorService *monitor.MonitorService, blogService *blog.BlogService, templateService *templates.Service, reminderService *reminder.ReminderService, ruleEngine *rules.Engine, searchIndex *search.Index, mediaStore media.Store) *Server {
 	// Set Gin mode
 	gin.SetMode(gin.ReleaseMode)
 
@@ -37,7 +53,7 @@ func NewServer(cfg *config.Config, waManager *whatsapp.Manager, repo *firestore.
 	wsHub := websocket.NewHub()
 
 	// Create handlers
-	handler := handlers.NewHandler(waManager, repo, wsHub)
+	handler := handlers.NewHandler(waManager, repo, wsHub, ob, webhooks, backupService, monitorService, blogService, templateService, reminderService, ruleEngine, searchIndex, cfg.LeadsClientID, mediaStore, cfg.MediaURLTTL)
 
 	server := &Server{
 		Config:    cfg,
@@ -46,11 +62,14 @@ func NewServer(cfg *config.Config, waManager *whatsapp.Manager, repo *firestore.
 		WAManager: waManager,
 		Handler:   handler,
 		Repo:      repo,
+		Outbox:    ob,
+		Webhooks:  webhooks,
+		httpServer: &http.Server{
+			Addr:    fmt.Sprintf(":%s", cfg.Port),
+			Handler: router,
+		},
 	}
 
-	// Serve static files (uploads)
-	router.Static("/uploads", "./uploads")
-
 	server.setupRoutes()
 
 	return server
@@ -69,6 +88,9 @@ func (s *Server) setupRoutes() {
 	// WebSocket endpoint
 	s.Router.GET("/ws", s.WSHub.HandleWebSocket)
 
+	// Signed media links (local media store only; the signature is the auth)
+	s.Router.GET(media.LocalRoute+"*key", s.Handler.ServeMedia)
+
 	// Sync endpoints
 	s.Router.GET("/sync-status", s.Handler.GetSyncStatus)
 
@@ -81,9 +103,38 @@ func (s *Server) setupRoutes() {
 		protected.POST("/send-message", s.Handler.SendMessage)
 		protected.POST("/send-media", s.Handler.SendMedia)
 
+		// Session management
+		protected.GET("/sessions", s.Handler.ListSessions)
+		protected.POST("/sessions", s.Handler.CreateSession)
+		protected.GET("/sessions/:id", s.Handler.GetSession)
+		protected.DELETE("/sessions/:id", s.Handler.DeleteSession)
+		protected.POST("/sessions/:id/connect", s.Handler.ConnectSession)
+		protected.POST("/sessions/:id/disconnect", s.Handler.DisconnectSession)
+		protected.POST("/sessions/:id/logout", s.Handler.LogoutSession)
+		protected.POST("/sessions/:id/pair-code", s.Handler.PairSessionCode)
+
+		// Queued send jobs
+		protected.GET("/jobs", s.Handler.ListJobs)
+		protected.GET("/jobs/:id", s.Handler.GetJob)
+		protected.DELETE("/jobs/:id", s.Handler.CancelJob)
+
+		// Outbound webhooks
+		protected.GET("/webhooks", s.Handler.ListWebhooks)
+		protected.POST("/webhooks", s.Handler.CreateWebhook)
+		protected.GET("/webhooks/dead-letters", s.Handler.ListDeadLetters)
+		protected.POST("/webhooks/dead-letters/:id/retry", s.Handler.RetryDeadLetter)
+		protected.GET("/webhooks/:id", s.Handler.GetWebhook)
+		protected.PUT("/webhooks/:id", s.Handler.UpdateWebhook)
+		protected.DELETE("/webhooks/:id", s.Handler.DeleteWebhook)
+		protected.POST("/webhooks/:id/test", s.Handler.TestWebhook)
+
 		// Chat endpoints
 		protected.GET("/get-chats", s.Handler.GetChats)
 		protected.GET("/get-messages/:chatId", s.Handler.GetMessages)
+		protected.POST("/chats/:id/tags", s.Handler.UpdateChatTags)
+		protected.POST("/chats/:id/read", s.Handler.MarkChatRead)
+		protected.GET("/search", s.Handler.SearchMessages)
+		protected.POST("/search/reindex", s.Handler.ReindexMessages)
 		protected.GET("/get-media/:messageId", s.Handler.GetMedia)
 		protected.GET("/get-invoice-chats", s.Handler.GetInvoiceChats)
 
@@ -95,8 +146,34 @@ func (s *Server) setupRoutes() {
 
 		// Feature endpoints
 		protected.POST("/trigger-backup", s.Handler.TriggerBackup)
+		protected.GET("/backups", s.Handler.ListBackups)
+		protected.GET("/backups/files", s.Handler.ListBackupFiles)
+		protected.POST("/backups/restore", s.Handler.RestoreBackup)
+		protected.GET("/backups/:id", s.Handler.GetBackup)
+		protected.GET("/monitor/status", s.Handler.GetMonitorStatus)
+		protected.GET("/monitor/report", s.Handler.GetMonitorReport)
 		protected.POST("/api/blog/manual-trigger", s.Handler.TriggerBlog)
+		protected.GET("/blog/posts", s.Handler.ListBlogPosts)
+		protected.GET("/blog/posts/:id", s.Handler.GetBlogPost)
 		protected.POST("/sync-invoices", s.Handler.SyncInvoices)
+		protected.POST("/reminders/run", s.Handler.RunReminders)
+		protected.GET("/reminders", s.Handler.ListReminders)
+
+		// Message templates
+		protected.GET("/templates", s.Handler.ListTemplates)
+		protected.GET("/templates/:name", s.Handler.GetTemplate)
+		protected.PUT("/templates/:name", s.Handler.SaveTemplate)
+		protected.POST("/templates/:name/render", s.Handler.RenderTemplate)
+		protected.GET("/templates/:name/versions", s.Handler.ListTemplateVersions)
+		protected.POST("/templates/:name/rollback", s.Handler.RollbackTemplate)
+
+		// Chat classification rules
+		protected.GET("/rules", s.Handler.ListRules)
+		protected.POST("/rules", s.Handler.CreateRule)
+		protected.POST("/rules/test", s.Handler.TestRules)
+		protected.GET("/rules/:id", s.Handler.GetRule)
+		protected.PUT("/rules/:id", s.Handler.UpdateRule)
+		protected.DELETE("/rules/:id", s.Handler.DeleteRule)
 	}
 }
 
@@ -108,23 +185,50 @@ func (s *Server) Start() error {
 	// Start event forwarders
 	go s.forwardEvents()
 
-	addr := fmt.Sprintf(":%s", s.Config.Port)
 	log.Printf("✅ WhatsApp Server listening on port %s", s.Config.Port)
-	return s.Router.Run(addr)
+	return s.httpServer.ListenAndServe()
+}
+
+// Shutdown stops accepting requests, waits (until ctx expires) for in-flight ones
+// and disconnects WebSocket clients
+func (s *Server) Shutdown(ctx context.Context) error {
+	err := s.httpServer.Shutdown(ctx)
+	s.WSHub.Close()
+	return err
 }
 
-// forwardEvents forwards WhatsApp events to WebSocket clients
+// forwardEvents forwards WhatsApp events to WebSocket clients and webhooks
 func (s *Server) forwardEvents() {
 	for {
 		select {
+		case <-s.WAManager.Done():
+			return
+
 		case qr := <-s.WAManager.QRChannel():
 			s.WSHub.Broadcast("qr-image", qr)
+			s.Webhooks.Dispatch(webhook.EventQR, qr)
+
+		case code := <-s.WAManager.PairCodeChannel():
+			s.WSHub.Broadcast("pair-code", code)
 
 		case status := <-s.WAManager.StatusChannel():
 			s.WSHub.Broadcast("status-update", status)
+			s.Webhooks.Dispatch(webhook.EventStatusUpdate, status)
+			// A client came back: drain whatever queued up while it was offline
+			if status.Ready && s.Outbox != nil {
+				s.Outbox.Wake()
+			}
 
 		case msg := <-s.WAManager.MessageChannel():
 			s.WSHub.Broadcast("new-message", msg)
+			s.Webhooks.Dispatch(webhook.EventNewMessage, msg)
+
+		case ack := <-s.WAManager.AckChannel():
+			s.WSHub.Broadcast("message-ack", ack)
+			s.Webhooks.Dispatch(webhook.EventMessageAck, ack)
+
+		case update := <-s.WAManager.ChatUpdateChannel():
+			s.WSHub.Broadcast("chat-update", update)
 		}
 	}
 }
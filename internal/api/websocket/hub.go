@@ -33,6 +33,8 @@ type Hub struct {
 	broadcast  chan []byte
 	register   chan *Client
 	unregister chan *Client
+	done       chan struct{}
+	closeOnce  sync.Once
 	mu         sync.RWMutex
 }
 
@@ -43,6 +45,7 @@ func NewHub() *Hub {
 		broadcast:  make(chan []byte, 256),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
+		done:       make(chan struct{}),
 	}
 }
 
@@ -50,6 +53,16 @@ func NewHub() *Hub {
 func (h *Hub) Run() {
 	for {
 		select {
+		case <-h.done:
+			// Shutting down: writePump sends a close frame to every client
+			h.mu.Lock()
+			for client := range h.clients {
+				close(client.send)
+				delete(h.clients, client)
+			}
+			h.mu.Unlock()
+			return
+
 		case client := <-h.register:
 			h.mu.Lock()
 			h.clients[client] = true
@@ -91,7 +104,15 @@ func (h *Hub) Broadcast(event string, data interface{}) {
 		log.Printf("Error marshaling broadcast: %v", err)
 		return
 	}
-	h.broadcast <- jsonData
+	select {
+	case h.broadcast <- jsonData:
+	case <-h.done:
+	}
+}
+
+// Close disconnects all clients and stops the hub
+func (h *Hub) Close() {
+	h.closeOnce.Do(func() { close(h.done) })
 }
 
 // ClientCount returns the number of connected clients
@@ -115,7 +136,12 @@ func (h *Hub) HandleWebSocket(c *gin.Context) {
 		send: make(chan []byte, 256),
 	}
 
-	h.register <- client
+	select {
+	case h.register <- client:
+	case <-h.done:
+		conn.Close()
+		return
+	}
 
 	// Start goroutines for reading and writing
 	go client.writePump()
@@ -125,7 +151,10 @@ func (h *Hub) HandleWebSocket(c *gin.Context) {
 // readPump reads messages from the WebSocket connection
 func (c *Client) readPump() {
 	defer func() {
-		c.hub.unregister <- c
+		select {
+		case c.hub.unregister <- c:
+		case <-c.hub.done:
+		}
 		c.conn.Close()
 	}()
 
@@ -3,7 +3,10 @@ package config
 import (
 	"log"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -11,7 +14,8 @@ import (
 // Config holds all application configuration
 type Config struct {
 	// Server
-	Port string
+	Port    string
+	DataDir string
 
 	// WhatsApp
 	BotClientID   string
@@ -25,17 +29,66 @@ type Config struct {
 	FirebaseProjectID string
 	GoogleCredentials string
 
+	// Media storage
+	MediaStore        string // local or s3
+	MediaLocalDir     string
+	MediaSigningKey   string
+	MediaURLTTL       time.Duration
+	S3Endpoint        string
+	S3Region          string
+	S3Bucket          string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+	S3PathStyle       bool
+
 	// Features
 	BackupPhone    string
 	WebURL         string
 	TargetLabelTag string
 
+	// Backups
+	BackupDir          string
+	BackupKeepDaily    int
+	BackupKeepWeekly   int
+	BackupPassphrase   string
+	BackupRestoreURL   string
+	BackupRestoreToken string
+
+	// Company details used by message templates
+	CompanyName    string
+	CompanyWebsite string
+	CompanyEmail   string
+	CompanyPhone   string
+
+	// Invoice reminders
+	ReminderSourceURL   string
+	ReminderSourceToken string
+	ReminderSchedule    string
+	ReminderOverdueDays []int
+	ReminderQuietStart  int
+	ReminderQuietEnd    int
+	ReminderLocale      string
+
+	// Monitor
+	MonitorTargetsFile string
+	MonitorAlertPhone  string
+
 	// Blog Automator
 	GroqAPIKey                 string
 	PexelsAPIKey               string
 	ContentfulManagementToken  string
 	ContentfulSpaceID          string
 	ContentfulEnvironment      string
+	ContentfulContentType      string
+	ContentfulLocale           string
+	BlogLLMURL                 string
+	BlogLLMModel               string
+	BlogTopics                 []string
+	BlogSchedule               string
+	BlogAdminPhone             string
+	BlogLanguage               string
+	BlogReviewTimeout          time.Duration
+	BlogPostURL                string
 }
 
 // Load reads configuration from environment variables
@@ -47,7 +100,8 @@ func Load() *Config {
 
 	cfg := &Config{
 		// Server
-		Port: getEnv("PORT", "3001"),
+		Port:    getEnv("PORT", "3001"),
+		DataDir: getEnv("DATA_DIR", "."),
 
 		// WhatsApp
 		BotClientID:   getEnv("WA_BOT_CLIENT_ID", "bot"),
@@ -61,11 +115,29 @@ func Load() *Config {
 		FirebaseProjectID: getEnv("FIREBASE_PROJECT_ID", ""),
 		GoogleCredentials: getEnv("GOOGLE_APPLICATION_CREDENTIALS", ""),
 
+		// Media storage
+		MediaStore:        getEnv("MEDIA_STORE", "local"),
+		MediaLocalDir:     getEnv("MEDIA_LOCAL_DIR", "./uploads"),
+		MediaSigningKey:   getEnv("MEDIA_SIGNING_KEY", ""),
+		MediaURLTTL:       getEnvDuration("MEDIA_URL_TTL", 15*time.Minute),
+		S3Endpoint:        getEnv("S3_ENDPOINT", ""),
+		S3Region:          getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:          getEnv("S3_BUCKET", ""),
+		S3AccessKeyID:     getEnv("S3_ACCESS_KEY_ID", ""),
+		S3SecretAccessKey: getEnv("S3_SECRET_ACCESS_KEY", ""),
+		S3PathStyle:       getEnv("S3_PATH_STYLE", "true") == "true",
+
 		// Features
 		BackupPhone:    getEnv("BACKUP_PHONE", ""),
 		WebURL:         getEnv("WEB_URL", "https://valprointertech.com"),
 		TargetLabelTag: getEnv("TARGET_LABEL_TAG", "leads_for_web"),
 
+		// Company details used by message templates
+		CompanyName:    getEnv("COMPANY_NAME", "Valpro Intertech"),
+		CompanyWebsite: getEnv("COMPANY_WEBSITE", "valprointertech.com"),
+		CompanyEmail:   getEnv("COMPANY_EMAIL", "mail@valprointertech.com"),
+		CompanyPhone:   getEnv("COMPANY_PHONE", "+62 813-9971-0085"),
+
 		// Blog Automator
 		GroqAPIKey:                 getEnv("GROQ_API_KEY", ""),
 		PexelsAPIKey:               getEnv("PEXELS_API_KEY", ""),
@@ -74,6 +146,44 @@ func Load() *Config {
 		ContentfulEnvironment:      getEnv("CONTENTFUL_ENVIRONMENT", "master"),
 	}
 
+	// Backups (defaults depend on DATA_DIR and WEB_URL)
+	cfg.BackupDir = getEnv("BACKUP_DIR", filepath.Join(cfg.DataDir, "backups"))
+	cfg.BackupKeepDaily = getEnvInt("BACKUP_KEEP_DAILY", 7)
+	cfg.BackupKeepWeekly = getEnvInt("BACKUP_KEEP_WEEKLY", 4)
+	cfg.BackupPassphrase = getEnv("BACKUP_PASSPHRASE", "")
+	cfg.BackupRestoreURL = getEnv("BACKUP_RESTORE_URL", strings.TrimRight(cfg.WebURL, "/")+"/api/backup/restore")
+	cfg.BackupRestoreToken = getEnv("BACKUP_RESTORE_TOKEN", "")
+
+	// Invoice reminders (quiet hours are "start-end" in WIB, e.g. 21-8)
+	cfg.ReminderSourceURL = getEnv("REMINDER_SOURCE_URL", strings.TrimRight(cfg.WebURL, "/")+"/api/invoices/unpaid")
+	cfg.ReminderSourceToken = getEnv("REMINDER_SOURCE_TOKEN", "")
+	cfg.ReminderSchedule = getEnv("REMINDER_SCHEDULE", "0 * * * *")
+	if cfg.ReminderSchedule == "off" {
+		cfg.ReminderSchedule = ""
+	}
+	cfg.ReminderOverdueDays = getEnvIntList("REMINDER_OVERDUE_DAYS", []int{1, 3, 7, 14})
+	cfg.ReminderQuietStart, cfg.ReminderQuietEnd = getEnvHourRange("REMINDER_QUIET_HOURS", 21, 8)
+	cfg.ReminderLocale = getEnv("REMINDER_LOCALE", "id")
+
+	// Monitor (without a targets file, only WEB_URL/api/health is checked)
+	cfg.MonitorTargetsFile = getEnv("MONITOR_TARGETS_FILE", "")
+	cfg.MonitorAlertPhone = getEnv("MONITOR_ALERT_PHONE", cfg.BackupPhone)
+
+	// Blog Automator (topics are separated by ";")
+	cfg.ContentfulContentType = getEnv("CONTENTFUL_CONTENT_TYPE", "blogPost")
+	cfg.ContentfulLocale = getEnv("CONTENTFUL_LOCALE", "en-US")
+	cfg.BlogLLMURL = getEnv("BLOG_LLM_URL", "https://api.groq.com/openai/v1")
+	cfg.BlogLLMModel = getEnv("BLOG_LLM_MODEL", "llama-3.3-70b-versatile")
+	cfg.BlogTopics = parseList(getEnv("BLOG_TOPICS", ""), ";")
+	cfg.BlogSchedule = getEnv("BLOG_SCHEDULE", "0 9 * * 1")
+	if cfg.BlogSchedule == "off" {
+		cfg.BlogSchedule = ""
+	}
+	cfg.BlogAdminPhone = getEnv("BLOG_ADMIN_PHONE", cfg.BackupPhone)
+	cfg.BlogLanguage = getEnv("BLOG_LANGUAGE", "Indonesian")
+	cfg.BlogReviewTimeout = getEnvDuration("BLOG_REVIEW_TIMEOUT", 48*time.Hour)
+	cfg.BlogPostURL = getEnv("BLOG_POST_URL", strings.TrimRight(cfg.WebURL, "/")+"/blog")
+
 	return cfg
 }
 
@@ -84,11 +194,82 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		d, err := time.ParseDuration(value)
+		if err == nil && d > 0 {
+			return d
+		}
+		log.Printf("⚠️ Invalid %s=%q, using %s", key, value, defaultValue)
+	}
+	return defaultValue
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		n, err := strconv.Atoi(value)
+		if err == nil && n >= 0 {
+			return n
+		}
+		log.Printf("⚠️ Invalid %s=%q, using %d", key, value, defaultValue)
+	}
+	return defaultValue
+}
+
+// getEnvIntList parses an ascending, comma-separated list of positive integers
+func getEnvIntList(key string, defaultValue []int) []int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	var result []int
+	for _, item := range parseList(value, ",") {
+		n, err := strconv.Atoi(item)
+		if err != nil || n <= 0 || (len(result) > 0 && n <= result[len(result)-1]) {
+			log.Printf("⚠️ Invalid %s=%q, using %v", key, value, defaultValue)
+			return defaultValue
+		}
+		result = append(result, n)
+	}
+	if len(result) == 0 {
+		return defaultValue
+	}
+	return result
+}
+
+// getEnvHourRange parses "start-end" hours (0-23)
+func getEnvHourRange(key string, defaultStart, defaultEnd int) (int, int) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultStart, defaultEnd
+	}
+	parts := strings.Split(value, "-")
+	if len(parts) == 2 {
+		start, err1 := strconv.Atoi(strings.TrimSpace(parts[0]))
+		end, err2 := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err1 == nil && err2 == nil && start >= 0 && start < 24 && end >= 0 && end < 24 {
+			return start, end
+		}
+	}
+	log.Printf("⚠️ Invalid %s=%q, using %d-%d", key, value, defaultStart, defaultEnd)
+	return defaultStart, defaultEnd
+}
+
+// BlogConfigured reports whether the LLM and Contentful credentials are set
+func (c *Config) BlogConfigured() bool {
+	return c.GroqAPIKey != "" && c.ContentfulManagementToken != "" && c.ContentfulSpaceID != ""
+}
+
 func parseAllowedDomains(domainsStr string) []string {
-	domains := strings.Split(domainsStr, ",")
-	result := make([]string, 0, len(domains))
-	for _, d := range domains {
-		trimmed := strings.TrimSpace(d)
+	return parseList(domainsStr, ",")
+}
+
+// parseList splits s by sep and drops empty items
+func parseList(s, sep string) []string {
+	items := strings.Split(s, sep)
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		trimmed := strings.TrimSpace(item)
 		if trimmed != "" {
 			result = append(result, trimmed)
 		}
@@ -0,0 +1,141 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backupFileName matches the files written by RunBackup: BACKUP_<DATA|RESTORE>_<yyyymmdd>.<ext>
+var backupFileName = regexp.MustCompile(`^BACKUP_(DATA|RESTORE)_(\d{8})\.[a-z.]+$`)
+
+// StoredFile is a backup file kept in the local backup directory
+type StoredFile struct {
+	Name       string    `json:"name"`
+	Date       string    `json:"date"`
+	Size       int64     `json:"size"`
+	Encrypted  bool      `json:"encrypted"`
+	ModifiedAt time.Time `json:"modifiedAt"`
+}
+
+// StoredFiles lists the local backup files, newest first
+func (s *BackupService) StoredFiles() ([]StoredFile, error) {
+	entries, err := os.ReadDir(s.cfg.Dir)
+	if os.IsNotExist(err) {
+		return []StoredFile{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]StoredFile, 0, len(entries))
+	for _, entry := range entries {
+		m := backupFileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || m == nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, StoredFile{
+			Name:       entry.Name(),
+			Date:       m[2],
+			Size:       info.Size(),
+			Encrypted:  strings.Contains(entry.Name(), ".enc."),
+			ModifiedAt: info.ModTime(),
+		})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].Date != files[j].Date {
+			return files[i].Date > files[j].Date
+		}
+		return files[i].Name < files[j].Name
+	})
+	return files, nil
+}
+
+// ReadStored returns the content of a local backup file
+func (s *BackupService) ReadStored(name string) ([]byte, error) {
+	if name != filepath.Base(name) || !backupFileName.MatchString(name) {
+		return nil, fmt.Errorf("invalid backup file name %q", name)
+	}
+	return os.ReadFile(filepath.Join(s.cfg.Dir, name))
+}
+
+// saveLocal writes a backup file to the backup directory (replacing a file of the same day)
+func (s *BackupService) saveLocal(name string, data []byte) error {
+	if err := os.MkdirAll(s.cfg.Dir, 0o700); err != nil {
+		return err
+	}
+	path := filepath.Join(s.cfg.Dir, name)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// applyRetention deletes local backups that are neither among the newest KeepDaily days
+// nor the newest backup of one of the last KeepWeekly weeks. Both set to 0 keeps everything.
+func (s *BackupService) applyRetention() ([]string, error) {
+	if s.cfg.KeepDaily <= 0 && s.cfg.KeepWeekly <= 0 {
+		return nil, nil
+	}
+
+	files, err := s.StoredFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	keep := retainedDates(files, s.cfg.KeepDaily, s.cfg.KeepWeekly)
+	var removed []string
+	for _, f := range files {
+		if keep[f.Date] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.cfg.Dir, f.Name)); err != nil && !os.IsNotExist(err) {
+			return removed, err
+		}
+		removed = append(removed, f.Name)
+	}
+	return removed, nil
+}
+
+// retainedDates picks the backup days to keep from files sorted newest first
+func retainedDates(files []StoredFile, keepDaily, keepWeekly int) map[string]bool {
+	var dates []string
+	seen := make(map[string]bool)
+	for _, f := range files {
+		if !seen[f.Date] {
+			seen[f.Date] = true
+			dates = append(dates, f.Date)
+		}
+	}
+
+	keep := make(map[string]bool)
+	weeks := make(map[string]bool)
+	for i, date := range dates {
+		if i < keepDaily {
+			keep[date] = true
+		}
+
+		t, err := time.Parse("20060102", date)
+		if err != nil {
+			keep[date] = true // not ours to judge
+			continue
+		}
+		year, week := t.ISOWeek()
+		key := fmt.Sprintf("%d-%02d", year, week)
+		if !weeks[key] && len(weeks) < keepWeekly {
+			keep[date] = true
+		}
+		weeks[key] = true
+	}
+	return keep
+}
@@ -0,0 +1,60 @@
+package backup
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestRetainedDates(t *testing.T) {
+	// Newest first, as StoredFiles returns them. 2026-W01 runs from Mon 2025-12-29 to Sun 2026-01-04.
+	files := storedFiles(
+		"20260105", // Mon, 2026-W02
+		"20260104", // Sun, 2026-W01
+		"20260103", // Sat, 2026-W01
+		"20251229", // Mon, 2026-W01
+		"20251228", // Sun, 2025-W52
+		"20251221", // Sun, 2025-W51
+	)
+
+	tests := []struct {
+		name      string
+		files     []StoredFile
+		daily     int
+		weekly    int
+		wantDates []string
+	}{
+		{"daily only", files, 2, 0, []string{"20260104", "20260105"}},
+		{"weekly only keeps the newest day of each week", files, 0, 3, []string{"20251228", "20260104", "20260105"}},
+		{"week spanning the new year", files, 0, 2, []string{"20260104", "20260105"}},
+		{"daily and weekly overlap", files, 3, 2, []string{"20260103", "20260104", "20260105"}},
+		{"daily and weekly combined", files, 1, 4, []string{"20251221", "20251228", "20260104", "20260105"}},
+		{"more than stored", files, 10, 10, []string{"20251221", "20251228", "20251229", "20260103", "20260104", "20260105"}},
+		{"data and restore file share a day", storedFiles("20260105", "20260105", "20260104", "20260104", "20260103"), 2, 0, []string{"20260104", "20260105"}},
+		{"unparseable date kept", storedFiles("20260105", "2026xx01"), 1, 0, []string{"20260105", "2026xx01"}},
+		{"nothing stored", nil, 7, 4, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keep := retainedDates(tt.files, tt.daily, tt.weekly)
+			got := make([]string, 0, len(keep))
+			for date, ok := range keep {
+				if ok {
+					got = append(got, date)
+				}
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.wantDates) {
+				t.Errorf("retainedDates(daily=%d, weekly=%d) = %v, want %v", tt.daily, tt.weekly, got, tt.wantDates)
+			}
+		})
+	}
+}
+
+func storedFiles(dates ...string) []StoredFile {
+	files := make([]StoredFile, len(dates))
+	for i, date := range dates {
+		files[i] = StoredFile{Name: "BACKUP_DATA_" + date + ".json", Date: date}
+	}
+	return files
+}
@@ -1,45 +1,89 @@
 package backup
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
+	"wa-server-go/internal/whatsapp"
+
 	"github.com/robfig/cron/v3"
-	"github.com/xuri/excelize/v2"
 	"go.mau.fi/whatsmeow"
 	waProto "go.mau.fi/whatsmeow/proto/waE2E"
 	"go.mau.fi/whatsmeow/types"
 	"google.golang.org/protobuf/proto"
 )
 
+// ErrAlreadyRunning is returned when a backup is triggered while another one is in progress
+var ErrAlreadyRunning = errors.New("a backup is already running")
+
+const (
+	fetchTimeout = 2 * time.Minute
+	runTimeout   = 15 * time.Minute
+)
+
+// Config holds the backup settings
+type Config struct {
+	WebURL      string // data dump source: WebURL/api/backup/data-dump
+	BackupPhone string // recipient of the backup files
+
+	Dir        string // local copies of every backup
+	KeepDaily  int    // newest days kept in Dir
+	KeepWeekly int    // newest weeks (one backup each) kept in Dir
+
+	Passphrase string // encrypts the restore JSON with AES-GCM when set
+
+	RestoreURL   string // where POST /backups/restore replays a backup
+	RestoreToken string // sent as a Bearer token to RestoreURL
+}
+
 // BackupService handles scheduled backup tasks
 type BackupService struct {
-	waClient    *whatsmeow.Client
-	webURL      string
-	backupPhone string
-	cron        *cron.Cron
+	waManager  *whatsapp.Manager
+	clientID   string
+	cfg        Config
+	store      *Store
+	cron       *cron.Cron
+	httpClient *http.Client
+
+	mu      sync.Mutex
+	running *Run
+	wg      sync.WaitGroup
 }
 
-// NewBackupService creates a new backup service
-func NewBackupService(waClient *whatsmeow.Client, webURL, backupPhone string) *BackupService {
+// NewBackupService creates a new backup service. Files are sent through the WhatsApp
+// client clientID (looked up on every run, so re-pairing the session is picked up).
+func NewBackupService(waManager *whatsapp.Manager, clientID string, cfg Config, store *Store) *BackupService {
+	if cfg.Passphrase == "" {
+		log.Println("⚠️ [BACKUP] BACKUP_PASSPHRASE not set: restore files are sent unencrypted")
+	}
 	return &BackupService{
-		waClient:    waClient,
-		webURL:      webURL,
-		backupPhone: backupPhone,
-		cron:        cron.New(),
+		waManager:  waManager,
+		clientID:   clientID,
+		cfg:        cfg,
+		store:      store,
+		cron:       cron.New(),
+		httpClient: &http.Client{Timeout: fetchTimeout},
 	}
 }
 
 // Start starts the backup cron job (runs daily at 23:00 WIB)
 func (s *BackupService) Start() error {
+	if n, err := s.store.FailInterrupted(context.Background()); err != nil {
+		log.Printf("⚠️ [BACKUP] Failed to close interrupted runs: %v", err)
+	} else if n > 0 {
+		log.Printf("⚠️ [BACKUP] %d run(s) were interrupted by the last shutdown", n)
+	}
+
 	_, err := s.cron.AddFunc("0 23 * * *", func() {
 		log.Println("🔄 [BACKUP] Starting scheduled backup...")
-		if err := s.RunBackup(); err != nil {
+		if _, err := s.Trigger(TriggerScheduled); err != nil {
 			log.Printf("❌ [BACKUP] Failed: %v", err)
 		}
 	})
@@ -52,29 +96,144 @@ func (s *BackupService) Start() error {
 	return nil
 }
 
-// Stop stops the backup cron job
-func (s *BackupService) Stop() {
+// Stop stops the backup cron job and waits (until ctx expires) for a running backup
+func (s *BackupService) Stop(ctx context.Context) error {
 	s.cron.Stop()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("gave up waiting for the running backup: %w", ctx.Err())
+	}
 }
 
-// RunBackup executes the backup process
-func (s *BackupService) RunBackup() error {
-	ctx := context.Background()
+// Trigger starts a backup in the background and returns a copy of its run record.
+// Only one backup runs at a time; ErrAlreadyRunning is returned with the run in progress.
+func (s *BackupService) Trigger(trigger string) (*Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running != nil {
+		return s.running.clone(), ErrAlreadyRunning
+	}
+
+	run, err := s.store.Insert(context.Background(), trigger)
+	if err != nil {
+		return nil, err
+	}
+	s.running = run
+	s.wg.Add(1)
+
+	go func() {
+		defer s.wg.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), runTimeout)
+		defer cancel()
+
+		err := s.RunBackup(ctx, run)
+		s.finish(run, err)
+	}()
+
+	return run.clone(), nil
+}
+
+// TriggerManual runs backup immediately (for API trigger)
+func (s *BackupService) TriggerManual() (*Run, error) {
+	return s.Trigger(TriggerManual)
+}
+
+// Running returns a copy of the backup in progress, if any
+func (s *BackupService) Running() *Run {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running == nil {
+		return nil
+	}
+	return s.running.clone()
+}
+
+// Get returns a backup run by ID
+func (s *BackupService) Get(ctx context.Context, id string) (*Run, error) {
+	return s.store.Get(ctx, id)
+}
+
+// History returns recent backup runs, newest first
+func (s *BackupService) History(ctx context.Context, limit int) ([]*Run, error) {
+	return s.store.List(ctx, limit)
+}
+
+// finish records the outcome of a run and releases the running slot
+func (s *BackupService) finish(run *Run, err error) {
+	now := time.Now()
+	s.mu.Lock()
+	run.FinishedAt = &now
+	if err != nil {
+		run.Status = RunFailed
+		run.Error = err.Error()
+	} else {
+		run.Status = RunSucceeded
+	}
+	final := run.clone()
+	s.mu.Unlock()
+
+	if err != nil {
+		log.Printf("❌ [BACKUP] Run %s failed: %v", final.ID, err)
+	} else {
+		log.Printf("✅ [BACKUP] Run %s completed in %s", final.ID, now.Sub(final.StartedAt).Round(time.Second))
+	}
+
+	if err := s.store.Update(context.Background(), final); err != nil {
+		log.Printf("❌ [BACKUP] Failed to record run %s: %v", final.ID, err)
+	}
+
+	s.mu.Lock()
+	s.running = nil
+	s.mu.Unlock()
+}
+
+// RunBackup executes the backup process, recording the produced files on run.
+// run is only changed while holding the service's lock, so Running can copy it meanwhile.
+func (s *BackupService) RunBackup(ctx context.Context, run *Run) error {
 	timestamp := time.Now()
 	dateStr := timestamp.Format("20060102")
 
+	if s.cfg.BackupPhone == "" {
+		return fmt.Errorf("BACKUP_PHONE is not configured")
+	}
+	client, ok := s.waManager.GetClient(s.clientID)
+	if !ok || !client.IsReady() {
+		return fmt.Errorf("WhatsApp client %s is not ready", s.clientID)
+	}
+
 	// 1. Fetch data from web API
-	dataURL := fmt.Sprintf("%s/api/backup/data-dump", s.webURL)
+	dataURL := fmt.Sprintf("%s/api/backup/data-dump", s.cfg.WebURL)
 	log.Printf("📥 [BACKUP] Fetching data from %s", dataURL)
 
-	resp, err := http.Get(dataURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, dataURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to fetch backup data: %w", err)
+	}
+	resp, err := s.httpClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to fetch backup data: %w", err)
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch backup data: %s returned %d", dataURL, resp.StatusCode)
+	}
 
+	// Numbers are kept as written (json.Number) so IDs and amounts are not rounded through float64
 	var backupData map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&backupData); err != nil {
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&backupData); err != nil {
 		return fmt.Errorf("failed to parse backup data: %w", err)
 	}
 
@@ -84,33 +243,68 @@ func (s *BackupService) RunBackup() error {
 		return fmt.Errorf("failed to generate Excel: %w", err)
 	}
 
-	// 3. Generate JSON backup
+	// 3. Generate JSON backup (encrypted before it leaves the server when a passphrase is set)
 	jsonData, err := json.MarshalIndent(backupData, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to generate JSON: %w", err)
 	}
+	jsonFileName := fmt.Sprintf("BACKUP_RESTORE_%s.json", dateStr)
+	if s.cfg.Passphrase != "" {
+		if jsonData, err = encrypt(jsonData, s.cfg.Passphrase); err != nil {
+			return fmt.Errorf("failed to encrypt JSON: %w", err)
+		}
+		jsonFileName = fmt.Sprintf("BACKUP_RESTORE_%s.enc.json", dateStr)
+	}
 
-	// 4. Send Excel via WhatsApp
-	excelFileName := fmt.Sprintf("BACKUP_DATA_%s.xlsx", dateStr)
-	if err := s.sendFile(ctx, excelData, excelFileName, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"); err != nil {
-		log.Printf("⚠️ [BACKUP] Failed to send Excel: %v", err)
-	} else {
-		log.Println("✅ [BACKUP] Excel file sent")
+	// 4. Keep local copies and send Excel and JSON via WhatsApp
+	files := []struct {
+		name, mimeType string
+		data           []byte
+	}{
+		{fmt.Sprintf("BACKUP_DATA_%s.xlsx", dateStr), "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", excelData},
+		{jsonFileName, "application/json", jsonData},
 	}
 
-	// 5. Send JSON via WhatsApp
-	jsonFileName := fmt.Sprintf("BACKUP_RESTORE_%s.json", dateStr)
-	if err := s.sendFile(ctx, jsonData, jsonFileName, "application/json"); err != nil {
-		log.Printf("⚠️ [BACKUP] Failed to send JSON: %v", err)
-	} else {
-		log.Println("✅ [BACKUP] JSON file sent")
+	failed := 0
+	for _, f := range files {
+		file := File{Name: f.name, Size: len(f.data)}
+		var problems []string
+		if err := s.saveLocal(f.name, f.data); err != nil {
+			log.Printf("⚠️ [BACKUP] Failed to store %s: %v", f.name, err)
+			problems = append(problems, "store: "+err.Error())
+		} else {
+			file.Stored = true
+		}
+		if err := s.sendFile(ctx, client.WAClient, f.data, f.name, f.mimeType); err != nil {
+			log.Printf("⚠️ [BACKUP] Failed to send %s: %v", f.name, err)
+			problems = append(problems, "send: "+err.Error())
+		} else {
+			log.Printf("✅ [BACKUP] %s sent", f.name)
+			file.Sent = true
+		}
+		if len(problems) > 0 {
+			file.Error = strings.Join(problems, "; ")
+			failed++
+		}
+		s.mu.Lock()
+		run.Files = append(run.Files, file)
+		s.mu.Unlock()
+	}
+
+	if removed, err := s.applyRetention(); err != nil {
+		log.Printf("⚠️ [BACKUP] Retention failed: %v", err)
+	} else if len(removed) > 0 {
+		log.Printf("🧹 [BACKUP] Retention removed %d old file(s)", len(removed))
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d file(s) could not be stored or sent", failed, len(files))
 	}
 
-	// 6. Send completion notification
+	// 5. Send completion notification
 	notification := fmt.Sprintf("✅ *BACKUP BERHASIL*\n\n📁 Files: %s, %s\n🕐 Waktu: %s\n\nBackup data harian telah berhasil dikirim.",
-		excelFileName, jsonFileName, timestamp.Format("02 Jan 2006 15:04 WIB"))
-	jid := types.NewJID(s.backupPhone, types.DefaultUserServer)
-	_, _ = s.waClient.SendMessage(ctx, jid, &waProto.Message{
+		files[0].name, files[1].name, timestamp.Format("02 Jan 2006 15:04 WIB"))
+	jid := types.NewJID(s.cfg.BackupPhone, types.DefaultUserServer)
+	_, _ = client.WAClient.SendMessage(ctx, jid, &waProto.Message{
 		Conversation: proto.String(notification),
 	})
 
@@ -118,66 +312,17 @@ func (s *BackupService) RunBackup() error {
 	return nil
 }
 
-// generateExcel creates an Excel file from backup data
-func (s *BackupService) generateExcel(data map[string]interface{}, dateStr string) ([]byte, error) {
-	f := excelize.NewFile()
-	defer f.Close()
-
-	// Create sheets for each data type
-	for sheetName, sheetData := range data {
-		// Create sheet
-		_, err := f.NewSheet(sheetName)
-		if err != nil {
-			continue
-		}
-
-		// Write data based on type
-		if arr, ok := sheetData.([]interface{}); ok {
-			for i, row := range arr {
-				if rowMap, ok := row.(map[string]interface{}); ok {
-					col := 'A'
-					for key, value := range rowMap {
-						if i == 0 {
-							// Write header
-							headerCell := fmt.Sprintf("%c%d", col, 1)
-							f.SetCellValue(sheetName, headerCell, key)
-						}
-						dataCell := fmt.Sprintf("%c%d", col, i+2)
-						f.SetCellValue(sheetName, dataCell, fmt.Sprintf("%v", value))
-						col++
-					}
-				}
-			}
-		}
-	}
-
-	// Delete default sheet
-	f.DeleteSheet("Sheet1")
-
-	// Write to buffer
-	var buf bytes.Buffer
-	if err := f.Write(&buf); err != nil {
-		return nil, err
-	}
-
-	return buf.Bytes(), nil
-}
-
 // sendFile uploads and sends a file via WhatsApp
-func (s *BackupService) sendFile(ctx context.Context, data []byte, fileName, mimeType string) error {
-	if s.waClient == nil {
-		return fmt.Errorf("WhatsApp client not available")
-	}
-
+func (s *BackupService) sendFile(ctx context.Context, waClient *whatsmeow.Client, data []byte, fileName, mimeType string) error {
 	// Upload file
-	uploaded, err := s.waClient.Upload(ctx, data, whatsmeow.MediaDocument)
+	uploaded, err := waClient.Upload(ctx, data, whatsmeow.MediaDocument)
 	if err != nil {
 		return fmt.Errorf("failed to upload file: %w", err)
 	}
 
 	// Send document
-	jid := types.NewJID(s.backupPhone, types.DefaultUserServer)
-	_, err = s.waClient.SendMessage(ctx, jid, &waProto.Message{
+	jid := types.NewJID(s.cfg.BackupPhone, types.DefaultUserServer)
+	_, err = waClient.SendMessage(ctx, jid, &waProto.Message{
 		DocumentMessage: &waProto.DocumentMessage{
 			URL:           proto.String(uploaded.URL),
 			Mimetype:      proto.String(mimeType),
@@ -193,8 +338,3 @@ func (s *BackupService) sendFile(ctx context.Context, data []byte, fileName, mim
 
 	return err
 }
-
-// TriggerManual runs backup immediately (for API trigger)
-func (s *BackupService) TriggerManual() error {
-	return s.RunBackup()
-}
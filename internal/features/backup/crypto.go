@@ -0,0 +1,99 @@
+package backup
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// encryptedFormat identifies a passphrase-encrypted backup envelope
+const encryptedFormat = "wa-backup-aes256gcm-v1"
+
+const (
+	kdfIterations = 600000
+	saltSize      = 16
+)
+
+// ErrPassphrase is returned when an encrypted backup cannot be opened with the given passphrase
+var ErrPassphrase = errors.New("wrong passphrase or corrupted backup")
+
+// encryptedBackup is the JSON envelope of an encrypted restore file. The key is derived
+// from the passphrase with PBKDF2-SHA256; the payload is sealed with AES-256-GCM.
+type encryptedBackup struct {
+	Format     string `json:"format"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// encrypt seals plaintext with a key derived from passphrase
+func encrypt(plaintext []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(passphrase, salt, kdfIterations)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return json.MarshalIndent(encryptedBackup{
+		Format:     encryptedFormat,
+		KDF:        "pbkdf2-sha256",
+		Iterations: kdfIterations,
+		Salt:       salt,
+		Nonce:      nonce,
+		Ciphertext: gcm.Seal(nil, nonce, plaintext, []byte(encryptedFormat)),
+	}, "", "  ")
+}
+
+// decrypt opens an envelope produced by encrypt
+func decrypt(env *encryptedBackup, passphrase string) ([]byte, error) {
+	if env.KDF != "pbkdf2-sha256" || env.Iterations <= 0 {
+		return nil, fmt.Errorf("unsupported key derivation %q", env.KDF)
+	}
+	gcm, err := newGCM(passphrase, env.Salt, env.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	if len(env.Nonce) != gcm.NonceSize() {
+		return nil, ErrPassphrase
+	}
+	plaintext, err := gcm.Open(nil, env.Nonce, env.Ciphertext, []byte(env.Format))
+	if err != nil {
+		return nil, ErrPassphrase
+	}
+	return plaintext, nil
+}
+
+// parseEncrypted returns the envelope if data is an encrypted backup, nil otherwise
+func parseEncrypted(data []byte) *encryptedBackup {
+	var env encryptedBackup
+	if err := json.Unmarshal(data, &env); err != nil || env.Format != encryptedFormat {
+		return nil
+	}
+	return &env
+}
+
+func newGCM(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
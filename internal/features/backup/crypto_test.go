@@ -0,0 +1,92 @@
+package backup
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestEncryptRoundTrip(t *testing.T) {
+	plaintext := []byte(`{"invoices":[{"number":"INV-1","total":150000}]}`)
+
+	sealed, err := encrypt(plaintext, "correct horse")
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if bytes.Contains(sealed, []byte("INV-1")) {
+		t.Fatal("encrypted backup contains the plaintext")
+	}
+
+	env := parseEncrypted(sealed)
+	if env == nil {
+		t.Fatal("parseEncrypted did not recognize an encrypted backup")
+	}
+	got, err := decrypt(env, "correct horse")
+	if err != nil {
+		t.Fatalf("decrypt: %v", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("decrypt = %s, want %s", got, plaintext)
+	}
+
+	if parseEncrypted(plaintext) != nil {
+		t.Error("parseEncrypted recognized a plain data dump as encrypted")
+	}
+}
+
+func TestDecryptRejects(t *testing.T) {
+	sealed, err := encrypt([]byte(`{"chats":[]}`), "secret")
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+
+	// each case gets its own copy of the envelope
+	envelope := func() *encryptedBackup {
+		var env encryptedBackup
+		if err := json.Unmarshal(sealed, &env); err != nil {
+			t.Fatalf("unmarshal envelope: %v", err)
+		}
+		return &env
+	}
+
+	tests := []struct {
+		name       string
+		passphrase string
+		modify     func(env *encryptedBackup)
+	}{
+		{"wrong passphrase", "Secret", func(env *encryptedBackup) {}},
+		{"empty passphrase", "", func(env *encryptedBackup) {}},
+		{"tampered ciphertext", "secret", func(env *encryptedBackup) { env.Ciphertext[0] ^= 0x01 }},
+		{"truncated ciphertext", "secret", func(env *encryptedBackup) { env.Ciphertext = env.Ciphertext[:len(env.Ciphertext)-1] }},
+		{"empty ciphertext", "secret", func(env *encryptedBackup) { env.Ciphertext = nil }},
+		{"tampered salt", "secret", func(env *encryptedBackup) { env.Salt[0] ^= 0x01 }},
+		{"tampered nonce", "secret", func(env *encryptedBackup) { env.Nonce[0] ^= 0x01 }},
+		{"short nonce", "secret", func(env *encryptedBackup) { env.Nonce = env.Nonce[:4] }},
+		{"other format", "secret", func(env *encryptedBackup) { env.Format = "wa-backup-aes256gcm-v2" }},
+		{"lower iterations", "secret", func(env *encryptedBackup) { env.Iterations = 1000 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := envelope()
+			tt.modify(env)
+			if _, err := decrypt(env, tt.passphrase); !errors.Is(err, ErrPassphrase) {
+				t.Errorf("decrypt error = %v, want ErrPassphrase", err)
+			}
+		})
+	}
+
+	t.Run("unknown key derivation", func(t *testing.T) {
+		env := envelope()
+		env.KDF = "scrypt"
+		if _, err := decrypt(env, "secret"); err == nil || errors.Is(err, ErrPassphrase) {
+			t.Errorf("decrypt error = %v, want an unsupported key derivation error", err)
+		}
+	})
+
+	t.Run("truncated file", func(t *testing.T) {
+		if env := parseEncrypted(sealed[:len(sealed)/2]); env != nil {
+			t.Error("parseEncrypted accepted a truncated file")
+		}
+	})
+}
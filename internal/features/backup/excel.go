@@ -0,0 +1,317 @@
+package backup
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// columnsKey is an optional top-level key of the data dump that declares the column order
+// per sheet, e.g. {"_columns": {"invoices": ["number", "customer", "total"]}}.
+// Declared columns come first; any other keys found in the rows follow in sorted order.
+const columnsKey = "_columns"
+
+const (
+	minColWidth = 8
+	maxColWidth = 60
+
+	// Excel keeps 15 significant digits; larger integers (IDs) are written as text
+	maxExactInt = 1e15
+)
+
+// dateLayouts are the string formats recognized as dates, with whether they carry a time
+var dateLayouts = []struct {
+	layout  string
+	hasTime bool
+}{
+	{time.RFC3339Nano, true},
+	{"2006-01-02T15:04:05", true},
+	{"2006-01-02 15:04:05", true},
+	{"2006-01-02", false},
+}
+
+// sheetStyles holds the style IDs shared by all sheets of a workbook
+type sheetStyles struct {
+	header   int
+	date     int
+	dateTime int
+}
+
+// generateExcel creates an Excel file from backup data: one sheet per array of objects,
+// sheets in name order, a stable column schema, native cell types, a frozen header row,
+// an auto-filter and fitted column widths
+func (s *BackupService) generateExcel(data map[string]interface{}, dateStr string) ([]byte, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	styles, err := newSheetStyles(f)
+	if err != nil {
+		return nil, err
+	}
+	declared := declaredColumns(data[columnsKey])
+
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		if !strings.HasPrefix(key, "_") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	used := make(map[string]bool)
+	for _, key := range keys {
+		arr, ok := data[key].([]interface{})
+		if !ok {
+			continue
+		}
+		rows := make([]map[string]interface{}, 0, len(arr))
+		for _, row := range arr {
+			if rowMap, ok := row.(map[string]interface{}); ok {
+				rows = append(rows, rowMap)
+			}
+		}
+
+		sheet := sheetName(key, used)
+		if _, err := f.NewSheet(sheet); err != nil {
+			return nil, fmt.Errorf("failed to create sheet %s: %w", sheet, err)
+		}
+		if err := writeSheet(f, sheet, columnsFor(rows, declared[key]), rows, styles); err != nil {
+			return nil, fmt.Errorf("failed to write sheet %s: %w", sheet, err)
+		}
+	}
+
+	// Delete default sheet (kept only when the dump had no tables)
+	if len(used) > 0 {
+		f.DeleteSheet("Sheet1")
+		f.SetActiveSheet(0)
+	}
+
+	// Write to buffer
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeSheet writes a header row and one row per record into sheet
+func writeSheet(f *excelize.File, sheet string, columns []string, rows []map[string]interface{}, styles sheetStyles) error {
+	if len(columns) == 0 {
+		return nil
+	}
+
+	widths := make([]int, len(columns))
+	header := make([]interface{}, len(columns))
+	for i, col := range columns {
+		widths[i] = utf8.RuneCountInString(col)
+		header[i] = col
+	}
+	if err := f.SetSheetRow(sheet, "A1", &header); err != nil {
+		return err
+	}
+
+	values := make([]interface{}, len(columns))
+	cellStyles := make([]int, len(columns))
+	for r, row := range rows {
+		for i, col := range columns {
+			value, style, width := cellValue(row[col], styles)
+			values[i], cellStyles[i] = value, style
+			if width > widths[i] {
+				widths[i] = width
+			}
+		}
+
+		cell, _ := excelize.CoordinatesToCellName(1, r+2)
+		if err := f.SetSheetRow(sheet, cell, &values); err != nil {
+			return err
+		}
+		for i, style := range cellStyles {
+			if style == 0 {
+				continue
+			}
+			ref, _ := excelize.CoordinatesToCellName(i+1, r+2)
+			if err := f.SetCellStyle(sheet, ref, ref, style); err != nil {
+				return err
+			}
+		}
+	}
+
+	last, _ := excelize.ColumnNumberToName(len(columns))
+	if err := f.SetCellStyle(sheet, "A1", last+"1", styles.header); err != nil {
+		return err
+	}
+	for i, width := range widths {
+		width += 2
+		if width < minColWidth {
+			width = minColWidth
+		}
+		if width > maxColWidth {
+			width = maxColWidth
+		}
+		col, _ := excelize.ColumnNumberToName(i + 1)
+		if err := f.SetColWidth(sheet, col, col, float64(width)); err != nil {
+			return err
+		}
+	}
+
+	if err := f.SetPanes(sheet, &excelize.Panes{
+		Freeze:      true,
+		YSplit:      1,
+		TopLeftCell: "A2",
+		ActivePane:  "bottomLeft",
+	}); err != nil {
+		return err
+	}
+	return f.AutoFilter(sheet, fmt.Sprintf("A1:%s%d", last, len(rows)+1), nil)
+}
+
+// cellValue converts a JSON value to a native cell value and returns the style it needs
+// (0 for none) and its display width
+func cellValue(v interface{}, styles sheetStyles) (interface{}, int, int) {
+	switch val := v.(type) {
+	case nil:
+		return nil, 0, 0
+	case bool:
+		return val, 0, 5
+	case json.Number:
+		if n, err := val.Int64(); err == nil {
+			if n > -maxExactInt && n < maxExactInt {
+				return n, 0, len(val.String())
+			}
+			return val.String(), 0, len(val.String())
+		}
+		if n, err := val.Float64(); err == nil {
+			return n, 0, len(val.String())
+		}
+		return val.String(), 0, len(val.String())
+	case float64:
+		if val == math.Trunc(val) && math.Abs(val) < maxExactInt {
+			return int64(val), 0, len(fmt.Sprintf("%d", int64(val)))
+		}
+		return val, 0, len(fmt.Sprintf("%v", val))
+	case string:
+		for _, d := range dateLayouts {
+			// Timestamps are shown in server time (WIB); values without a zone are taken as local
+			t, err := time.ParseInLocation(d.layout, val, time.Local)
+			if err != nil {
+				continue
+			}
+			if d.hasTime {
+				return t.In(time.Local), styles.dateTime, 16
+			}
+			return t, styles.date, 10
+		}
+		return val, 0, utf8.RuneCountInString(val)
+	default:
+		// Nested objects and arrays are kept as compact JSON
+		b, err := json.Marshal(val)
+		if err != nil {
+			return fmt.Sprintf("%v", val), 0, 0
+		}
+		return string(b), 0, utf8.RuneCountInString(string(b))
+	}
+}
+
+// columnsFor returns the declared columns followed by every other key found in rows, sorted
+func columnsFor(rows []map[string]interface{}, declared []string) []string {
+	seen := make(map[string]bool)
+	columns := make([]string, 0, len(declared))
+	for _, col := range declared {
+		if !seen[col] {
+			seen[col] = true
+			columns = append(columns, col)
+		}
+	}
+
+	var extra []string
+	for _, row := range rows {
+		for key := range row {
+			if !seen[key] {
+				seen[key] = true
+				extra = append(extra, key)
+			}
+		}
+	}
+	sort.Strings(extra)
+	return append(columns, extra...)
+}
+
+// declaredColumns parses the optional _columns entry of the data dump
+func declaredColumns(v interface{}) map[string][]string {
+	declared := make(map[string][]string)
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return declared
+	}
+	for sheet, cols := range m {
+		arr, ok := cols.([]interface{})
+		if !ok {
+			continue
+		}
+		for _, col := range arr {
+			if name, ok := col.(string); ok {
+				declared[sheet] = append(declared[sheet], name)
+			}
+		}
+	}
+	return declared
+}
+
+// sheetName makes a key a valid, unique Excel sheet name (max 31 chars, no []:*?/\)
+func sheetName(key string, used map[string]bool) string {
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, strings.Trim(key, "'"))
+	if name == "" || strings.EqualFold(name, "Sheet1") {
+		name = "Data"
+	}
+	name = truncateRunes(name, 31)
+
+	candidate := name
+	for i := 2; used[strings.ToLower(candidate)]; i++ {
+		suffix := fmt.Sprintf(" (%d)", i)
+		candidate = truncateRunes(name, 31-len(suffix)) + suffix
+	}
+	used[strings.ToLower(candidate)] = true
+	return candidate
+}
+
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+func newSheetStyles(f *excelize.File) (sheetStyles, error) {
+	var styles sheetStyles
+	var err error
+
+	styles.header, err = f.NewStyle(&excelize.Style{
+		Font:   &excelize.Font{Bold: true},
+		Fill:   excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"D9E1F2"}},
+		Border: []excelize.Border{{Type: "bottom", Color: "8EA9DB", Style: 1}},
+	})
+	if err != nil {
+		return styles, err
+	}
+
+	dateFmt := "yyyy-mm-dd"
+	if styles.date, err = f.NewStyle(&excelize.Style{CustomNumFmt: &dateFmt}); err != nil {
+		return styles, err
+	}
+	dateTimeFmt := "yyyy-mm-dd hh:mm"
+	styles.dateTime, err = f.NewStyle(&excelize.Style{CustomNumFmt: &dateTimeFmt})
+	return styles, err
+}
@@ -0,0 +1,127 @@
+package backup
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestColumnsFor(t *testing.T) {
+	rows := []map[string]interface{}{
+		{"total": 1, "number": "INV-1"},
+		{"customer": "Budi", "number": "INV-2", "dueDate": "2026-01-31"},
+		{"notes": "late"},
+	}
+
+	tests := []struct {
+		name     string
+		rows     []map[string]interface{}
+		declared []string
+		want     []string
+	}{
+		{"sorted union of keys", rows, nil, []string{"customer", "dueDate", "notes", "number", "total"}},
+		{"declared first", rows, []string{"number", "customer", "total"}, []string{"number", "customer", "total", "dueDate", "notes"}},
+		{"declared without rows", nil, []string{"number", "missing"}, []string{"number", "missing"}},
+		{"duplicate declared", rows[:1], []string{"total", "total"}, []string{"total", "number"}},
+		{"no rows", nil, nil, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Map iteration order varies, so the same input must give the same columns every time
+			for i := 0; i < 20; i++ {
+				if got := columnsFor(tt.rows, tt.declared); !reflect.DeepEqual(got, tt.want) {
+					t.Fatalf("columnsFor = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSheetName(t *testing.T) {
+	long := strings.Repeat("x", 40)
+
+	tests := []struct {
+		name string
+		keys []string
+		want []string
+	}{
+		{"plain", []string{"invoices"}, []string{"invoices"}},
+		{"forbidden characters", []string{`a[b]c:d*e?f/g\h`}, []string{"a_b_c_d_e_f_g_h"}},
+		{"quotes trimmed", []string{"'chats'"}, []string{"chats"}},
+		{"empty", []string{""}, []string{"Data"}},
+		{"reserved default sheet", []string{"sheet1"}, []string{"Data"}},
+		{"31 character limit", []string{long}, []string{long[:31]}},
+		{"duplicate names", []string{"a/b", "a:b", "A_B"}, []string{"a_b", "a_b (2)", "A_B (3)"}},
+		{"duplicate long names", []string{long, long + "y"}, []string{long[:31], long[:27] + " (2)"}},
+		{"multibyte", []string{strings.Repeat("é", 40)}, []string{strings.Repeat("é", 31)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			used := make(map[string]bool)
+			for i, key := range tt.keys {
+				got := sheetName(key, used)
+				if got != tt.want[i] {
+					t.Errorf("sheetName(%q) = %q, want %q", key, got, tt.want[i])
+				}
+				if n := len([]rune(got)); n > 31 {
+					t.Errorf("sheetName(%q) has %d characters, want at most 31", key, n)
+				}
+			}
+		})
+	}
+}
+
+func TestCellValue(t *testing.T) {
+	wib := time.FixedZone("WIB", 7*3600)
+	local := time.Local
+	time.Local = wib
+	defer func() { time.Local = local }()
+
+	styles := sheetStyles{header: 1, date: 2, dateTime: 3}
+
+	tests := []struct {
+		name      string
+		in        interface{}
+		want      interface{}
+		wantStyle int
+	}{
+		{"nil", nil, nil, 0},
+		{"bool", true, true, 0},
+		{"integer float", float64(42), int64(42), 0},
+		{"fraction", 12.5, 12.5, 0},
+		{"json integer", json.Number("1500000"), int64(1500000), 0},
+		{"json fraction", json.Number("0.25"), 0.25, 0},
+		{"large id kept as text", json.Number("6281234567890123"), "6281234567890123", 0},
+		{"plain string", "hello", "hello", 0},
+		{"numeric string stays text", "0812", "0812", 0},
+		{"date", "2026-01-31", time.Date(2026, 1, 31, 0, 0, 0, 0, wib), 2},
+		{"rfc3339 utc in wib", "2026-01-31T03:04:05Z", time.Date(2026, 1, 31, 10, 4, 5, 0, wib), 3},
+		{"rfc3339 offset in wib", "2026-01-31T10:04:05+09:00", time.Date(2026, 1, 31, 8, 4, 5, 0, wib), 3},
+		{"local datetime", "2026-01-31 10:04:05", time.Date(2026, 1, 31, 10, 4, 5, 0, wib), 3},
+		{"nested object", map[string]interface{}{"b": 1, "a": "x"}, `{"a":"x","b":1}`, 0},
+		{"array", []interface{}{"a", float64(1)}, `["a",1]`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, style, _ := cellValue(tt.in, styles)
+			if style != tt.wantStyle {
+				t.Errorf("style = %d, want %d", style, tt.wantStyle)
+			}
+			if want, ok := tt.want.(time.Time); ok {
+				gotTime, ok := got.(time.Time)
+				if !ok {
+					t.Fatalf("cellValue(%v) = %T %v, want a time", tt.in, got, got)
+				}
+				if !gotTime.Equal(want) || gotTime.Hour() != want.Hour() {
+					t.Errorf("cellValue(%v) = %v, want %v", tt.in, gotTime, want)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("cellValue(%v) = %T %v, want %T %v", tt.in, got, got, tt.want, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	restoreTimeout = 5 * time.Minute
+	maxProblems    = 20
+)
+
+// ValidationError lists what is wrong with a restore file
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid backup: " + strings.Join(e.Problems, "; ")
+}
+
+// RestoreResult describes a validated (and, unless dry run, replayed) restore file
+type RestoreResult struct {
+	DryRun     bool           `json:"dryRun"`
+	Encrypted  bool           `json:"encrypted"`
+	Tables     map[string]int `json:"tables"`
+	Rows       int            `json:"rows"`
+	Target     string         `json:"target,omitempty"`
+	StatusCode int            `json:"statusCode,omitempty"`
+	Response   string         `json:"response,omitempty"`
+}
+
+// ReplayError is returned when the restore endpoint rejects or cannot receive the backup
+type ReplayError struct {
+	Result *RestoreResult
+	Err    error
+}
+
+func (e *ReplayError) Error() string {
+	return fmt.Sprintf("restore endpoint failed: %v", e.Err)
+}
+
+// Restore validates a BACKUP_RESTORE file (decrypting it if needed) and, unless dryRun,
+// posts the data to the configured restore endpoint of the web app.
+// An empty passphrase falls back to BACKUP_PASSPHRASE.
+func (s *BackupService) Restore(ctx context.Context, data []byte, passphrase string, dryRun bool) (*RestoreResult, error) {
+	result := &RestoreResult{DryRun: dryRun, Tables: map[string]int{}}
+
+	if env := parseEncrypted(data); env != nil {
+		result.Encrypted = true
+		if passphrase == "" {
+			passphrase = s.cfg.Passphrase
+		}
+		if passphrase == "" {
+			return nil, &ValidationError{Problems: []string{"backup is encrypted but no passphrase was given"}}
+		}
+		plaintext, err := decrypt(env, passphrase)
+		if err != nil {
+			return nil, &ValidationError{Problems: []string{err.Error()}}
+		}
+		data = plaintext
+	}
+
+	if err := validateRestore(data, result); err != nil {
+		return nil, err
+	}
+	if dryRun {
+		log.Printf("🔍 [BACKUP] Restore dry run: %d table(s), %d row(s)", len(result.Tables), result.Rows)
+		return result, nil
+	}
+
+	result.Target = s.cfg.RestoreURL
+	if result.Target == "" {
+		return nil, fmt.Errorf("BACKUP_RESTORE_URL is not configured")
+	}
+	log.Printf("♻️ [BACKUP] Replaying restore (%d row(s)) to %s", result.Rows, result.Target)
+
+	ctx, cancel := context.WithTimeout(ctx, restoreTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, result.Target, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.cfg.RestoreToken != "" {
+		req.Header.Set("Authorization", "Bearer "+s.cfg.RestoreToken)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, &ReplayError{Result: result, Err: err}
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	result.StatusCode = resp.StatusCode
+	result.Response = string(bytes.TrimSpace(body))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &ReplayError{Result: result, Err: fmt.Errorf("status %d", resp.StatusCode)}
+	}
+	log.Printf("✅ [BACKUP] Restore accepted by %s (%d)", result.Target, resp.StatusCode)
+	return result, nil
+}
+
+// validateRestore checks that data has the shape of a data dump: a JSON object whose
+// arrays (the tables) hold objects. Other top-level values are metadata and are passed through.
+func validateRestore(data []byte, result *RestoreResult) error {
+	var dump map[string]json.RawMessage
+	if err := json.Unmarshal(data, &dump); err != nil {
+		return &ValidationError{Problems: []string{"not a JSON object: " + err.Error()}}
+	}
+
+	keys := make([]string, 0, len(dump))
+	for key := range dump {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var problems []string
+	for _, key := range keys {
+		raw := bytes.TrimSpace(dump[key])
+		if strings.HasPrefix(key, "_") || len(raw) == 0 || raw[0] != '[' {
+			continue
+		}
+
+		var rows []json.RawMessage
+		if err := json.Unmarshal(raw, &rows); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", key, err))
+			continue
+		}
+		for i, row := range rows {
+			row = bytes.TrimSpace(row)
+			if len(row) == 0 || row[0] != '{' {
+				problems = append(problems, fmt.Sprintf("%s[%d]: expected an object", key, i))
+				if len(problems) >= maxProblems {
+					break
+				}
+			}
+		}
+		result.Tables[key] = len(rows)
+		result.Rows += len(rows)
+
+		if len(problems) >= maxProblems {
+			problems = append(problems, "too many problems, stopped checking")
+			break
+		}
+	}
+
+	if len(result.Tables) == 0 {
+		problems = append(problems, "no tables found (expected arrays of records)")
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
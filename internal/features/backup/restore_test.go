@@ -0,0 +1,62 @@
+package backup
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateRestore(t *testing.T) {
+	result := &RestoreResult{Tables: map[string]int{}}
+	data := `{"_columns":{"invoices":["number"]},"exportedAt":"2026-01-05","invoices":[{"number":"INV-1"},{"number":"INV-2"}],"chats":[]}`
+	if err := validateRestore([]byte(data), result); err != nil {
+		t.Fatalf("validateRestore: %v", err)
+	}
+	if want := map[string]int{"invoices": 2, "chats": 0}; !reflect.DeepEqual(result.Tables, want) {
+		t.Errorf("Tables = %v, want %v", result.Tables, want)
+	}
+	if result.Rows != 2 {
+		t.Errorf("Rows = %d, want 2", result.Rows)
+	}
+}
+
+func TestValidateRestoreRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		problem string
+	}{
+		{"not json", `BACKUP`, "not a JSON object"},
+		{"truncated", `{"invoices":[{"number":"INV-1"}`, "not a JSON object"},
+		{"top-level array", `[{"number":"INV-1"}]`, "not a JSON object"},
+		{"no tables", `{"exportedAt":"2026-01-05","_columns":{}}`, "no tables found"},
+		{"empty object", `{}`, "no tables found"},
+		{"row is not an object", `{"invoices":[{"number":"INV-1"},"INV-2"]}`, "invoices[1]: expected an object"},
+		{"null row", `{"invoices":[null]}`, "invoices[0]: expected an object"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRestore([]byte(tt.data), &RestoreResult{Tables: map[string]int{}})
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("validateRestore error = %v, want a ValidationError", err)
+			}
+			if !strings.Contains(verr.Error(), tt.problem) {
+				t.Errorf("validateRestore error = %q, want it to mention %q", verr.Error(), tt.problem)
+			}
+		})
+	}
+
+	t.Run("problems are capped", func(t *testing.T) {
+		rows := strings.Repeat(`1,`, 2*maxProblems) + `1`
+		err := validateRestore([]byte(`{"numbers":[`+rows+`]}`), &RestoreResult{Tables: map[string]int{}})
+		var verr *ValidationError
+		if !errors.As(err, &verr) {
+			t.Fatalf("validateRestore error = %v, want a ValidationError", err)
+		}
+		if n := len(verr.Problems); n > maxProblems+1 {
+			t.Errorf("got %d problems, want at most %d", n, maxProblems+1)
+		}
+	})
+}
@@ -0,0 +1,193 @@
+package backup
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RunStatus represents the state of a backup run
+type RunStatus string
+
+const (
+	RunRunning   RunStatus = "running"
+	RunSucceeded RunStatus = "succeeded"
+	RunFailed    RunStatus = "failed"
+)
+
+// Run triggers
+const (
+	TriggerScheduled = "scheduled"
+	TriggerManual    = "manual"
+)
+
+// ErrNotFound is returned when a run ID does not exist
+var ErrNotFound = errors.New("backup run not found")
+
+// File is one file produced by a backup run
+type File struct {
+	Name   string `json:"name"`
+	Size   int    `json:"size"`
+	Stored bool   `json:"stored"`
+	Sent   bool   `json:"sent"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Run is the record of one backup execution
+type Run struct {
+	ID         string     `json:"id"`
+	Trigger    string     `json:"trigger"`
+	Status     RunStatus  `json:"status"`
+	Files      []File     `json:"files"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+}
+
+// clone returns a copy of the run that shares no memory with it
+func (r *Run) clone() *Run {
+	c := *r
+	c.Files = append([]File{}, r.Files...)
+	if r.FinishedAt != nil {
+		finished := *r.FinishedAt
+		c.FinishedAt = &finished
+	}
+	return &c
+}
+
+// Store persists the backup run history in SQLite
+type Store struct {
+	db *sql.DB
+}
+
+const schema = `
+CREATE TABLE IF NOT EXISTS backup_runs (
+	seq         INTEGER PRIMARY KEY AUTOINCREMENT,
+	id          TEXT NOT NULL UNIQUE,
+	trigger     TEXT NOT NULL,
+	status      TEXT NOT NULL,
+	files       TEXT NOT NULL DEFAULT '[]',
+	error       TEXT NOT NULL DEFAULT '',
+	started_at  INTEGER NOT NULL,
+	finished_at INTEGER NOT NULL DEFAULT 0
+);
+`
+
+const runColumns = `id, trigger, status, files, error, started_at, finished_at`
+
+// NewStore creates the backup tables if needed
+func NewStore(db *sql.DB) (*Store, error) {
+	if _, err := db.Exec(schema); err != nil {
+		return nil, fmt.Errorf("failed to create backup schema: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Insert records a new running backup
+func (s *Store) Insert(ctx context.Context, trigger string) (*Run, error) {
+	run := &Run{
+		ID:        newRunID(),
+		Trigger:   trigger,
+		Status:    RunRunning,
+		Files:     []File{},
+		StartedAt: time.Now(),
+	}
+	_, err := s.db.ExecContext(ctx, `INSERT INTO backup_runs (id, trigger, status, started_at) VALUES (?, ?, ?, ?)`,
+		run.ID, run.Trigger, string(run.Status), run.StartedAt.UnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert backup run: %w", err)
+	}
+	return run, nil
+}
+
+// Update saves the status, files and error of a run
+func (s *Store) Update(ctx context.Context, run *Run) error {
+	files, err := json.Marshal(run.Files)
+	if err != nil {
+		return err
+	}
+	var finishedAt int64
+	if run.FinishedAt != nil {
+		finishedAt = run.FinishedAt.UnixMilli()
+	}
+	_, err = s.db.ExecContext(ctx, `UPDATE backup_runs SET status = ?, files = ?, error = ?, finished_at = ? WHERE id = ?`,
+		string(run.Status), string(files), run.Error, finishedAt, run.ID)
+	return err
+}
+
+// Get returns a run by ID
+func (s *Store) Get(ctx context.Context, id string) (*Run, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+runColumns+` FROM backup_runs WHERE id = ?`, id)
+	run, err := scanRun(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return run, err
+}
+
+// List returns runs newest first
+func (s *Store) List(ctx context.Context, limit int) ([]*Run, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+runColumns+` FROM backup_runs ORDER BY seq DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := make([]*Run, 0)
+	for rows.Next() {
+		run, err := scanRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+// FailInterrupted marks runs left "running" by a previous process as failed
+func (s *Store) FailInterrupted(ctx context.Context) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `UPDATE backup_runs SET status = ?, error = ?, finished_at = ? WHERE status = ?`,
+		string(RunFailed), "interrupted by shutdown", time.Now().UnixMilli(), string(RunRunning))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRun(row rowScanner) (*Run, error) {
+	var (
+		run                 Run
+		status, files       string
+		started, finishedAt int64
+	)
+	if err := row.Scan(&run.ID, &run.Trigger, &status, &files, &run.Error, &started, &finishedAt); err != nil {
+		return nil, err
+	}
+
+	run.Status = RunStatus(status)
+	if err := json.Unmarshal([]byte(files), &run.Files); err != nil || run.Files == nil {
+		run.Files = []File{}
+	}
+	run.StartedAt = time.UnixMilli(started)
+	if finishedAt > 0 {
+		t := time.UnixMilli(finishedAt)
+		run.FinishedAt = &t
+	}
+	return &run, nil
+}
+
+func newRunID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
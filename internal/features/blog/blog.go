@@ -0,0 +1,421 @@
+package blog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"wa-server-go/internal/whatsapp"
+
+	"github.com/robfig/cron/v3"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// ErrAlreadyRunning is returned when a post is triggered while another one is being generated
+var ErrAlreadyRunning = errors.New("a blog post is already being generated")
+
+// runTimeout bounds one generation (LLM, image search and Contentful calls)
+const runTimeout = 10 * time.Minute
+
+// DefaultTopics are used when BLOG_TOPICS is not set
+var DefaultTopics = []string{
+	"Tips memilih jasa pembuatan website untuk UMKM",
+	"Manfaat sistem invoice digital untuk bisnis kecil",
+	"Cara meningkatkan keamanan website perusahaan",
+	"Strategi digital marketing dengan WhatsApp Business",
+	"Pentingnya backup data rutin untuk perusahaan",
+	"Tren teknologi web yang perlu diketahui pemilik bisnis",
+}
+
+// Config holds the blog automation settings
+type Config struct {
+	Topics        []string      // picked in turn, least used first
+	Schedule      string        // cron spec of scheduled runs
+	AdminPhone    string        // reviews the drafts over WhatsApp
+	Language      string        // article language
+	ReviewTimeout time.Duration // how long a draft waits for the admin's reply
+	PostURL       string        // published articles are at PostURL/<slug>
+}
+
+// Article is the content generated for a post
+type Article struct {
+	Title      string   `json:"title"`
+	Slug       string   `json:"slug"`
+	Excerpt    string   `json:"excerpt"`
+	Content    string   `json:"content"` // Markdown
+	Tags       []string `json:"tags"`
+	ImageQuery string   `json:"imageQuery"` // stock photo search terms
+}
+
+// BlogService generates blog drafts (article from an LLM, cover from Pexels, draft in
+// Contentful) and lets the admin publish, regenerate or discard them over WhatsApp
+type BlogService struct {
+	waManager  *whatsapp.Manager
+	clientID   string
+	cfg        Config
+	llm        ChatCompleter
+	images     ImageSearcher
+	cms        CMS
+	store      *Store
+	cron       *cron.Cron
+	httpClient *http.Client
+
+	mu      sync.Mutex
+	running *Post
+	stopped bool // set by Stop; review commands are ignored from then on
+	wg      sync.WaitGroup
+
+	reviewMu sync.Mutex // one review action at a time
+}
+
+// NewBlogService creates the blog service. images may be nil (drafts get no cover).
+// The admin is notified through the WhatsApp client clientID.
+func NewBlogService(waManager *whatsapp.Manager, clientID string, cfg Config, llm ChatCompleter, images ImageSearcher, cms CMS, store *Store) *BlogService {
+	if len(cfg.Topics) == 0 {
+		cfg.Topics = DefaultTopics
+	}
+	if cfg.Language == "" {
+		cfg.Language = "Indonesian"
+	}
+	if cfg.ReviewTimeout <= 0 {
+		cfg.ReviewTimeout = DefaultReviewTimeout
+	}
+	return &BlogService{
+		waManager:  waManager,
+		clientID:   clientID,
+		cfg:        cfg,
+		llm:        llm,
+		images:     images,
+		cms:        cms,
+		store:      store,
+		cron:       cron.New(),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Start schedules the generation (cfg.Schedule, WIB) and the review deadlines, and listens
+// for the admin's review replies
+func (s *BlogService) Start() error {
+	if n, err := s.store.FailInterrupted(context.Background()); err != nil {
+		log.Printf("⚠️ [BLOG] Failed to close interrupted posts: %v", err)
+	} else if n > 0 {
+		log.Printf("⚠️ [BLOG] %d post(s) were interrupted by the last shutdown", n)
+	}
+
+	if s.cfg.Schedule != "" {
+		_, err := s.cron.AddFunc(s.cfg.Schedule, func() {
+			log.Println("🔄 [BLOG] Starting scheduled post...")
+			if _, err := s.Trigger(TriggerScheduled, ""); err != nil {
+				log.Printf("❌ [BLOG] Failed: %v", err)
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("failed to schedule blog automation: %w", err)
+		}
+	}
+	if _, err := s.cron.AddFunc("@every 10m", s.expireDrafts); err != nil {
+		return fmt.Errorf("failed to schedule review deadlines: %w", err)
+	}
+	if s.cfg.AdminPhone != "" {
+		s.waManager.HandleCommands(s.clientID, s.cfg.AdminPhone, s.handleCommand)
+	}
+
+	s.cron.Start()
+	log.Printf("✅ [BLOG] Scheduler started (%q, %d topic(s))", s.cfg.Schedule, len(s.cfg.Topics))
+	return nil
+}
+
+// Stop stops the cron jobs and waits (until ctx expires) for a running generation or review action
+func (s *BlogService) Stop(ctx context.Context) error {
+	s.cron.Stop()
+
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("gave up waiting for the running blog post: %w", ctx.Err())
+	}
+}
+
+// Trigger starts generating a post about topic (the next topic from the list if empty) in the
+// background and returns a copy of it. Only one post is generated at a time; ErrAlreadyRunning
+// is returned with it.
+func (s *BlogService) Trigger(trigger, topic string) (*Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running != nil {
+		return s.running.clone(), ErrAlreadyRunning
+	}
+
+	ctx := context.Background()
+	if topic == "" {
+		var err error
+		if topic, err = s.nextTopic(ctx); err != nil {
+			return nil, err
+		}
+	}
+	post, err := s.store.Insert(ctx, trigger, topic)
+	if err != nil {
+		return nil, err
+	}
+	s.running = post
+	s.wg.Add(1)
+
+	go func() {
+		defer s.wg.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), runTimeout)
+		defer cancel()
+
+		err := s.Generate(ctx, post)
+		s.finish(post, err)
+	}()
+
+	return post.clone(), nil
+}
+
+// Running returns a copy of the post being generated, if any
+func (s *BlogService) Running() *Post {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running == nil {
+		return nil
+	}
+	return s.running.clone()
+}
+
+// update changes post while holding the lock, so Running never sees it half-written
+func (s *BlogService) update(post *Post, change func(p *Post)) {
+	s.mu.Lock()
+	change(post)
+	s.mu.Unlock()
+}
+
+// Get returns a post by ID
+func (s *BlogService) Get(ctx context.Context, id string) (*Post, error) {
+	return s.store.Get(ctx, id)
+}
+
+// Events returns the audit trail of a post
+func (s *BlogService) Events(ctx context.Context, id string) ([]Event, error) {
+	return s.store.Events(ctx, id)
+}
+
+// History returns recent posts, newest first
+func (s *BlogService) History(ctx context.Context, limit int) ([]*Post, error) {
+	return s.store.List(ctx, limit)
+}
+
+// nextTopic returns the configured topic with the fewest posts (list order breaks ties)
+func (s *BlogService) nextTopic(ctx context.Context) (string, error) {
+	counts, err := s.store.TopicCounts(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to pick a topic: %w", err)
+	}
+	best := s.cfg.Topics[0]
+	for _, t := range s.cfg.Topics[1:] {
+		if counts[t] < counts[best] {
+			best = t
+		}
+	}
+	return best, nil
+}
+
+// finish records the outcome of a generation and releases the running slot
+func (s *BlogService) finish(post *Post, err error) {
+	ctx := context.Background()
+	if err != nil {
+		s.update(post, func(p *Post) {
+			p.Status = PostFailed
+			p.Error = err.Error()
+		})
+	}
+	post = post.clone() // the generation is over: nothing else writes to it
+
+	if err != nil {
+		log.Printf("❌ [BLOG] Post %s failed: %v", post.ID, err)
+		if s.cfg.AdminPhone != "" {
+			jid := types.NewJID(s.cfg.AdminPhone, types.DefaultUserServer)
+			s.sendText(ctx, s.clientID, jid, fmt.Sprintf("❌ *DRAFT BLOG GAGAL*\n\n🏷️ Topik: %s\n❗ %s", post.Topic, post.Error))
+		}
+	} else {
+		log.Printf("✅ [BLOG] Draft %q created: %s", post.Title, post.DraftURL)
+	}
+
+	if err := s.store.Update(ctx, post); err != nil {
+		log.Printf("❌ [BLOG] Failed to record post %s: %v", post.ID, err)
+	}
+	if post.Status == PostDraft {
+		s.audit(ctx, post.ID, ActionCreated, actorSystem, post.DraftURL)
+		if post.PreviewMessageID != "" {
+			s.audit(ctx, post.ID, ActionPreviewSent, actorSystem, s.cfg.AdminPhone)
+		}
+	}
+
+	s.mu.Lock()
+	s.running = nil
+	s.mu.Unlock()
+}
+
+// Generate writes the article, picks a cover, creates the Contentful draft and sends it to the
+// admin for review. post is only changed while holding the service's lock.
+func (s *BlogService) Generate(ctx context.Context, post *Post) error {
+	// 1. Write the article
+	log.Printf("✍️ [BLOG] Writing article about %q", post.Topic)
+	article, err := s.writeArticle(ctx, post.Topic)
+	if err != nil {
+		return err
+	}
+	s.update(post, func(p *Post) {
+		p.Title = article.Title
+		p.Slug = article.Slug
+		p.Excerpt = article.Excerpt
+		p.Tags = article.Tags
+	})
+
+	// 2. Cover image (optional: a draft without cover is still useful)
+	if s.images != nil {
+		if assetID, photo := s.cover(ctx, article); assetID != "" {
+			s.update(post, func(p *Post) {
+				p.AssetID = assetID
+				p.ImageURL = photo.URL
+				p.ImageCredit = photo.Credit()
+			})
+		}
+	}
+
+	// 3. Contentful draft
+	entryID, err := s.cms.CreateDraft(ctx, article, post.AssetID)
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(s.cfg.ReviewTimeout)
+	s.update(post, func(p *Post) {
+		p.EntryID = entryID
+		p.DraftURL = s.cms.EntryURL(entryID)
+		p.Status = PostDraft
+		p.ReviewDeadline = &deadline
+	})
+
+	// 4. Ask the admin for a review
+	if messageID := s.sendPreview(ctx, post); messageID != "" {
+		s.update(post, func(p *Post) { p.PreviewMessageID = messageID })
+	}
+	return nil
+}
+
+// writeArticle asks the LLM for an article about topic
+func (s *BlogService) writeArticle(ctx context.Context, topic string) (*Article, error) {
+	messages := []ChatMessage{
+		{Role: "system", Content: fmt.Sprintf(`You write blog articles for Valpro Intertech, an Indonesian IT services company (websites, business systems, digital marketing).
+Write in %s for business owners: practical, friendly and concrete, 800-1200 words, using Markdown headings and lists.
+Reply with a JSON object only, with the keys:
+"title" (max 80 characters), "slug" (lowercase words separated by hyphens), "excerpt" (1-2 sentences, max 200 characters),
+"content" (the Markdown article without the title), "tags" (3-5 short lowercase tags),
+"imageQuery" (2-4 English words to search a stock cover photo).`, s.cfg.Language)},
+		{Role: "user", Content: "Topic: " + topic},
+	}
+
+	reply, err := s.llm.Complete(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	return parseArticle(reply)
+}
+
+// cover finds a photo for the article and uploads it as a Contentful asset.
+// Failures are logged and yield no cover.
+func (s *BlogService) cover(ctx context.Context, article *Article) (string, *Photo) {
+	query := article.ImageQuery
+	if query == "" {
+		query = article.Title
+	}
+	photo, err := s.images.SearchPhoto(ctx, query)
+	if err != nil {
+		log.Printf("⚠️ [BLOG] Cover search failed, continuing without cover: %v", err)
+		return "", nil
+	}
+	if photo == nil {
+		log.Printf("⚠️ [BLOG] No cover found for %q", query)
+		return "", nil
+	}
+
+	assetID, err := s.cms.CreateAsset(ctx, photo, article.Title)
+	if err != nil {
+		log.Printf("⚠️ [BLOG] Cover upload failed, continuing without cover: %v", err)
+		return "", nil
+	}
+	return assetID, photo
+}
+
+var (
+	codeFence   = regexp.MustCompile("^```[a-zA-Z]*\\s*|\\s*```$")
+	nonSlugChar = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// parseArticle decodes and validates the LLM reply
+func parseArticle(reply string) (*Article, error) {
+	reply = codeFence.ReplaceAllString(strings.TrimSpace(reply), "")
+
+	var article Article
+	if err := json.Unmarshal([]byte(reply), &article); err != nil {
+		return nil, fmt.Errorf("failed to parse generated article: %w", err)
+	}
+	article.Title = strings.TrimSpace(article.Title)
+	article.Content = strings.TrimSpace(article.Content)
+	if article.Title == "" || article.Content == "" {
+		return nil, fmt.Errorf("generated article has no title or content")
+	}
+
+	article.Slug = slugify(article.Slug)
+	if article.Slug == "" {
+		article.Slug = slugify(article.Title)
+	}
+	article.Excerpt = truncate(strings.TrimSpace(article.Excerpt), 250)
+	article.Title = truncate(article.Title, 250)
+
+	tags := make([]string, 0, len(article.Tags))
+	for _, t := range article.Tags {
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	article.Tags = tags
+	return &article, nil
+}
+
+// slugify lowercases s and joins its letters and digits with hyphens
+func slugify(s string) string {
+	slug := strings.Trim(nonSlugChar.ReplaceAllString(strings.ToLower(s), "-"), "-")
+	if len(slug) > 80 {
+		slug = strings.TrimRight(slug[:80], "-")
+	}
+	return slug
+}
+
+// truncate shortens s to at most n runes, ending with an ellipsis when cut
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + "…"
+}
@@ -0,0 +1,179 @@
+package blog
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeLLM struct {
+	reply string
+	err   error
+	asked []ChatMessage
+}
+
+func (f *fakeLLM) Complete(ctx context.Context, messages []ChatMessage) (string, error) {
+	f.asked = messages
+	return f.reply, f.err
+}
+
+type fakeImages struct {
+	photo *Photo
+	err   error
+	query string
+}
+
+func (f *fakeImages) SearchPhoto(ctx context.Context, query string) (*Photo, error) {
+	f.query = query
+	return f.photo, f.err
+}
+
+type fakeCMS struct {
+	assetErr, draftErr error
+
+	draft        *Article
+	draftAssetID string
+}
+
+func (f *fakeCMS) CreateAsset(ctx context.Context, photo *Photo, title string) (string, error) {
+	if f.assetErr != nil {
+		return "", f.assetErr
+	}
+	return "asset1", nil
+}
+
+func (f *fakeCMS) CreateDraft(ctx context.Context, article *Article, assetID string) (string, error) {
+	f.draft, f.draftAssetID = article, assetID
+	if f.draftErr != nil {
+		return "", f.draftErr
+	}
+	return "entry1", nil
+}
+
+func (f *fakeCMS) EntryURL(entryID string) string { return "https://cms.example/" + entryID }
+
+func (f *fakeCMS) Publish(ctx context.Context, entryID, assetID string) error { return nil }
+
+func (f *fakeCMS) Delete(ctx context.Context, entryID, assetID string) error { return nil }
+
+// articleReply is an LLM reply as the prompt asks for it, wrapped in a code fence like models often do
+const articleReply = "```json\n" + `{
+	"title": "Tips Memilih Jasa Website",
+	"slug": "Tips Memilih Jasa Website!",
+	"excerpt": "Panduan singkat.",
+	"content": "## Mulai dari kebutuhan",
+	"tags": ["Website", " umkm ", ""],
+	"imageQuery": "small business website"
+}` + "\n```"
+
+func newTestBlogService(llm ChatCompleter, images ImageSearcher, cms CMS) *BlogService {
+	// No admin phone: the preview is skipped, so no WhatsApp client is needed
+	return NewBlogService(nil, "default", Config{ReviewTimeout: time.Hour}, llm, images, cms, nil)
+}
+
+func TestGenerate(t *testing.T) {
+	llm := &fakeLLM{reply: articleReply}
+	images := &fakeImages{photo: &Photo{URL: "https://images.example/1.jpg", Photographer: "Jane"}}
+	cms := &fakeCMS{}
+	s := newTestBlogService(llm, images, cms)
+
+	post := &Post{ID: "p1", Topic: "Tips memilih jasa website", Status: PostGenerating}
+	start := time.Now()
+	if err := s.Generate(context.Background(), post); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	if len(llm.asked) != 2 || llm.asked[1].Content != "Topic: Tips memilih jasa website" {
+		t.Errorf("LLM was asked %+v", llm.asked)
+	}
+	if !strings.Contains(llm.asked[0].Content, "Indonesian") {
+		t.Errorf("system prompt does not ask for the default language: %q", llm.asked[0].Content)
+	}
+	if images.query != "small business website" {
+		t.Errorf("image query = %q, want the article's imageQuery", images.query)
+	}
+	if cms.draftAssetID != "asset1" || cms.draft.Content != "## Mulai dari kebutuhan" {
+		t.Errorf("draft = %+v with asset %q", cms.draft, cms.draftAssetID)
+	}
+
+	if post.Status != PostDraft {
+		t.Errorf("status = %s, want %s", post.Status, PostDraft)
+	}
+	if post.Title != "Tips Memilih Jasa Website" || post.Slug != "tips-memilih-jasa-website" || post.Excerpt != "Panduan singkat." {
+		t.Errorf("article fields = %q, %q, %q", post.Title, post.Slug, post.Excerpt)
+	}
+	if strings.Join(post.Tags, ",") != "website,umkm" {
+		t.Errorf("tags = %v, want [website umkm]", post.Tags)
+	}
+	if post.AssetID != "asset1" || post.ImageURL != "https://images.example/1.jpg" || post.ImageCredit != "Photo by Jane on Pexels" {
+		t.Errorf("cover = %q, %q, %q", post.AssetID, post.ImageURL, post.ImageCredit)
+	}
+	if post.EntryID != "entry1" || post.DraftURL != "https://cms.example/entry1" {
+		t.Errorf("entry = %q, %q", post.EntryID, post.DraftURL)
+	}
+	if post.ReviewDeadline == nil || post.ReviewDeadline.Before(start.Add(time.Hour)) {
+		t.Errorf("review deadline = %v, want an hour from now", post.ReviewDeadline)
+	}
+	if post.PreviewMessageID != "" {
+		t.Errorf("preview message = %q, want none without an admin phone", post.PreviewMessageID)
+	}
+}
+
+func TestGenerateWithoutCover(t *testing.T) {
+	tests := []struct {
+		name   string
+		images ImageSearcher
+		cms    *fakeCMS
+	}{
+		{"no image searcher", nil, &fakeCMS{}},
+		{"search fails", &fakeImages{err: errors.New("pexels down")}, &fakeCMS{}},
+		{"nothing found", &fakeImages{}, &fakeCMS{}},
+		{"upload fails", &fakeImages{photo: &Photo{URL: "https://images.example/1.jpg"}}, &fakeCMS{assetErr: errors.New("quota")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestBlogService(&fakeLLM{reply: articleReply}, tt.images, tt.cms)
+			post := &Post{ID: "p1", Topic: "x", Status: PostGenerating}
+
+			if err := s.Generate(context.Background(), post); err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			if post.Status != PostDraft || post.AssetID != "" || post.ImageURL != "" {
+				t.Errorf("post = %+v, want a draft without cover", post)
+			}
+			if tt.cms.draft == nil || tt.cms.draftAssetID != "" {
+				t.Errorf("draft created with asset %q, want none", tt.cms.draftAssetID)
+			}
+		})
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		llm     *fakeLLM
+		cms     *fakeCMS
+		wantErr string
+	}{
+		{"LLM fails", &fakeLLM{err: errors.New("chat completion failed: 500")}, &fakeCMS{}, "chat completion failed"},
+		{"reply is not JSON", &fakeLLM{reply: "Here is your article!"}, &fakeCMS{}, "failed to parse"},
+		{"reply without content", &fakeLLM{reply: `{"title":"Judul"}`}, &fakeCMS{}, "no title or content"},
+		{"draft fails", &fakeLLM{reply: articleReply}, &fakeCMS{draftErr: errors.New("failed to create entry: 422")}, "422"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestBlogService(tt.llm, nil, tt.cms)
+			post := &Post{ID: "p1", Topic: "x", Status: PostGenerating}
+
+			err := s.Generate(context.Background(), post)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want it to mention %q", err, tt.wantErr)
+			}
+			if post.Status != PostGenerating || post.EntryID != "" {
+				t.Errorf("post = %+v, want it left generating (finish marks it failed)", post)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package blog
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Contentful defaults
+const (
+	DefaultContentfulURL         = "https://api.contentful.com"
+	DefaultContentfulContentType = "blogPost"
+	DefaultContentfulLocale      = "en-US"
+)
+
+// contentfulMediaType is the content type of Content Management API requests
+const contentfulMediaType = "application/vnd.contentful.management.v1+json"
+
+// CMS stores generated articles as drafts and publishes or deletes them after review
+type CMS interface {
+	// CreateAsset creates an (unpublished) image asset from photo and returns its ID
+	CreateAsset(ctx context.Context, photo *Photo, title string) (string, error)
+	// CreateDraft creates an unpublished entry for article (assetID may be empty) and returns its ID
+	CreateDraft(ctx context.Context, article *Article, assetID string) (string, error)
+	// EntryURL returns the editor link of an entry
+	EntryURL(entryID string) string
+	// Publish publishes the cover asset (if any) and the entry
+	Publish(ctx context.Context, entryID, assetID string) error
+	// Delete removes a draft entry and its cover asset (if any)
+	Delete(ctx context.Context, entryID, assetID string) error
+}
+
+// ContentfulClient uses the Contentful Content Management API. Entries of contentType need
+// the fields title, slug, excerpt, content (Markdown), tags and coverImage (asset link).
+type ContentfulClient struct {
+	baseURL     string
+	token       string
+	spaceID     string
+	environment string
+	contentType string
+	locale      string
+	httpClient  *http.Client
+}
+
+// NewContentfulClient creates a Contentful Management client. baseURL, contentType and
+// locale default to the public API, "blogPost" and "en-US".
+func NewContentfulClient(baseURL, token, spaceID, environment, contentType, locale string) *ContentfulClient {
+	if baseURL == "" {
+		baseURL = DefaultContentfulURL
+	}
+	if environment == "" {
+		environment = "master"
+	}
+	if contentType == "" {
+		contentType = DefaultContentfulContentType
+	}
+	if locale == "" {
+		locale = DefaultContentfulLocale
+	}
+	return &ContentfulClient{
+		baseURL:     strings.TrimRight(baseURL, "/"),
+		token:       token,
+		spaceID:     spaceID,
+		environment: environment,
+		contentType: contentType,
+		locale:      locale,
+		httpClient:  &http.Client{Timeout: time.Minute},
+	}
+}
+
+// contentfulSys is the sys block of Contentful responses
+type contentfulSys struct {
+	Sys struct {
+		ID      string `json:"id"`
+		Version int    `json:"version"`
+	} `json:"sys"`
+}
+
+// CreateAsset creates an asset from the photo URL and starts processing it
+func (c *ContentfulClient) CreateAsset(ctx context.Context, photo *Photo, title string) (string, error) {
+	body := map[string]interface{}{
+		"fields": map[string]interface{}{
+			"title":       c.localized(title),
+			"description": c.localized(photo.Alt + " (" + photo.Credit() + ")"),
+			"file": c.localized(map[string]string{
+				"contentType": "image/jpeg",
+				"fileName":    "cover.jpg",
+				"upload":      photo.URL,
+			}),
+		},
+	}
+	var asset contentfulSys
+	if err := c.do(ctx, http.MethodPost, "/assets", body, &asset, nil); err != nil {
+		return "", fmt.Errorf("failed to create asset: %w", err)
+	}
+
+	path := fmt.Sprintf("/assets/%s/files/%s/process", asset.Sys.ID, c.locale)
+	err := c.do(ctx, http.MethodPut, path, nil, nil, map[string]string{
+		"X-Contentful-Version": strconv.Itoa(asset.Sys.Version),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to process asset %s: %w", asset.Sys.ID, err)
+	}
+	return asset.Sys.ID, nil
+}
+
+// CreateDraft creates an unpublished entry of the blog content type
+func (c *ContentfulClient) CreateDraft(ctx context.Context, article *Article, assetID string) (string, error) {
+	fields := map[string]interface{}{
+		"title":   c.localized(article.Title),
+		"slug":    c.localized(article.Slug),
+		"excerpt": c.localized(article.Excerpt),
+		"content": c.localized(article.Content),
+		"tags":    c.localized(article.Tags),
+	}
+	if assetID != "" {
+		fields["coverImage"] = c.localized(map[string]interface{}{
+			"sys": map[string]string{"type": "Link", "linkType": "Asset", "id": assetID},
+		})
+	}
+
+	var entry contentfulSys
+	err := c.do(ctx, http.MethodPost, "/entries", map[string]interface{}{"fields": fields}, &entry, map[string]string{
+		"X-Contentful-Content-Type": c.contentType,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create entry: %w", err)
+	}
+	return entry.Sys.ID, nil
+}
+
+// EntryURL returns the Contentful web app link of an entry
+func (c *ContentfulClient) EntryURL(entryID string) string {
+	return fmt.Sprintf("https://app.contentful.com/spaces/%s/environments/%s/entries/%s", c.spaceID, c.environment, entryID)
+}
+
+// Publish publishes the asset first so the entry never links to an unpublished cover
+func (c *ContentfulClient) Publish(ctx context.Context, entryID, assetID string) error {
+	if assetID != "" {
+		if err := c.publish(ctx, "/assets/"+assetID); err != nil {
+			return fmt.Errorf("failed to publish asset %s: %w", assetID, err)
+		}
+	}
+	if err := c.publish(ctx, "/entries/"+entryID); err != nil {
+		return fmt.Errorf("failed to publish entry %s: %w", entryID, err)
+	}
+	return nil
+}
+
+// Delete deletes the entry, then its asset. An asset that cannot be deleted is left behind.
+func (c *ContentfulClient) Delete(ctx context.Context, entryID, assetID string) error {
+	if err := c.delete(ctx, "/entries/"+entryID); err != nil {
+		return fmt.Errorf("failed to delete entry %s: %w", entryID, err)
+	}
+	if assetID != "" {
+		if err := c.delete(ctx, "/assets/"+assetID); err != nil {
+			return fmt.Errorf("failed to delete asset %s: %w", assetID, err)
+		}
+	}
+	return nil
+}
+
+// version returns the current version of an entry or asset (required by updates)
+func (c *ContentfulClient) version(ctx context.Context, path string) (string, error) {
+	var current contentfulSys
+	if err := c.do(ctx, http.MethodGet, path, nil, &current, nil); err != nil {
+		return "", err
+	}
+	return strconv.Itoa(current.Sys.Version), nil
+}
+
+func (c *ContentfulClient) publish(ctx context.Context, path string) error {
+	version, err := c.version(ctx, path)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodPut, path+"/published", nil, nil, map[string]string{"X-Contentful-Version": version})
+}
+
+func (c *ContentfulClient) delete(ctx context.Context, path string) error {
+	version, err := c.version(ctx, path)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodDelete, path, nil, nil, map[string]string{"X-Contentful-Version": version})
+}
+
+func (c *ContentfulClient) localized(v interface{}) map[string]interface{} {
+	return map[string]interface{}{c.locale: v}
+}
+
+// do calls path under the space environment
+func (c *ContentfulClient) do(ctx context.Context, method, path string, body, out interface{}, headers map[string]string) error {
+	url := fmt.Sprintf("%s/spaces/%s/environments/%s%s", c.baseURL, c.spaceID, c.environment, path)
+	return doJSON(ctx, c.httpClient, method, url, body, out, func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+		if body != nil {
+			req.Header.Set("Content-Type", contentfulMediaType)
+		}
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+	})
+}
@@ -0,0 +1,200 @@
+package blog
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// contentfulRequest is what the fake Contentful server saw
+type contentfulRequest struct {
+	Method, Path, Version, ContentType string
+	Body                               map[string]interface{}
+}
+
+// fakeContentful answers like the Content Management API: every object has version 7.
+// failPath makes requests to that path fail with 422.
+type fakeContentful struct {
+	t        *testing.T
+	failPath string
+
+	mu       sync.Mutex
+	requests []contentfulRequest
+}
+
+const contentfulPrefix = "/spaces/space1/environments/master"
+
+func (f *fakeContentful) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if auth := r.Header.Get("Authorization"); auth != "Bearer cma-token" {
+		f.t.Errorf("%s %s: Authorization = %q", r.Method, r.URL.Path, auth)
+	}
+	req := contentfulRequest{
+		Method:      r.Method,
+		Path:        strings.TrimPrefix(r.URL.Path, contentfulPrefix),
+		Version:     r.Header.Get("X-Contentful-Version"),
+		ContentType: r.Header.Get("X-Contentful-Content-Type"),
+	}
+	if data, _ := io.ReadAll(r.Body); len(data) > 0 {
+		if ct := r.Header.Get("Content-Type"); ct != contentfulMediaType {
+			f.t.Errorf("%s %s: Content-Type = %q", r.Method, r.URL.Path, ct)
+		}
+		if err := json.Unmarshal(data, &req.Body); err != nil {
+			f.t.Errorf("%s %s: invalid body: %v", r.Method, r.URL.Path, err)
+		}
+	}
+	f.mu.Lock()
+	f.requests = append(f.requests, req)
+	f.mu.Unlock()
+
+	if req.Path == f.failPath {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"sys":{"id":"ValidationFailed"}}`))
+		return
+	}
+	id := "entry1"
+	if strings.HasPrefix(req.Path, "/assets") {
+		id = "asset1"
+	}
+	w.Write([]byte(`{"sys":{"id":"` + id + `","version":7}}`))
+}
+
+// calls lists the requests as "METHOD path [version]"
+func (f *fakeContentful) calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	calls := make([]string, 0, len(f.requests))
+	for _, r := range f.requests {
+		call := r.Method + " " + r.Path
+		if r.Version != "" {
+			call += " v" + r.Version
+		}
+		calls = append(calls, call)
+	}
+	return calls
+}
+
+func newFakeContentful(t *testing.T, failPath string) (*fakeContentful, *ContentfulClient) {
+	fake := &fakeContentful{t: t, failPath: failPath}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	return fake, NewContentfulClient(srv.URL, "cma-token", "space1", "", "", "id-ID")
+}
+
+func TestContentfulCreateAsset(t *testing.T) {
+	fake, c := newFakeContentful(t, "")
+
+	photo := &Photo{URL: "https://images.pexels.com/1.jpg", Alt: "Laptop", Photographer: "Jane"}
+	id, err := c.CreateAsset(context.Background(), photo, "Judul")
+	if err != nil {
+		t.Fatalf("CreateAsset: %v", err)
+	}
+	if id != "asset1" {
+		t.Errorf("id = %q, want asset1", id)
+	}
+
+	want := []string{"POST /assets", "PUT /assets/asset1/files/id-ID/process v7"}
+	if got := fake.calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("calls = %v, want %v", got, want)
+	}
+	fields := fake.requests[0].Body["fields"].(map[string]interface{})
+	file := fields["file"].(map[string]interface{})["id-ID"].(map[string]interface{})
+	if file["upload"] != photo.URL {
+		t.Errorf("file = %v, want upload from %s", file, photo.URL)
+	}
+	if desc := fields["description"].(map[string]interface{})["id-ID"]; desc != "Laptop (Photo by Jane on Pexels)" {
+		t.Errorf("description = %v", desc)
+	}
+}
+
+func TestContentfulCreateDraft(t *testing.T) {
+	fake, c := newFakeContentful(t, "")
+
+	article := &Article{Title: "Judul", Slug: "judul", Excerpt: "Ringkas", Content: "## Isi", Tags: []string{"umkm"}}
+	id, err := c.CreateDraft(context.Background(), article, "asset1")
+	if err != nil {
+		t.Fatalf("CreateDraft: %v", err)
+	}
+	if id != "entry1" {
+		t.Errorf("id = %q, want entry1", id)
+	}
+
+	if got := fake.calls(); !reflect.DeepEqual(got, []string{"POST /entries"}) {
+		t.Fatalf("calls = %v, want [POST /entries]", got)
+	}
+	req := fake.requests[0]
+	if req.ContentType != DefaultContentfulContentType {
+		t.Errorf("X-Contentful-Content-Type = %q, want %q", req.ContentType, DefaultContentfulContentType)
+	}
+	fields := req.Body["fields"].(map[string]interface{})
+	for name, want := range map[string]interface{}{"title": "Judul", "slug": "judul", "excerpt": "Ringkas", "content": "## Isi"} {
+		if got := fields[name].(map[string]interface{})["id-ID"]; got != want {
+			t.Errorf("%s = %v, want %v", name, got, want)
+		}
+	}
+	cover := fields["coverImage"].(map[string]interface{})["id-ID"].(map[string]interface{})["sys"]
+	wantCover := map[string]interface{}{"type": "Link", "linkType": "Asset", "id": "asset1"}
+	if !reflect.DeepEqual(cover, wantCover) {
+		t.Errorf("coverImage = %v, want %v", cover, wantCover)
+	}
+
+	if url := c.EntryURL(id); url != "https://app.contentful.com/spaces/space1/environments/master/entries/entry1" {
+		t.Errorf("EntryURL = %s", url)
+	}
+}
+
+func TestContentfulPublishAndDelete(t *testing.T) {
+	fake, c := newFakeContentful(t, "")
+	ctx := context.Background()
+
+	if err := c.Publish(ctx, "entry1", "asset1"); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	// The cover is published first, and every update sends the version just read
+	want := []string{
+		"GET /assets/asset1", "PUT /assets/asset1/published v7",
+		"GET /entries/entry1", "PUT /entries/entry1/published v7",
+	}
+	if got := fake.calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Publish calls = %v, want %v", got, want)
+	}
+
+	fake.requests = nil
+	if err := c.Delete(ctx, "entry1", ""); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	want = []string{"GET /entries/entry1", "DELETE /entries/entry1 v7"}
+	if got := fake.calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Delete calls = %v, want %v", got, want)
+	}
+}
+
+func TestContentfulErrors(t *testing.T) {
+	ctx := context.Background()
+
+	_, c := newFakeContentful(t, "/entries")
+	if _, err := c.CreateDraft(ctx, &Article{Title: "Judul"}, ""); err == nil || !strings.Contains(err.Error(), "422") {
+		t.Errorf("CreateDraft err = %v, want a 422 error", err)
+	}
+
+	// A cover that cannot be published keeps the entry unpublished
+	fake, c := newFakeContentful(t, "/assets/asset1/published")
+	if err := c.Publish(ctx, "entry1", "asset1"); err == nil || !strings.Contains(err.Error(), "asset asset1") {
+		t.Errorf("Publish err = %v, want an asset error", err)
+	}
+	for _, call := range fake.calls() {
+		if strings.HasPrefix(call, "PUT /entries/") {
+			t.Errorf("entry was published after the asset failed: %s", call)
+		}
+	}
+
+	_, c = newFakeContentful(t, "/assets/asset1/files/id-ID/process")
+	if _, err := c.CreateAsset(ctx, &Photo{URL: "https://images.pexels.com/1.jpg"}, "Judul"); err == nil || !strings.Contains(err.Error(), "process asset") {
+		t.Errorf("CreateAsset err = %v, want a processing error", err)
+	}
+}
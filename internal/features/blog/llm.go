@@ -0,0 +1,75 @@
+package blog
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Groq defaults (any OpenAI-compatible chat completion API works)
+const (
+	DefaultLLMURL   = "https://api.groq.com/openai/v1"
+	DefaultLLMModel = "llama-3.3-70b-versatile"
+)
+
+// ChatMessage is one message of a chat completion request
+type ChatMessage struct {
+	Role    string `json:"role"` // system, user or assistant
+	Content string `json:"content"`
+}
+
+// ChatCompleter returns the assistant reply to a conversation
+type ChatCompleter interface {
+	Complete(ctx context.Context, messages []ChatMessage) (string, error)
+}
+
+// ChatClient calls an OpenAI-compatible /chat/completions endpoint
+type ChatClient struct {
+	baseURL    string
+	apiKey     string
+	model      string
+	httpClient *http.Client
+}
+
+// NewChatClient creates a chat completion client. baseURL defaults to Groq.
+func NewChatClient(baseURL, apiKey, model string) *ChatClient {
+	if baseURL == "" {
+		baseURL = DefaultLLMURL
+	}
+	if model == "" {
+		model = DefaultLLMModel
+	}
+	return &ChatClient{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		model:      model,
+		httpClient: &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+// Complete asks for a JSON object reply to messages
+func (c *ChatClient) Complete(ctx context.Context, messages []ChatMessage) (string, error) {
+	body := map[string]interface{}{
+		"model":           c.model,
+		"messages":        messages,
+		"temperature":     0.7,
+		"response_format": map[string]string{"type": "json_object"},
+	}
+	var resp struct {
+		Choices []struct {
+			Message ChatMessage `json:"message"`
+		} `json:"choices"`
+	}
+	err := doJSON(ctx, c.httpClient, http.MethodPost, c.baseURL+"/chat/completions", body, &resp, func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	})
+	if err != nil {
+		return "", fmt.Errorf("chat completion failed: %w", err)
+	}
+	if len(resp.Choices) == 0 || strings.TrimSpace(resp.Choices[0].Message.Content) == "" {
+		return "", fmt.Errorf("chat completion returned no content")
+	}
+	return resp.Choices[0].Message.Content, nil
+}
@@ -0,0 +1,82 @@
+package blog
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestChatClientComplete(t *testing.T) {
+	var got struct {
+		Model          string            `json:"model"`
+		Messages       []ChatMessage     `json:"messages"`
+		ResponseFormat map[string]string `json:"response_format"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/openai/v1/chat/completions" {
+			t.Errorf("request = %s %s, want POST /openai/v1/chat/completions", r.Method, r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer gsk_test" {
+			t.Errorf("Authorization = %q, want Bearer gsk_test", auth)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", ct)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"{\"title\":\"Hi\"}"}}]}`))
+	}))
+	defer srv.Close()
+
+	c := NewChatClient(srv.URL+"/openai/v1/", "gsk_test", "")
+	messages := []ChatMessage{{Role: "system", Content: "be brief"}, {Role: "user", Content: "Topic: x"}}
+	reply, err := c.Complete(context.Background(), messages)
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if reply != `{"title":"Hi"}` {
+		t.Errorf("reply = %q", reply)
+	}
+
+	if got.Model != DefaultLLMModel {
+		t.Errorf("model = %q, want %q", got.Model, DefaultLLMModel)
+	}
+	if len(got.Messages) != 2 || got.Messages[1] != messages[1] {
+		t.Errorf("messages = %+v, want %+v", got.Messages, messages)
+	}
+	if got.ResponseFormat["type"] != "json_object" {
+		t.Errorf("response_format = %v, want json_object", got.ResponseFormat)
+	}
+}
+
+func TestChatClientErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{"rate limited", http.StatusTooManyRequests, `{"error":{"message":"slow down"}}`, "429"},
+		{"no choices", http.StatusOK, `{"choices":[]}`, "no content"},
+		{"empty content", http.StatusOK, `{"choices":[{"message":{"role":"assistant","content":"  "}}]}`, "no content"},
+		{"invalid JSON", http.StatusOK, `{"choices":`, "failed to decode"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			_, err := NewChatClient(srv.URL, "key", "model").Complete(context.Background(), nil)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
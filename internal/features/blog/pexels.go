@@ -0,0 +1,97 @@
+package blog
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultPexelsURL is the Pexels API base URL
+const DefaultPexelsURL = "https://api.pexels.com/v1"
+
+// Photo is a cover image candidate
+type Photo struct {
+	URL             string `json:"url"` // image file to upload
+	PageURL         string `json:"pageUrl"`
+	Alt             string `json:"alt"`
+	Photographer    string `json:"photographer"`
+	PhotographerURL string `json:"photographerUrl"`
+}
+
+// Credit returns the attribution line for the photo
+func (p *Photo) Credit() string {
+	return fmt.Sprintf("Photo by %s on Pexels", p.Photographer)
+}
+
+// ImageSearcher finds a cover image for a query. It returns nil (and no error) when nothing matches.
+type ImageSearcher interface {
+	SearchPhoto(ctx context.Context, query string) (*Photo, error)
+}
+
+// PexelsClient searches photos on Pexels
+type PexelsClient struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// NewPexelsClient creates a Pexels client. baseURL defaults to the Pexels API.
+func NewPexelsClient(baseURL, apiKey string) *PexelsClient {
+	if baseURL == "" {
+		baseURL = DefaultPexelsURL
+	}
+	return &PexelsClient{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// SearchPhoto returns the best landscape photo for query
+func (c *PexelsClient) SearchPhoto(ctx context.Context, query string) (*Photo, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("orientation", "landscape")
+	params.Set("per_page", "1")
+
+	var resp struct {
+		Photos []struct {
+			URL             string `json:"url"`
+			Alt             string `json:"alt"`
+			Photographer    string `json:"photographer"`
+			PhotographerURL string `json:"photographer_url"`
+			Src             struct {
+				Large2x   string `json:"large2x"`
+				Landscape string `json:"landscape"`
+			} `json:"src"`
+		} `json:"photos"`
+	}
+	err := doJSON(ctx, c.httpClient, http.MethodGet, c.baseURL+"/search?"+params.Encode(), nil, &resp, func(req *http.Request) {
+		req.Header.Set("Authorization", c.apiKey)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("pexels search failed: %w", err)
+	}
+	if len(resp.Photos) == 0 {
+		return nil, nil
+	}
+
+	p := resp.Photos[0]
+	photo := &Photo{
+		URL:             p.Src.Large2x,
+		PageURL:         p.URL,
+		Alt:             p.Alt,
+		Photographer:    p.Photographer,
+		PhotographerURL: p.PhotographerURL,
+	}
+	if photo.URL == "" {
+		photo.URL = p.Src.Landscape
+	}
+	if photo.URL == "" {
+		return nil, nil
+	}
+	return photo, nil
+}
@@ -0,0 +1,93 @@
+package blog
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPexelsSearchPhoto(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/v1/search" {
+			t.Errorf("request = %s %s, want GET /v1/search", r.Method, r.URL.Path)
+		}
+		// Pexels takes the bare API key, without "Bearer"
+		if auth := r.Header.Get("Authorization"); auth != "pexels-key" {
+			t.Errorf("Authorization = %q, want pexels-key", auth)
+		}
+		q := r.URL.Query()
+		if q.Get("query") != "small business laptop" || q.Get("orientation") != "landscape" || q.Get("per_page") != "1" {
+			t.Errorf("query = %s", r.URL.RawQuery)
+		}
+		w.Write([]byte(`{"photos":[{
+			"url":"https://www.pexels.com/photo/123/",
+			"alt":"A laptop on a desk",
+			"photographer":"Jane Doe",
+			"photographer_url":"https://www.pexels.com/@jane",
+			"src":{"large2x":"https://images.pexels.com/123-large2x.jpg","landscape":"https://images.pexels.com/123-landscape.jpg"}
+		}]}`))
+	}))
+	defer srv.Close()
+
+	photo, err := NewPexelsClient(srv.URL+"/v1", "pexels-key").SearchPhoto(context.Background(), "small business laptop")
+	if err != nil {
+		t.Fatalf("SearchPhoto: %v", err)
+	}
+	want := Photo{
+		URL:             "https://images.pexels.com/123-large2x.jpg",
+		PageURL:         "https://www.pexels.com/photo/123/",
+		Alt:             "A laptop on a desk",
+		Photographer:    "Jane Doe",
+		PhotographerURL: "https://www.pexels.com/@jane",
+	}
+	if photo == nil || *photo != want {
+		t.Fatalf("photo = %+v, want %+v", photo, want)
+	}
+	if credit := photo.Credit(); credit != "Photo by Jane Doe on Pexels" {
+		t.Errorf("Credit() = %q", credit)
+	}
+}
+
+func TestPexelsSearchPhotoResults(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantURL string // "" for no photo
+		wantErr string
+	}{
+		{"landscape fallback", http.StatusOK, `{"photos":[{"src":{"landscape":"https://images.pexels.com/1.jpg"}}]}`, "https://images.pexels.com/1.jpg", ""},
+		{"no results", http.StatusOK, `{"photos":[]}`, "", ""},
+		{"no image file", http.StatusOK, `{"photos":[{"src":{}}]}`, "", ""},
+		{"unauthorized", http.StatusUnauthorized, `{"error":"bad key"}`, "", "401"},
+		{"invalid JSON", http.StatusOK, `<html>`, "", "failed to decode"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			photo, err := NewPexelsClient(srv.URL, "key").SearchPhoto(context.Background(), "office")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SearchPhoto: %v", err)
+			}
+			switch {
+			case tt.wantURL == "" && photo != nil:
+				t.Errorf("photo = %+v, want none", photo)
+			case tt.wantURL != "" && (photo == nil || photo.URL != tt.wantURL):
+				t.Errorf("photo = %+v, want URL %s", photo, tt.wantURL)
+			}
+		})
+	}
+}
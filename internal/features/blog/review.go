@@ -0,0 +1,315 @@
+package blog
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"wa-server-go/internal/whatsapp"
+
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+// Review commands the admin replies with
+const (
+	CommandPublish    = "publish"
+	CommandRegenerate = "regenerate"
+	CommandDiscard    = "discard"
+)
+
+const (
+	// DefaultReviewTimeout is how long a draft waits for the admin's reply
+	DefaultReviewTimeout = 48 * time.Hour
+	// reviewActionTimeout bounds one review action (Contentful calls and the reply)
+	reviewActionTimeout = 2 * time.Minute
+	// maxPreviewImage bounds the cover downloaded for the WhatsApp preview
+	maxPreviewImage = 5 << 20
+)
+
+// Audit actors
+const (
+	actorSystem = "system"
+	actorAdmin  = "admin:"
+)
+
+// sendPreview sends the draft (cover, title, excerpt, link and the review commands) to the
+// admin. It returns the message ID ("" if not sent), so a quoted reply identifies the draft.
+func (s *BlogService) sendPreview(ctx context.Context, post *Post) string {
+	if s.cfg.AdminPhone == "" {
+		log.Println("⚠️ [BLOG] Cannot send preview: BLOG_ADMIN_PHONE is not configured")
+		return ""
+	}
+	client, ok := s.waManager.GetClient(s.clientID)
+	if !ok || !client.IsReady() {
+		log.Printf("⚠️ [BLOG] Cannot send preview: WhatsApp client %s not ready", s.clientID)
+		return ""
+	}
+
+	caption := fmt.Sprintf("📝 *DRAFT BLOG BARU* (kode: %s)\n\n📌 *%s*\n🏷️ Topik: %s\n\n%s\n\n🔗 %s\n\nBalas pesan ini dengan:\n• *publish* - terbitkan artikel\n• *regenerate* - tulis ulang artikel\n• *discard* - hapus draft\n\n⏰ Menunggu review sampai %s",
+		post.ShortID(), post.Title, post.Topic, post.Excerpt, post.DraftURL, post.ReviewDeadline.Format("02 Jan 2006 15:04 WIB"))
+	jid := types.NewJID(s.cfg.AdminPhone, types.DefaultUserServer)
+
+	msg := &waProto.Message{Conversation: proto.String(caption)}
+	if post.ImageURL != "" {
+		image, err := s.previewImage(ctx, client.WAClient, post.ImageURL, caption)
+		if err != nil {
+			log.Printf("⚠️ [BLOG] Sending preview without cover: %v", err)
+		} else {
+			msg = &waProto.Message{ImageMessage: image}
+		}
+	}
+
+	resp, err := client.WAClient.SendMessage(ctx, jid, msg)
+	if err != nil {
+		log.Printf("⚠️ [BLOG] Failed to send preview: %v", err)
+		return ""
+	}
+	return resp.ID
+}
+
+// previewImage downloads the cover and uploads it to WhatsApp
+func (s *BlogService) previewImage(ctx context.Context, waClient *whatsmeow.Client, imageURL, caption string) (*waProto.ImageMessage, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cover download returned %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxPreviewImage+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxPreviewImage {
+		return nil, fmt.Errorf("cover is larger than %d bytes", maxPreviewImage)
+	}
+
+	uploaded, err := waClient.Upload(ctx, data, whatsmeow.MediaImage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload cover: %w", err)
+	}
+	return &waProto.ImageMessage{
+		Caption:       proto.String(caption),
+		Mimetype:      proto.String(http.DetectContentType(data)),
+		URL:           proto.String(uploaded.URL),
+		DirectPath:    proto.String(uploaded.DirectPath),
+		MediaKey:      uploaded.MediaKey,
+		FileEncSHA256: uploaded.FileEncSHA256,
+		FileSHA256:    uploaded.FileSHA256,
+		FileLength:    proto.Uint64(uint64(len(data))),
+	}, nil
+}
+
+// parseCommand reads "<command> [code]" from a reply; ok is false for anything else
+func parseCommand(body string) (command, code string, ok bool) {
+	fields := strings.Fields(strings.ToLower(body))
+	if len(fields) == 0 || len(fields) > 2 {
+		return "", "", false
+	}
+	command = strings.TrimPrefix(fields[0], "/")
+	switch command {
+	case CommandPublish, CommandRegenerate, CommandDiscard:
+	default:
+		return "", "", false
+	}
+	if len(fields) == 2 {
+		code = fields[1]
+	}
+	return command, code, true
+}
+
+// handleCommand receives the admin's messages; review commands run in the background
+func (s *BlogService) handleCommand(cmd whatsapp.Command) {
+	command, code, ok := parseCommand(cmd.Body)
+	if !ok {
+		return
+	}
+
+	// Stop waits for the running actions: once it started, new ones are dropped
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		log.Printf("⚠️ [BLOG] Ignoring review command from %s: shutting down", cmd.Sender)
+		return
+	}
+	s.wg.Add(1)
+	s.mu.Unlock()
+	log.Printf("📥 [BLOG] Review command from %s: %s %s", cmd.Sender, command, code)
+
+	go func() {
+		defer s.wg.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), reviewActionTimeout)
+		defer cancel()
+
+		reply := s.review(ctx, cmd, command, code)
+		s.sendText(ctx, cmd.Client, cmd.Chat, reply)
+	}()
+}
+
+// review applies a command to the draft it refers to and returns the reply for the admin
+func (s *BlogService) review(ctx context.Context, cmd whatsapp.Command, command, code string) string {
+	s.reviewMu.Lock()
+	defer s.reviewMu.Unlock()
+
+	post, problem := s.resolveDraft(ctx, cmd.QuotedID, code)
+	if post == nil {
+		return problem
+	}
+	actor := actorAdmin + cmd.Sender
+
+	switch command {
+	case CommandPublish:
+		if err := s.cms.Publish(ctx, post.EntryID, post.AssetID); err != nil {
+			s.audit(ctx, post.ID, ActionFailed, actor, "publish: "+err.Error())
+			return fmt.Sprintf("❌ Gagal menerbitkan \"%s\": %v\n\nDraft tetap menunggu review, silakan coba lagi.", post.Title, err)
+		}
+		post.Status = PostPublished
+		if s.cfg.PostURL != "" {
+			post.PublishedURL = strings.TrimRight(s.cfg.PostURL, "/") + "/" + post.Slug
+		}
+		s.closeReview(ctx, post, ActionPublished, actor, post.PublishedURL)
+		return fmt.Sprintf("✅ *ARTIKEL DITERBITKAN*\n\n📌 %s\n🔗 %s", post.Title, post.PublishedURL)
+
+	case CommandRegenerate:
+		next, err := s.Trigger(TriggerRegenerate, post.Topic)
+		if err == ErrAlreadyRunning {
+			return "⏳ Masih ada artikel yang sedang ditulis. Silakan coba lagi beberapa menit lagi."
+		}
+		if err != nil {
+			s.audit(ctx, post.ID, ActionFailed, actor, "regenerate: "+err.Error())
+			return fmt.Sprintf("❌ Gagal menulis ulang \"%s\": %v", post.Title, err)
+		}
+		if err := s.cms.Delete(ctx, post.EntryID, post.AssetID); err != nil {
+			log.Printf("⚠️ [BLOG] Failed to delete replaced draft %s: %v", post.ID, err)
+		}
+		post.Status = PostRegenerated
+		post.ReplacedBy = next.ID
+		s.closeReview(ctx, post, ActionRegenerated, actor, "replaced by "+next.ID)
+		return fmt.Sprintf("🔄 Menulis ulang artikel tentang \"%s\"...\n\nDraft baru akan dikirim setelah selesai.", post.Topic)
+
+	default: // CommandDiscard
+		if err := s.cms.Delete(ctx, post.EntryID, post.AssetID); err != nil {
+			s.audit(ctx, post.ID, ActionFailed, actor, "discard: "+err.Error())
+			return fmt.Sprintf("❌ Gagal menghapus draft \"%s\": %v", post.Title, err)
+		}
+		post.Status = PostDiscarded
+		s.closeReview(ctx, post, ActionDiscarded, actor, "")
+		return fmt.Sprintf("🗑️ Draft \"%s\" dihapus.", post.Title)
+	}
+}
+
+// resolveDraft finds the draft a reply refers to: the quoted preview, the code, or the only
+// pending draft. Without a match it returns the explanation for the admin.
+func (s *BlogService) resolveDraft(ctx context.Context, quotedID, code string) (*Post, string) {
+	if quotedID != "" {
+		post, err := s.store.GetByPreview(ctx, quotedID)
+		if err == nil {
+			if post.Status != PostDraft {
+				return nil, fmt.Sprintf("ℹ️ Draft \"%s\" sudah tidak menunggu review (status: %s).", post.Title, post.Status)
+			}
+			return post, ""
+		}
+		if err != ErrNotFound {
+			return nil, "❌ Gagal membaca draft: " + err.Error()
+		}
+	}
+
+	pending, err := s.store.Pending(ctx)
+	if err != nil {
+		return nil, "❌ Gagal membaca draft: " + err.Error()
+	}
+	if code != "" {
+		for _, post := range pending {
+			if strings.HasPrefix(post.ID, code) {
+				return post, ""
+			}
+		}
+		return nil, fmt.Sprintf("ℹ️ Tidak ada draft menunggu review dengan kode %s.", code)
+	}
+
+	switch len(pending) {
+	case 0:
+		return nil, "ℹ️ Tidak ada draft yang menunggu review."
+	case 1:
+		return pending[0], ""
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("ℹ️ Ada %d draft menunggu review. Balas pesan preview-nya atau sertakan kodenya, contoh: *publish %s*\n", len(pending), pending[0].ShortID()))
+	for _, post := range pending {
+		sb.WriteString(fmt.Sprintf("\n• %s - %s", post.ShortID(), post.Title))
+	}
+	return nil, sb.String()
+}
+
+// expireDrafts closes the reviews that ran past their deadline. The drafts stay in Contentful.
+func (s *BlogService) expireDrafts() {
+	ctx, cancel := context.WithTimeout(context.Background(), reviewActionTimeout)
+	defer cancel()
+
+	s.reviewMu.Lock()
+	defer s.reviewMu.Unlock()
+
+	pending, err := s.store.Pending(ctx)
+	if err != nil {
+		log.Printf("⚠️ [BLOG] Failed to check review deadlines: %v", err)
+		return
+	}
+	now := time.Now()
+	for _, post := range pending {
+		if post.ReviewDeadline == nil || now.Before(*post.ReviewDeadline) {
+			continue
+		}
+		post.Status = PostExpired
+		s.closeReview(ctx, post, ActionExpired, actorSystem, "")
+		log.Printf("⌛ [BLOG] Review of %q expired", post.Title)
+
+		if s.cfg.AdminPhone != "" {
+			jid := types.NewJID(s.cfg.AdminPhone, types.DefaultUserServer)
+			s.sendText(ctx, s.clientID, jid, fmt.Sprintf("⌛ Draft \"%s\" (kode: %s) tidak direview sampai batas waktu dan tidak diproses lagi.\n\nDraft tetap tersimpan di Contentful:\n🔗 %s",
+				post.Title, post.ShortID(), post.DraftURL))
+		}
+	}
+}
+
+// closeReview saves the final review state of a post and records it in the audit trail
+func (s *BlogService) closeReview(ctx context.Context, post *Post, action, actor, detail string) {
+	now := time.Now()
+	post.ReviewedAt = &now
+	if err := s.store.Update(ctx, post); err != nil {
+		log.Printf("❌ [BLOG] Failed to record review of %s: %v", post.ID, err)
+	}
+	s.audit(ctx, post.ID, action, actor, detail)
+}
+
+func (s *BlogService) audit(ctx context.Context, postID, action, actor, detail string) {
+	if err := s.store.AddEvent(ctx, postID, action, actor, detail); err != nil {
+		log.Printf("⚠️ [BLOG] Failed to record %s for %s: %v", action, postID, err)
+	}
+}
+
+// sendText sends a text message through session clientID
+func (s *BlogService) sendText(ctx context.Context, clientID string, jid types.JID, text string) {
+	client, ok := s.waManager.GetClient(clientID)
+	if !ok || !client.IsReady() {
+		log.Printf("⚠️ [BLOG] Cannot reply: WhatsApp client %s not ready", clientID)
+		return
+	}
+	if _, err := client.WAClient.SendMessage(ctx, jid, &waProto.Message{
+		Conversation: proto.String(text),
+	}); err != nil {
+		log.Printf("⚠️ [BLOG] Failed to reply: %v", err)
+	}
+}
@@ -0,0 +1,355 @@
+package blog
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// PostStatus represents the state of a generated post
+type PostStatus string
+
+const (
+	PostGenerating  PostStatus = "generating"
+	PostDraft       PostStatus = "draft" // created in Contentful, waiting for the admin's review
+	PostFailed      PostStatus = "failed"
+	PostPublished   PostStatus = "published"
+	PostDiscarded   PostStatus = "discarded"   // draft deleted from Contentful
+	PostRegenerated PostStatus = "regenerated" // draft deleted, replaced by a new post
+	PostExpired     PostStatus = "expired"     // not reviewed in time, left as a Contentful draft
+)
+
+// Post triggers
+const (
+	TriggerScheduled  = "scheduled"
+	TriggerManual     = "manual"
+	TriggerRegenerate = "regenerate"
+)
+
+// Audit trail actions
+const (
+	ActionCreated     = "created"
+	ActionPreviewSent = "preview_sent"
+	ActionPublished   = "published"
+	ActionRegenerated = "regenerated"
+	ActionDiscarded   = "discarded"
+	ActionExpired     = "expired"
+	ActionFailed      = "failed" // a review action that did not go through
+)
+
+// ErrNotFound is returned when a post ID does not exist
+var ErrNotFound = errors.New("blog post not found")
+
+// Post is the record of one generated article
+type Post struct {
+	ID          string     `json:"id"`
+	Trigger     string     `json:"trigger"`
+	Topic       string     `json:"topic"`
+	Status      PostStatus `json:"status"`
+	Title       string     `json:"title,omitempty"`
+	Slug        string     `json:"slug,omitempty"`
+	Excerpt     string     `json:"excerpt,omitempty"`
+	Tags        []string   `json:"tags"`
+	ImageURL    string     `json:"imageUrl,omitempty"`
+	ImageCredit string     `json:"imageCredit,omitempty"`
+	AssetID     string     `json:"assetId,omitempty"`
+	EntryID     string     `json:"entryId,omitempty"`
+	DraftURL    string     `json:"draftUrl,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+
+	// Review over WhatsApp
+	PreviewMessageID string     `json:"previewMessageId,omitempty"`
+	ReviewDeadline   *time.Time `json:"reviewDeadline,omitempty"`
+	ReviewedAt       *time.Time `json:"reviewedAt,omitempty"`
+	PublishedURL     string     `json:"publishedUrl,omitempty"`
+	ReplacedBy       string     `json:"replacedBy,omitempty"` // post generated by "regenerate"
+}
+
+// clone returns a copy of the post that shares no memory with it
+func (p *Post) clone() *Post {
+	c := *p
+	c.Tags = append([]string{}, p.Tags...)
+	c.ReviewDeadline = cloneTime(p.ReviewDeadline)
+	c.ReviewedAt = cloneTime(p.ReviewedAt)
+	return &c
+}
+
+func cloneTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
+
+// ShortID is the code the admin can use to name a draft in a reply
+func (p *Post) ShortID() string {
+	if len(p.ID) < 6 {
+		return p.ID
+	}
+	return p.ID[:6]
+}
+
+// Event is one entry of a post's audit trail
+type Event struct {
+	Action    string    `json:"action"`
+	Actor     string    `json:"actor"` // system, api or admin:<phone>
+	Detail    string    `json:"detail,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Store persists the generated posts in SQLite
+type Store struct {
+	db *sql.DB
+}
+
+const schema = `
+CREATE TABLE IF NOT EXISTS blog_posts (
+	seq                INTEGER PRIMARY KEY AUTOINCREMENT,
+	id                 TEXT NOT NULL UNIQUE,
+	trigger            TEXT NOT NULL,
+	topic              TEXT NOT NULL,
+	status             TEXT NOT NULL,
+	title              TEXT NOT NULL DEFAULT '',
+	slug               TEXT NOT NULL DEFAULT '',
+	excerpt            TEXT NOT NULL DEFAULT '',
+	tags               TEXT NOT NULL DEFAULT '[]',
+	image_url          TEXT NOT NULL DEFAULT '',
+	image_credit       TEXT NOT NULL DEFAULT '',
+	asset_id           TEXT NOT NULL DEFAULT '',
+	entry_id           TEXT NOT NULL DEFAULT '',
+	draft_url          TEXT NOT NULL DEFAULT '',
+	error              TEXT NOT NULL DEFAULT '',
+	created_at         INTEGER NOT NULL,
+	updated_at         INTEGER NOT NULL,
+	preview_message_id TEXT NOT NULL DEFAULT '',
+	review_deadline    INTEGER NOT NULL DEFAULT 0,
+	reviewed_at        INTEGER NOT NULL DEFAULT 0,
+	published_url      TEXT NOT NULL DEFAULT '',
+	replaced_by        TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_blog_posts_status ON blog_posts(status);
+
+CREATE TABLE IF NOT EXISTS blog_post_events (
+	seq        INTEGER PRIMARY KEY AUTOINCREMENT,
+	post_id    TEXT NOT NULL REFERENCES blog_posts(id) ON DELETE CASCADE,
+	action     TEXT NOT NULL,
+	actor      TEXT NOT NULL,
+	detail     TEXT NOT NULL DEFAULT '',
+	created_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_blog_post_events_post ON blog_post_events(post_id);
+`
+
+const postColumns = `id, trigger, topic, status, title, slug, excerpt, tags, image_url, image_credit,
+	asset_id, entry_id, draft_url, error, created_at, updated_at,
+	preview_message_id, review_deadline, reviewed_at, published_url, replaced_by`
+
+// NewStore creates the blog tables if needed
+func NewStore(db *sql.DB) (*Store, error) {
+	if _, err := db.Exec(schema); err != nil {
+		return nil, fmt.Errorf("failed to create blog schema: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Insert records a new post being generated for topic
+func (s *Store) Insert(ctx context.Context, trigger, topic string) (*Post, error) {
+	now := time.Now()
+	post := &Post{
+		ID:        newPostID(),
+		Trigger:   trigger,
+		Topic:     topic,
+		Status:    PostGenerating,
+		Tags:      []string{},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	_, err := s.db.ExecContext(ctx, `INSERT INTO blog_posts (id, trigger, topic, status, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		post.ID, post.Trigger, post.Topic, string(post.Status), now.UnixMilli(), now.UnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert blog post: %w", err)
+	}
+	return post, nil
+}
+
+// Update saves every field of a post
+func (s *Store) Update(ctx context.Context, post *Post) error {
+	tags, err := json.Marshal(post.Tags)
+	if err != nil {
+		return err
+	}
+	post.UpdatedAt = time.Now()
+	_, err = s.db.ExecContext(ctx, `UPDATE blog_posts SET status = ?, title = ?, slug = ?, excerpt = ?, tags = ?,
+		image_url = ?, image_credit = ?, asset_id = ?, entry_id = ?, draft_url = ?, error = ?, updated_at = ?,
+		preview_message_id = ?, review_deadline = ?, reviewed_at = ?, published_url = ?, replaced_by = ? WHERE id = ?`,
+		string(post.Status), post.Title, post.Slug, post.Excerpt, string(tags), post.ImageURL, post.ImageCredit,
+		post.AssetID, post.EntryID, post.DraftURL, post.Error, post.UpdatedAt.UnixMilli(),
+		post.PreviewMessageID, unixMilli(post.ReviewDeadline), unixMilli(post.ReviewedAt), post.PublishedURL, post.ReplacedBy, post.ID)
+	return err
+}
+
+// Get returns a post by ID
+func (s *Store) Get(ctx context.Context, id string) (*Post, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+postColumns+` FROM blog_posts WHERE id = ?`, id)
+	post, err := scanPost(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return post, err
+}
+
+// GetByPreview returns the post whose review preview is the WhatsApp message msgID
+func (s *Store) GetByPreview(ctx context.Context, msgID string) (*Post, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+postColumns+` FROM blog_posts WHERE preview_message_id = ?`, msgID)
+	post, err := scanPost(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return post, err
+}
+
+// List returns posts newest first
+func (s *Store) List(ctx context.Context, limit int) ([]*Post, error) {
+	return s.query(ctx, `SELECT `+postColumns+` FROM blog_posts ORDER BY seq DESC LIMIT ?`, limit)
+}
+
+// Pending returns the drafts waiting for review, oldest first
+func (s *Store) Pending(ctx context.Context) ([]*Post, error) {
+	return s.query(ctx, `SELECT `+postColumns+` FROM blog_posts WHERE status = ? ORDER BY seq`, string(PostDraft))
+}
+
+func (s *Store) query(ctx context.Context, query string, args ...interface{}) ([]*Post, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := make([]*Post, 0)
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, post)
+	}
+	return posts, rows.Err()
+}
+
+// AddEvent appends an entry to the audit trail of a post
+func (s *Store) AddEvent(ctx context.Context, postID, action, actor, detail string) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO blog_post_events (post_id, action, actor, detail, created_at) VALUES (?, ?, ?, ?, ?)`,
+		postID, action, actor, detail, time.Now().UnixMilli())
+	return err
+}
+
+// Events returns the audit trail of a post, oldest first
+func (s *Store) Events(ctx context.Context, postID string) ([]Event, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT action, actor, detail, created_at FROM blog_post_events WHERE post_id = ? ORDER BY seq`, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]Event, 0)
+	for rows.Next() {
+		var e Event
+		var createdAt int64
+		if err := rows.Scan(&e.Action, &e.Actor, &e.Detail, &createdAt); err != nil {
+			return nil, err
+		}
+		e.CreatedAt = time.UnixMilli(createdAt)
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// TopicCounts returns how many posts were generated for each topic (failed runs excluded)
+func (s *Store) TopicCounts(ctx context.Context) (map[string]int, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT topic, COUNT(*) FROM blog_posts WHERE status != ? GROUP BY topic`, string(PostFailed))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var topic string
+		var n int
+		if err := rows.Scan(&topic, &n); err != nil {
+			return nil, err
+		}
+		counts[topic] = n
+	}
+	return counts, rows.Err()
+}
+
+// FailInterrupted marks posts left "generating" by a previous process as failed
+func (s *Store) FailInterrupted(ctx context.Context) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `UPDATE blog_posts SET status = ?, error = ?, updated_at = ? WHERE status = ?`,
+		string(PostFailed), "interrupted by shutdown", time.Now().UnixMilli(), string(PostGenerating))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPost(row rowScanner) (*Post, error) {
+	var (
+		post                       Post
+		status, tags               string
+		createdAt, updatedAt       int64
+		reviewDeadline, reviewedAt int64
+	)
+	err := row.Scan(&post.ID, &post.Trigger, &post.Topic, &status, &post.Title, &post.Slug, &post.Excerpt, &tags,
+		&post.ImageURL, &post.ImageCredit, &post.AssetID, &post.EntryID, &post.DraftURL, &post.Error, &createdAt, &updatedAt,
+		&post.PreviewMessageID, &reviewDeadline, &reviewedAt, &post.PublishedURL, &post.ReplacedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	post.Status = PostStatus(status)
+	if err := json.Unmarshal([]byte(tags), &post.Tags); err != nil || post.Tags == nil {
+		post.Tags = []string{}
+	}
+	post.CreatedAt = time.UnixMilli(createdAt)
+	post.UpdatedAt = time.UnixMilli(updatedAt)
+	post.ReviewDeadline = fromUnixMilli(reviewDeadline)
+	post.ReviewedAt = fromUnixMilli(reviewedAt)
+	return &post, nil
+}
+
+// unixMilli stores an optional time (0 when unset)
+func unixMilli(t *time.Time) int64 {
+	if t == nil {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+func fromUnixMilli(ms int64) *time.Time {
+	if ms == 0 {
+		return nil
+	}
+	t := time.UnixMilli(ms)
+	return &t
+}
+
+func newPostID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,153 @@
+package monitor
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxBodyCheck bounds how much of a response is searched for expectBody
+const maxBodyCheck = 1 << 20
+
+// Check is the result of one probe of a target
+type Check struct {
+	Time          time.Time    `json:"time"`
+	Status        HealthStatus `json:"status"`
+	LatencyMs     int          `json:"latencyMs"`
+	Error         string       `json:"error,omitempty"`
+	CertExpiresAt *time.Time   `json:"certExpiresAt,omitempty"`
+}
+
+// probe runs one check of a target. Status is up, slow, expiring or down; alert state is
+// tracked by the caller.
+func probe(ctx context.Context, t *Target) Check {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(t.Timeout))
+	defer cancel()
+
+	check := Check{Time: time.Now()}
+	start := time.Now()
+
+	var err error
+	var certExpiry time.Time
+	switch t.Type {
+	case TargetHTTP:
+		certExpiry, err = probeHTTP(ctx, t)
+	case TargetTCP:
+		err = probeTCP(ctx, t.Address)
+	case TargetTLS:
+		certExpiry, err = probeTLS(ctx, t.Address)
+	}
+	latency := time.Since(start)
+	check.LatencyMs = int(latency.Milliseconds())
+
+	if !certExpiry.IsZero() {
+		check.CertExpiresAt = &certExpiry
+	}
+
+	switch {
+	case err != nil:
+		check.Status = StatusDown
+		check.Error = err.Error()
+	case latency > time.Duration(t.SlowThreshold):
+		check.Status = StatusSlow
+	case !certExpiry.IsZero() && t.CertWarnDays > 0 && time.Until(certExpiry) < time.Duration(t.CertWarnDays)*24*time.Hour:
+		check.Status = StatusExpiring
+		check.Error = fmt.Sprintf("certificate expires %s", certExpiry.Format("2006-01-02"))
+	default:
+		check.Status = StatusUp
+	}
+	return check
+}
+
+// probeHTTP GETs the URL and checks the status code and body. For https URLs with
+// certWarnDays set, it also returns the certificate expiry.
+func probeHTTP(ctx context.Context, t *Target) (time.Time, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.URL, nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	req.Header.Set("User-Agent", "wa-server-go-monitor/1.0")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer resp.Body.Close()
+
+	var certExpiry time.Time
+	if t.CertWarnDays > 0 && resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		certExpiry = resp.TLS.PeerCertificates[0].NotAfter
+	}
+
+	if !statusAccepted(resp.StatusCode, t.ExpectStatus) {
+		return certExpiry, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if t.ExpectBody != "" {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodyCheck))
+		if err != nil {
+			return certExpiry, fmt.Errorf("failed to read body: %w", err)
+		}
+		if !strings.Contains(string(body), t.ExpectBody) {
+			return certExpiry, fmt.Errorf("body does not contain %q", t.ExpectBody)
+		}
+	}
+	return certExpiry, nil
+}
+
+func probeTCP(ctx context.Context, address string) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// probeTLS completes a TLS handshake (verifying the chain) and returns the leaf certificate expiry
+func probeTLS(ctx context.Context, address string) (time.Time, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return time.Time{}, err
+	}
+	d := tls.Dialer{Config: &tls.Config{ServerName: host}}
+	conn, err := d.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer conn.Close()
+
+	certs := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return time.Time{}, fmt.Errorf("no certificate presented")
+	}
+	return certs[0].NotAfter, nil
+}
+
+// statusAccepted reports whether code is expected (any code below 400 if none are listed)
+func statusAccepted(code int, expected []int) bool {
+	if len(expected) == 0 {
+		return code < 400
+	}
+	for _, c := range expected {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// targetHost returns the host a target points at, for display
+func targetHost(t *Target) string {
+	if t.Type == TargetHTTP {
+		if u, err := url.Parse(t.URL); err == nil {
+			return u.Host
+		}
+	}
+	return t.Address
+}
@@ -4,11 +4,13 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"net/http"
+	"sync"
 	"time"
 
+	"wa-server-go/internal/templates"
+	"wa-server-go/internal/whatsapp"
+
 	"github.com/robfig/cron/v3"
-	"go.mau.fi/whatsmeow"
 	waProto "go.mau.fi/whatsmeow/proto/waE2E"
 	"go.mau.fi/whatsmeow/types"
 	"google.golang.org/protobuf/proto"
@@ -21,159 +23,371 @@ const (
 	StatusUp       HealthStatus = "up"
 	StatusDown     HealthStatus = "down"
 	StatusSlow     HealthStatus = "slow"
+	StatusExpiring HealthStatus = "expiring" // TLS certificate close to expiry
 	StatusRecovery HealthStatus = "recovery"
 )
 
+// historySize is how many recent checks are kept per target for GET /monitor/status
+const historySize = 100
+
+// checkRetention is how long stored checks are kept for reports
+const checkRetention = 90 * 24 * time.Hour
+
+// targetState is the alerting state and recent history of one target
+type targetState struct {
+	target          Target
+	lastStatus      HealthStatus
+	downSince       time.Time
+	alertSent       bool // down alert sent for the current outage
+	slowCount       int
+	slowAlertSent   bool
+	expiryAlertSent bool
+	lastCheck       *Check
+	history         []Check
+}
+
+// alert is a message to send once the state lock is released
+type alert struct {
+	target *Target
+	status HealthStatus
+	check  Check
+	since  time.Time
+}
+
 // MonitorService handles system health monitoring
 type MonitorService struct {
-	waClient     *whatsmeow.Client
-	healthURL    string
-	alertPhone   string
-	cron         *cron.Cron
-	lastStatus   HealthStatus
-	downSince    time.Time
-	alertSent    bool
-	slowCount    int
+	waManager  *whatsapp.Manager
+	clientID   string
+	alertPhone string
+	store      *Store
+	cron       *cron.Cron
+
+	mu      sync.Mutex
+	targets []*targetState
 }
 
-// NewMonitorService creates a new monitor service
-func NewMonitorService(waClient *whatsmeow.Client, webURL, alertPhone string) *MonitorService {
-	return &MonitorService{
-		waClient:   waClient,
-		healthURL:  fmt.Sprintf("%s/api/health", webURL),
+// NewMonitorService creates a monitor for targets. Alerts go through the WhatsApp client clientID
+// to each target's recipients (alertPhone by default); every check is saved in store.
+func NewMonitorService(waManager *whatsapp.Manager, clientID string, targets []Target, alertPhone string, store *Store) (*MonitorService, error) {
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no monitor targets configured")
+	}
+
+	s := &MonitorService{
+		waManager:  waManager,
+		clientID:   clientID,
 		alertPhone: alertPhone,
+		store:      store,
 		cron:       cron.New(),
-		lastStatus: StatusUp,
 	}
+	seen := make(map[string]bool)
+	for _, t := range targets {
+		if err := t.normalize(alertPhone); err != nil {
+			return nil, err
+		}
+		if seen[t.Name] {
+			return nil, fmt.Errorf("duplicate monitor target %q", t.Name)
+		}
+		seen[t.Name] = true
+		s.targets = append(s.targets, &targetState{target: t, lastStatus: StatusUp})
+	}
+	return s, nil
 }
 
-// Start starts the monitor cron job (runs every 5 minutes)
+// Start restores the recent state from the store, starts the monitor cron job (runs every
+// 5 minutes) and the weekly summary (Monday 08:00), and runs a first check right away
 func (s *MonitorService) Start() error {
+	s.restore(context.Background())
+
 	_, err := s.cron.AddFunc("*/5 * * * *", func() {
 		s.checkHealth()
 	})
 	if err != nil {
 		return fmt.Errorf("failed to schedule monitor: %w", err)
 	}
+	_, err = s.cron.AddFunc("0 8 * * 1", func() {
+		s.weeklySummary()
+	})
+	if err != nil {
+		return fmt.Errorf("failed to schedule weekly summary: %w", err)
+	}
 
 	s.cron.Start()
-	log.Println("✅ [MONITOR] Health check scheduler started (runs every 5 mins)")
+	go s.checkHealth()
+	log.Printf("✅ [MONITOR] Health check scheduler started (%d target(s), runs every 5 mins, weekly summary on Monday 08:00)", len(s.targets))
 	return nil
 }
 
-// Stop stops the monitor cron job
-func (s *MonitorService) Stop() {
-	s.cron.Stop()
+// restore loads the recent history and alerting state of each target saved before a restart.
+// The saved checks are replayed without sending their alerts, so an outage (or slow or expiring
+// spell) that was already reported is not reported again, and its recovery still is.
+func (s *MonitorService) restore(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, st := range s.targets {
+		history, err := s.store.Recent(ctx, st.target.Name, historySize)
+		if err != nil {
+			log.Printf("⚠️ [MONITOR] Failed to load history of %s: %v", st.target.Name, err)
+			continue
+		}
+		if len(history) == 0 {
+			continue
+		}
+		for _, check := range history {
+			st.record(check)
+		}
+
+		// An outage may have started before the loaded history
+		last := history[len(history)-1]
+		if last.Status == StatusDown {
+			if since, down, err := s.store.DownSince(ctx, st.target.Name, last.Time.Add(time.Millisecond)); err == nil && down && since.Before(st.downSince) {
+				st.downSince = since
+			}
+			if last.Time.Sub(st.downSince) >= time.Duration(st.target.DownAfter) {
+				st.alertSent = true
+			}
+		}
+	}
 }
 
-// checkHealth performs a health check and sends alerts if needed
+// Stop stops the monitor cron job and waits (until ctx expires) for a running check
+func (s *MonitorService) Stop(ctx context.Context) error {
+	select {
+	case <-s.cron.Stop().Done():
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("gave up waiting for the running check: %w", ctx.Err())
+	}
+}
+
+// checkHealth checks all targets in parallel and sends alerts if needed
 func (s *MonitorService) checkHealth() {
 	ctx := context.Background()
-	now := time.Now()
 
-	start := time.Now()
-	status, latency := s.pingHealth()
-	log.Printf("🏥 [MONITOR] Health check: %s (latency: %dms)", status, latency)
+	checks := make([]Check, len(s.targets))
+	var wg sync.WaitGroup
+	for i, st := range s.targets {
+		wg.Add(1)
+		go func(i int, t *Target) {
+			defer wg.Done()
+			checks[i] = probe(ctx, t)
+		}(i, &st.target)
+	}
+	wg.Wait()
+
+	var alerts []alert
+	s.mu.Lock()
+	for i, st := range s.targets {
+		check := checks[i]
+		if check.Error != "" {
+			log.Printf("🏥 [MONITOR] %s: %s (latency: %dms, %s)", st.target.Name, check.Status, check.LatencyMs, check.Error)
+		} else {
+			log.Printf("🏥 [MONITOR] %s: %s (latency: %dms)", st.target.Name, check.Status, check.LatencyMs)
+		}
+		alerts = append(alerts, st.record(check)...)
+	}
+	s.mu.Unlock()
+
+	for i, st := range s.targets {
+		if err := s.store.Insert(ctx, st.target.Name, checks[i]); err != nil {
+			log.Printf("⚠️ [MONITOR] Failed to save check of %s: %v", st.target.Name, err)
+		}
+	}
+
+	for _, a := range alerts {
+		s.sendAlert(ctx, a)
+	}
+}
+
+// record applies a check to the target state and returns the alerts it triggers
+func (st *targetState) record(check Check) []alert {
+	var alerts []alert
+	t := &st.target
+	now := check.Time
 
 	// Handle status transitions
-	switch status {
+	switch check.Status {
 	case StatusDown:
-		if s.lastStatus != StatusDown {
-			s.downSince = now
-			s.alertSent = false
+		if st.lastStatus != StatusDown {
+			st.downSince = now
+			st.alertSent = false
 		}
-		// Send alert after 5 minutes of downtime
-		if !s.alertSent && now.Sub(s.downSince) > 5*time.Minute {
-			s.sendAlert(ctx, StatusDown, latency)
-			s.alertSent = true
+		// Alert once the target has been down for downAfter
+		if !st.alertSent && now.Sub(st.downSince) >= time.Duration(t.DownAfter) {
+			alerts = append(alerts, alert{target: t, status: StatusDown, check: check, since: st.downSince})
+			st.alertSent = true
 		}
 
 	case StatusSlow:
-		s.slowCount++
-		// Alert after 3 consecutive slow checks
-		if s.slowCount >= 3 && s.lastStatus != StatusSlow {
-			s.sendAlert(ctx, StatusSlow, latency)
+		st.slowCount++
+		// Alert after slowAfter consecutive slow checks
+		if st.slowCount >= t.SlowAfter && !st.slowAlertSent {
+			alerts = append(alerts, alert{target: t, status: StatusSlow, check: check})
+			st.slowAlertSent = true
 		}
 
-	case StatusUp:
-		// Send recovery alert if was down
-		if s.lastStatus == StatusDown && s.alertSent {
-			s.sendAlert(ctx, StatusRecovery, latency)
+	case StatusExpiring:
+		if !st.expiryAlertSent {
+			alerts = append(alerts, alert{target: t, status: StatusExpiring, check: check})
+			st.expiryAlertSent = true
 		}
-		s.slowCount = 0
-		s.alertSent = false
 	}
 
-	s.lastStatus = status
-	_ = start // Used for latency calculation
-}
-
-// pingHealth performs the actual health check
-func (s *MonitorService) pingHealth() (HealthStatus, int) {
-	client := &http.Client{Timeout: 10 * time.Second}
-	
-	start := time.Now()
-	resp, err := client.Get(s.healthURL)
-	latency := int(time.Since(start).Milliseconds())
-
-	if err != nil {
-		log.Printf("❌ [MONITOR] Health check failed: %v", err)
-		return StatusDown, latency
+	if check.Status != StatusDown {
+		// Send recovery alert if an outage was reported
+		if st.lastStatus == StatusDown && st.alertSent {
+			alerts = append(alerts, alert{target: t, status: StatusRecovery, check: check, since: st.downSince})
+		}
+		st.alertSent = false
+		st.downSince = time.Time{}
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode >= 500 {
-		return StatusDown, latency
+	if check.Status != StatusSlow {
+		st.slowCount = 0
+		st.slowAlertSent = false
 	}
-
-	if latency > 5000 {
-		return StatusSlow, latency
+	if check.Status == StatusUp {
+		st.expiryAlertSent = false
 	}
 
-	return StatusUp, latency
+	st.lastStatus = check.Status
+	st.lastCheck = &check
+	st.history = append(st.history, check)
+	if len(st.history) > historySize {
+		st.history = st.history[len(st.history)-historySize:]
+	}
+	return alerts
 }
 
-// sendAlert sends a WhatsApp alert
-func (s *MonitorService) sendAlert(ctx context.Context, status HealthStatus, latency int) {
-	if s.waClient == nil || s.alertPhone == "" {
-		log.Printf("⚠️ [MONITOR] Cannot send alert: client or phone not configured")
+// sendAlert sends a WhatsApp alert to the target's recipients
+func (s *MonitorService) sendAlert(ctx context.Context, a alert) {
+	if len(a.target.Recipients) == 0 {
+		log.Printf("⚠️ [MONITOR] Cannot send %s alert for %s: no recipients configured", a.status, a.target.Name)
+		return
+	}
+	client, ok := s.waManager.GetClient(s.clientID)
+	if !ok || !client.IsReady() {
+		log.Printf("⚠️ [MONITOR] Cannot send %s alert for %s: WhatsApp client %s not ready", a.status, a.target.Name, s.clientID)
 		return
 	}
 
-	var message string
-	timestamp := time.Now()
+	message := alertMessage(a)
+	for _, phone := range a.target.Recipients {
+		jid := types.NewJID(phone, types.DefaultUserServer)
+		_, err := client.WAClient.SendMessage(ctx, jid, &waProto.Message{
+			Conversation: proto.String(message),
+		})
+		if err != nil {
+			log.Printf("❌ [MONITOR] Failed to send alert to %s: %v", phone, err)
+		} else {
+			log.Printf("📤 [MONITOR] Alert sent to %s for %s: %s", phone, a.target.Name, a.status)
+		}
+	}
+}
 
-	switch status {
+func alertMessage(a alert) string {
+	timestamp := a.check.Time.Format("02 Jan 2006 15:04")
+	target := fmt.Sprintf("🎯 Target: %s (%s)", a.target.Name, targetHost(a.target))
+
+	switch a.status {
 	case StatusRecovery:
-		message = fmt.Sprintf("✅ *SISTEM PULIH*\n\n🕐 %s\n\nSistem Valpro Intertech kembali online setelah mengalami gangguan.",
-			timestamp.Format("02 Jan 2006 15:04"))
+		return fmt.Sprintf("✅ *SISTEM PULIH*\n\n%s\n🕐 %s\n⏱️ Down selama: %s\n\nSistem kembali online setelah mengalami gangguan.",
+			target, timestamp, a.check.Time.Sub(a.since).Round(time.Minute))
 	case StatusSlow:
-		message = fmt.Sprintf("⚠️ *SISTEM LAMBAT*\n\n🕐 %s\n⏱️ Latency: %dms\n\nRespon sistem lebih lambat dari normal (> 5 detik).",
-			timestamp.Format("02 Jan 2006 15:04"), latency)
-	case StatusDown:
-		message = fmt.Sprintf("🚨 *SISTEM DOWN*\n\n🕐 %s\n⏱️ Down sejak: %s\n\nSistem tidak dapat diakses. Tim teknis sedang menangani.",
-			timestamp.Format("02 Jan 2006 15:04"), s.downSince.Format("15:04"))
+		return fmt.Sprintf("⚠️ *SISTEM LAMBAT*\n\n%s\n🕐 %s\n⏱️ Latency: %dms\n\nRespon sistem lebih lambat dari normal (> %s).",
+			target, timestamp, a.check.LatencyMs, time.Duration(a.target.SlowThreshold))
+	case StatusExpiring:
+		return fmt.Sprintf("🔒 *SERTIFIKAT SEGERA KEDALUWARSA*\n\n%s\n🕐 %s\n📅 Berlaku sampai: %s\n\nSegera perbarui sertifikat TLS.",
+			target, timestamp, a.check.CertExpiresAt.Format("02 Jan 2006"))
 	default:
+		return fmt.Sprintf("🚨 *SISTEM DOWN*\n\n%s\n🕐 %s\n⏱️ Down sejak: %s\n❗ %s\n\nSistem tidak dapat diakses. Tim teknis sedang menangani.",
+			target, timestamp, a.since.Format("15:04"), a.check.Error)
+	}
+}
+
+// weeklySummary sends the report of the last 7 days to the alert phone and prunes old checks
+func (s *MonitorService) weeklySummary() {
+	ctx := context.Background()
+	to := time.Now()
+	from := to.AddDate(0, 0, -7)
+
+	if n, err := s.store.Prune(ctx, to.Add(-checkRetention)); err != nil {
+		log.Printf("⚠️ [MONITOR] Failed to prune old checks: %v", err)
+	} else if n > 0 {
+		log.Printf("🧹 [MONITOR] Pruned %d check(s) older than %d days", n, int(checkRetention.Hours()/24))
+	}
+
+	if s.alertPhone == "" {
+		log.Println("⚠️ [MONITOR] Cannot send weekly summary: no alert phone configured")
+		return
+	}
+	report, err := s.Report(ctx, from, to)
+	if err != nil {
+		log.Printf("❌ [MONITOR] Failed to build weekly report: %v", err)
+		return
+	}
+	client, ok := s.waManager.GetClient(s.clientID)
+	if !ok || !client.IsReady() {
+		log.Printf("⚠️ [MONITOR] Cannot send weekly summary: WhatsApp client %s not ready", s.clientID)
 		return
 	}
 
+	targets := make([]templates.HealthReportTarget, 0, len(report.Targets))
+	for _, t := range report.Targets {
+		targets = append(targets, templates.HealthReportTarget{
+			Name:          t.Name,
+			UptimePercent: t.UptimePercent,
+			P95LatencyMs:  t.P95LatencyMs,
+			Incidents:     t.Incidents,
+			Downtime:      time.Duration(t.DowntimeSeconds) * time.Second,
+		})
+	}
 	jid := types.NewJID(s.alertPhone, types.DefaultUserServer)
-	_, err := s.waClient.SendMessage(ctx, jid, &waProto.Message{
-		Conversation: proto.String(message),
+	_, err = client.WAClient.SendMessage(ctx, jid, &waProto.Message{
+		Conversation: proto.String(templates.GenerateHealthReport(from, to, targets)),
 	})
 	if err != nil {
-		log.Printf("❌ [MONITOR] Failed to send alert: %v", err)
-	} else {
-		log.Printf("📤 [MONITOR] Alert sent for status: %s", status)
+		log.Printf("❌ [MONITOR] Failed to send weekly summary to %s: %v", s.alertPhone, err)
+		return
 	}
+	log.Printf("📤 [MONITOR] Weekly summary sent to %s", s.alertPhone)
 }
 
-// GetStatus returns current health status (for API)
+// GetStatus returns the current status and recent checks of every target (for API)
 func (s *MonitorService) GetStatus() map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	targets := make([]map[string]interface{}, 0, len(s.targets))
+	overall := StatusUp
+	for _, st := range s.targets {
+		var downSince *time.Time
+		if !st.downSince.IsZero() {
+			t := st.downSince
+			downSince = &t
+		}
+		history := make([]Check, len(st.history))
+		copy(history, st.history)
+
+		targets = append(targets, map[string]interface{}{
+			"name":       st.target.Name,
+			"type":       st.target.Type,
+			"host":       targetHost(&st.target),
+			"config":     st.target,
+			"lastStatus": st.lastStatus,
+			"lastCheck":  st.lastCheck,
+			"downSince":  downSince,
+			"alertSent":  st.alertSent,
+			"slowCount":  st.slowCount,
+			"history":    history,
+		})
+		if st.lastStatus == StatusDown || (st.lastStatus != StatusUp && overall == StatusUp) {
+			overall = st.lastStatus
+		}
+	}
+
 	return map[string]interface{}{
-		"lastStatus": s.lastStatus,
-		"downSince":  s.downSince,
-		"alertSent":  s.alertSent,
-		"slowCount":  s.slowCount,
+		"status":  overall,
+		"targets": targets,
 	}
 }
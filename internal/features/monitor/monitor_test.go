@@ -0,0 +1,181 @@
+package monitor
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var testStart = time.Date(2026, 1, 5, 8, 0, 0, 0, time.UTC)
+
+func testTarget() Target {
+	return Target{Name: "web", Type: TargetHTTP, SlowAfter: 2, DownAfter: Duration(10 * time.Minute)}
+}
+
+// at returns a check minutes after testStart
+func at(minutes int, status HealthStatus) Check {
+	return Check{Time: testStart.Add(time.Duration(minutes) * time.Minute), Status: status}
+}
+
+func TestRecord(t *testing.T) {
+	tests := []struct {
+		name   string
+		checks []Check
+		want   [][]HealthStatus // alerts after each check
+	}{
+		{
+			"down after downAfter",
+			[]Check{at(0, StatusDown), at(5, StatusDown), at(10, StatusDown), at(15, StatusDown)},
+			[][]HealthStatus{nil, nil, {StatusDown}, nil},
+		},
+		{
+			"recovery of a reported outage",
+			[]Check{at(0, StatusDown), at(10, StatusDown), at(15, StatusUp), at(20, StatusUp)},
+			[][]HealthStatus{nil, {StatusDown}, {StatusRecovery}, nil},
+		},
+		{
+			"short outage is not reported",
+			[]Check{at(0, StatusDown), at(5, StatusDown), at(10, StatusUp), at(15, StatusDown), at(20, StatusDown)},
+			[][]HealthStatus{nil, nil, nil, nil, nil},
+		},
+		{
+			"slow count",
+			[]Check{at(0, StatusSlow), at(5, StatusSlow), at(10, StatusSlow), at(15, StatusUp), at(20, StatusSlow), at(25, StatusSlow)},
+			[][]HealthStatus{nil, {StatusSlow}, nil, nil, nil, {StatusSlow}},
+		},
+		{
+			"slow count reset by another status",
+			[]Check{at(0, StatusSlow), at(5, StatusDown), at(10, StatusSlow)},
+			[][]HealthStatus{nil, nil, nil},
+		},
+		{
+			"expiry",
+			[]Check{at(0, StatusExpiring), at(5, StatusExpiring), at(10, StatusSlow), at(15, StatusExpiring), at(20, StatusUp), at(25, StatusExpiring)},
+			[][]HealthStatus{{StatusExpiring}, nil, nil, nil, nil, {StatusExpiring}},
+		},
+		{
+			"recovery to slow",
+			[]Check{at(0, StatusDown), at(10, StatusDown), at(15, StatusSlow)},
+			[][]HealthStatus{nil, {StatusDown}, {StatusRecovery}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := &targetState{target: testTarget(), lastStatus: StatusUp}
+			for i, check := range tt.checks {
+				var got []HealthStatus
+				for _, a := range st.record(check) {
+					got = append(got, a.status)
+				}
+				if !reflect.DeepEqual(got, tt.want[i]) {
+					t.Errorf("check %d (%s): alerts = %v, want %v", i, check.Status, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRecordOutageStart(t *testing.T) {
+	st := &targetState{target: testTarget(), lastStatus: StatusUp}
+	st.record(at(0, StatusUp))
+	st.record(at(5, StatusDown))
+	alerts := st.record(at(15, StatusDown))
+	if len(alerts) != 1 || !alerts[0].since.Equal(testStart.Add(5*time.Minute)) {
+		t.Fatalf("alerts = %+v, want one down alert since minute 5", alerts)
+	}
+	alerts = st.record(at(20, StatusUp))
+	if len(alerts) != 1 || !alerts[0].since.Equal(testStart.Add(5*time.Minute)) {
+		t.Fatalf("alerts = %+v, want one recovery alert since minute 5", alerts)
+	}
+}
+
+func TestRecordHistory(t *testing.T) {
+	st := &targetState{target: testTarget(), lastStatus: StatusUp}
+	for i := 0; i < historySize+10; i++ {
+		st.record(at(i, StatusUp))
+	}
+	if len(st.history) != historySize {
+		t.Fatalf("history has %d checks, want %d", len(st.history), historySize)
+	}
+	if !st.history[0].Time.Equal(at(10, StatusUp).Time) {
+		t.Errorf("oldest check at %v, want %v", st.history[0].Time, at(10, StatusUp).Time)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	tests := []struct {
+		name          string
+		checks        []Check
+		wantDownSince time.Time
+		wantAlertSent bool
+		wantNext      []HealthStatus // alerts of a check after the restart
+		next          Check
+	}{
+		{
+			name:     "no history",
+			next:     at(0, StatusDown),
+			wantNext: nil,
+		},
+		{
+			// More down checks than the loaded history, so the start comes from DownSince
+			name:          "reported outage longer than the history",
+			checks:        repeat(0, historySize+20, StatusDown),
+			wantDownSince: testStart,
+			wantAlertSent: true,
+			next:          at(historySize+20, StatusUp),
+			wantNext:      []HealthStatus{StatusRecovery},
+		},
+		{
+			name:          "outage not yet reported",
+			checks:        append(repeat(0, 3, StatusUp), repeat(3, 2, StatusDown)...),
+			wantDownSince: testStart.Add(3 * time.Minute),
+			next:          at(13, StatusDown),
+			wantNext:      []HealthStatus{StatusDown},
+		},
+		{
+			name:     "ended outage",
+			checks:   append(repeat(0, 20, StatusDown), at(20, StatusUp)),
+			next:     at(21, StatusUp),
+			wantNext: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := newTestStore(t)
+			for _, check := range tt.checks {
+				if err := store.Insert(ctx, "web", check); err != nil {
+					t.Fatalf("Insert: %v", err)
+				}
+			}
+
+			st := &targetState{target: testTarget(), lastStatus: StatusUp}
+			s := &MonitorService{store: store, targets: []*targetState{st}}
+			s.restore(ctx)
+
+			if !st.downSince.Equal(tt.wantDownSince) {
+				t.Errorf("downSince = %v, want %v", st.downSince, tt.wantDownSince)
+			}
+			if st.alertSent != tt.wantAlertSent {
+				t.Errorf("alertSent = %v, want %v", st.alertSent, tt.wantAlertSent)
+			}
+			var got []HealthStatus
+			for _, a := range st.record(tt.next) {
+				got = append(got, a.status)
+			}
+			if !reflect.DeepEqual(got, tt.wantNext) {
+				t.Errorf("alerts after restart = %v, want %v", got, tt.wantNext)
+			}
+		})
+	}
+}
+
+// repeat returns n checks with status, one minute apart from minute start
+func repeat(start, n int, status HealthStatus) []Check {
+	checks := make([]Check, n)
+	for i := range checks {
+		checks[i] = at(start+i, status)
+	}
+	return checks
+}
@@ -0,0 +1,157 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Incident is an outage: a run of consecutive down checks of one target
+type Incident struct {
+	Target          string     `json:"target"`
+	StartedAt       time.Time  `json:"startedAt"`
+	EndedAt         *time.Time `json:"endedAt,omitempty"` // first check that was not down; nil while ongoing
+	DurationSeconds int64      `json:"durationSeconds"`   // within the report range
+	Checks          int        `json:"checks"`
+	Error           string     `json:"error,omitempty"` // last error seen
+}
+
+// TargetReport summarizes the checks of one target over the report range
+type TargetReport struct {
+	Name            string   `json:"name"`
+	Type            string   `json:"type"`
+	Checks          int      `json:"checks"`
+	DownChecks      int      `json:"downChecks"`
+	SlowChecks      int      `json:"slowChecks"`
+	UptimePercent   *float64 `json:"uptimePercent"` // share of checks that were not down; nil without checks
+	P50LatencyMs    *int     `json:"p50LatencyMs"`  // over checks that were not down
+	P95LatencyMs    *int     `json:"p95LatencyMs"`
+	Incidents       int      `json:"incidents"`
+	DowntimeSeconds int64    `json:"downtimeSeconds"`
+}
+
+// Report is the uptime/SLA report returned by GET /monitor/report
+type Report struct {
+	From          time.Time      `json:"from"`
+	To            time.Time      `json:"to"`
+	UptimePercent *float64       `json:"uptimePercent"` // over all targets
+	Targets       []TargetReport `json:"targets"`
+	Incidents     []Incident     `json:"incidents"`
+}
+
+// Report builds the uptime report of [from, to) from the stored checks
+func (s *MonitorService) Report(ctx context.Context, from, to time.Time) (*Report, error) {
+	if !from.Before(to) {
+		return nil, fmt.Errorf("from must be before to")
+	}
+
+	report := &Report{From: from, To: to, Targets: []TargetReport{}, Incidents: []Incident{}}
+	var checks, up int
+	for _, st := range s.targets {
+		tr, incidents, err := s.targetReport(ctx, &st.target, from, to)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build report for %s: %w", st.target.Name, err)
+		}
+		report.Targets = append(report.Targets, *tr)
+		report.Incidents = append(report.Incidents, incidents...)
+		checks += tr.Checks
+		up += tr.Checks - tr.DownChecks
+	}
+	report.UptimePercent = uptime(up, checks)
+
+	sort.SliceStable(report.Incidents, func(i, j int) bool {
+		return report.Incidents[i].StartedAt.Before(report.Incidents[j].StartedAt)
+	})
+	return report, nil
+}
+
+func (s *MonitorService) targetReport(ctx context.Context, t *Target, from, to time.Time) (*TargetReport, []Incident, error) {
+	checks, err := s.store.Range(ctx, t.Name, from, to)
+	if err != nil {
+		return nil, nil, err
+	}
+	tr := &TargetReport{Name: t.Name, Type: t.Type, Checks: len(checks)}
+
+	// An outage that started before the range is still an incident of the range
+	var current *Incident
+	downSince, down, err := s.store.DownSince(ctx, t.Name, from)
+	if err != nil {
+		return nil, nil, err
+	}
+	if down {
+		current = &Incident{Target: t.Name, StartedAt: downSince}
+	}
+
+	incidents := make([]Incident, 0)
+	closeIncident := func(end time.Time, ended bool) {
+		start := current.StartedAt
+		if start.Before(from) {
+			start = from
+		}
+		current.DurationSeconds = int64(end.Sub(start).Seconds())
+		if ended {
+			current.EndedAt = &end
+		}
+		incidents = append(incidents, *current)
+		tr.DowntimeSeconds += current.DurationSeconds
+		current = nil
+	}
+
+	latencies := make([]int, 0, len(checks))
+	for _, check := range checks {
+		switch check.Status {
+		case StatusDown:
+			tr.DownChecks++
+			if current == nil {
+				current = &Incident{Target: t.Name, StartedAt: check.Time}
+			}
+			current.Checks++
+			current.Error = check.Error
+			continue
+		case StatusSlow:
+			tr.SlowChecks++
+		}
+		latencies = append(latencies, check.LatencyMs)
+		if current != nil {
+			closeIncident(check.Time, true)
+		}
+	}
+	if current != nil {
+		end := to
+		if now := time.Now(); now.Before(end) {
+			end = now
+		}
+		closeIncident(end, false)
+	}
+
+	tr.Incidents = len(incidents)
+	tr.UptimePercent = uptime(tr.Checks-tr.DownChecks, tr.Checks)
+	sort.Ints(latencies)
+	tr.P50LatencyMs = percentile(latencies, 50)
+	tr.P95LatencyMs = percentile(latencies, 95)
+	return tr, incidents, nil
+}
+
+// uptime returns up/total as a percentage rounded to 3 decimals, or nil without checks
+func uptime(up, total int) *float64 {
+	if total == 0 {
+		return nil
+	}
+	v := math.Round(float64(up)/float64(total)*100000) / 1000
+	return &v
+}
+
+// percentile returns the nearest-rank p-th percentile of sorted values, or nil if empty
+func percentile(sorted []int, p int) *int {
+	if len(sorted) == 0 {
+		return nil
+	}
+	rank := int(math.Ceil(float64(p) / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	v := sorted[rank-1]
+	return &v
+}
@@ -0,0 +1,158 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	tests := []struct {
+		name   string
+		sorted []int
+		p      int
+		want   *int
+	}{
+		{"empty", nil, 50, nil},
+		{"single", []int{7}, 95, intPtr(7)},
+		{"median of odd", []int{100, 200, 900}, 50, intPtr(200)},
+		{"median of even", []int{1, 2, 3, 4}, 50, intPtr(2)},
+		{"p95 of three", []int{100, 200, 900}, 95, intPtr(900)},
+		{"p95 of twenty", seq(20), 95, intPtr(19)},
+		{"p0", []int{5, 6}, 0, intPtr(5)},
+		{"p100", []int{5, 6}, 100, intPtr(6)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := percentile(tt.sorted, tt.p)
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("percentile(%v, %d) = %v, want %v", tt.sorted, tt.p, deref(got), deref(tt.want))
+			}
+		})
+	}
+}
+
+func TestUptime(t *testing.T) {
+	tests := []struct {
+		up, total int
+		want      *float64
+	}{
+		{0, 0, nil},
+		{10, 10, floatPtr(100)},
+		{0, 4, floatPtr(0)},
+		{3, 4, floatPtr(75)},
+		{2, 3, floatPtr(66.667)},
+		{9999, 10000, floatPtr(99.99)},
+	}
+	for _, tt := range tests {
+		got := uptime(tt.up, tt.total)
+		if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+			t.Errorf("uptime(%d, %d) = %v, want %v", tt.up, tt.total, derefFloat(got), derefFloat(tt.want))
+		}
+	}
+}
+
+func TestReportIncidentAcrossStart(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	from := testStart
+	to := from.Add(30 * time.Minute)
+
+	checks := []Check{
+		// Before the report: an outage starting at -10m
+		{Time: from.Add(-20 * time.Minute), Status: StatusUp, LatencyMs: 50},
+		{Time: from.Add(-10 * time.Minute), Status: StatusDown, Error: "refused"},
+		{Time: from.Add(-5 * time.Minute), Status: StatusDown, Error: "refused"},
+		// In the report
+		{Time: from.Add(5 * time.Minute), Status: StatusDown, Error: "timeout"},
+		{Time: from.Add(10 * time.Minute), Status: StatusUp, LatencyMs: 200},
+		{Time: from.Add(15 * time.Minute), Status: StatusSlow, LatencyMs: 900},
+		{Time: from.Add(20 * time.Minute), Status: StatusUp, LatencyMs: 100},
+		{Time: from.Add(25 * time.Minute), Status: StatusDown, Error: "reset"},
+		// After the report
+		{Time: to, Status: StatusUp, LatencyMs: 10},
+	}
+	for _, check := range checks {
+		if err := store.Insert(ctx, "web", check); err != nil {
+			t.Fatalf("Insert: %v", err)
+		}
+	}
+
+	s := &MonitorService{store: store, targets: []*targetState{{target: testTarget()}}}
+	report, err := s.Report(ctx, from, to)
+	if err != nil {
+		t.Fatalf("Report: %v", err)
+	}
+
+	if len(report.Targets) != 1 {
+		t.Fatalf("got %d target reports, want 1", len(report.Targets))
+	}
+	tr := report.Targets[0]
+	if tr.Checks != 5 || tr.DownChecks != 2 || tr.SlowChecks != 1 {
+		t.Errorf("checks/down/slow = %d/%d/%d, want 5/2/1", tr.Checks, tr.DownChecks, tr.SlowChecks)
+	}
+	if tr.UptimePercent == nil || *tr.UptimePercent != 60 {
+		t.Errorf("uptime = %v, want 60", derefFloat(tr.UptimePercent))
+	}
+	if deref(tr.P50LatencyMs) != "200" || deref(tr.P95LatencyMs) != "900" {
+		t.Errorf("p50/p95 = %s/%s, want 200/900", deref(tr.P50LatencyMs), deref(tr.P95LatencyMs))
+	}
+	if tr.Incidents != 2 || tr.DowntimeSeconds != 600+300 {
+		t.Errorf("incidents/downtime = %d/%d, want 2/900", tr.Incidents, tr.DowntimeSeconds)
+	}
+
+	if len(report.Incidents) != 2 {
+		t.Fatalf("got %d incidents, want 2", len(report.Incidents))
+	}
+	first, last := report.Incidents[0], report.Incidents[1]
+
+	// Starts before the report, but only the part inside it counts
+	if !first.StartedAt.Equal(from.Add(-10*time.Minute)) || first.EndedAt == nil || !first.EndedAt.Equal(from.Add(10*time.Minute)) {
+		t.Errorf("first incident = %v to %v, want -10m to 10m", first.StartedAt, first.EndedAt)
+	}
+	if first.DurationSeconds != 600 || first.Checks != 1 || first.Error != "timeout" {
+		t.Errorf("first incident duration/checks/error = %d/%d/%q, want 600/1/timeout", first.DurationSeconds, first.Checks, first.Error)
+	}
+
+	// Still down at the end of the report
+	if !last.StartedAt.Equal(from.Add(25*time.Minute)) || last.EndedAt != nil || last.DurationSeconds != 300 {
+		t.Errorf("last incident = %v, ended %v, %ds, want 25m, ongoing, 300s", last.StartedAt, last.EndedAt, last.DurationSeconds)
+	}
+	if report.UptimePercent == nil || *report.UptimePercent != 60 {
+		t.Errorf("overall uptime = %v, want 60", derefFloat(report.UptimePercent))
+	}
+}
+
+func TestReportRange(t *testing.T) {
+	s := &MonitorService{store: newTestStore(t)}
+	if _, err := s.Report(context.Background(), testStart, testStart); err == nil {
+		t.Error("Report with from == to succeeded, want an error")
+	}
+}
+
+func seq(n int) []int {
+	values := make([]int, n)
+	for i := range values {
+		values[i] = i + 1
+	}
+	return values
+}
+
+func intPtr(v int) *int { return &v }
+
+func floatPtr(v float64) *float64 { return &v }
+
+func deref(v *int) string {
+	if v == nil {
+		return "nil"
+	}
+	return fmt.Sprint(*v)
+}
+
+func derefFloat(v *float64) string {
+	if v == nil {
+		return "nil"
+	}
+	return fmt.Sprint(*v)
+}
@@ -0,0 +1,130 @@
+package monitor
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Store persists every check in SQLite for uptime reports
+type Store struct {
+	db *sql.DB
+}
+
+const schema = `
+CREATE TABLE IF NOT EXISTS monitor_checks (
+	seq             INTEGER PRIMARY KEY AUTOINCREMENT,
+	target          TEXT NOT NULL,
+	status          TEXT NOT NULL,
+	latency_ms      INTEGER NOT NULL,
+	error           TEXT NOT NULL DEFAULT '',
+	cert_expires_at INTEGER NOT NULL DEFAULT 0,
+	checked_at      INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_monitor_checks_target ON monitor_checks(target, checked_at);
+CREATE INDEX IF NOT EXISTS idx_monitor_checks_time ON monitor_checks(checked_at);
+`
+
+const checkColumns = `status, latency_ms, error, cert_expires_at, checked_at`
+
+// NewStore creates the monitor tables if needed
+func NewStore(db *sql.DB) (*Store, error) {
+	if _, err := db.Exec(schema); err != nil {
+		return nil, fmt.Errorf("failed to create monitor schema: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Insert records a check of a target
+func (s *Store) Insert(ctx context.Context, target string, check Check) error {
+	var certExpiresAt int64
+	if check.CertExpiresAt != nil {
+		certExpiresAt = check.CertExpiresAt.UnixMilli()
+	}
+	_, err := s.db.ExecContext(ctx, `INSERT INTO monitor_checks (target, `+checkColumns+`) VALUES (?, ?, ?, ?, ?, ?)`,
+		target, string(check.Status), check.LatencyMs, check.Error, certExpiresAt, check.Time.UnixMilli())
+	return err
+}
+
+// Range returns the checks of a target in [from, to), oldest first
+func (s *Store) Range(ctx context.Context, target string, from, to time.Time) ([]Check, error) {
+	return s.query(ctx, `SELECT `+checkColumns+` FROM monitor_checks
+		WHERE target = ? AND checked_at >= ? AND checked_at < ? ORDER BY checked_at, seq`,
+		target, from.UnixMilli(), to.UnixMilli())
+}
+
+// DownSince reports whether the last check of a target before t was down and, if so, when
+// that run of down checks started
+func (s *Store) DownSince(ctx context.Context, target string, t time.Time) (time.Time, bool, error) {
+	var status string
+	err := s.db.QueryRowContext(ctx, `SELECT status FROM monitor_checks
+		WHERE target = ? AND checked_at < ? ORDER BY checked_at DESC, seq DESC LIMIT 1`,
+		target, t.UnixMilli()).Scan(&status)
+	if err == sql.ErrNoRows || (err == nil && status != string(StatusDown)) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	var since int64
+	err = s.db.QueryRowContext(ctx, `SELECT MIN(checked_at) FROM monitor_checks
+		WHERE target = ? AND checked_at < ? AND checked_at > COALESCE(
+			(SELECT MAX(checked_at) FROM monitor_checks WHERE target = ? AND checked_at < ? AND status != ?), -1)`,
+		target, t.UnixMilli(), target, t.UnixMilli(), string(StatusDown)).Scan(&since)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return time.UnixMilli(since), true, nil
+}
+
+// Recent returns the last n checks of a target, oldest first
+func (s *Store) Recent(ctx context.Context, target string, n int) ([]Check, error) {
+	checks, err := s.query(ctx, `SELECT `+checkColumns+` FROM monitor_checks
+		WHERE target = ? ORDER BY checked_at DESC, seq DESC LIMIT ?`, target, n)
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(checks)-1; i < j; i, j = i+1, j-1 {
+		checks[i], checks[j] = checks[j], checks[i]
+	}
+	return checks, nil
+}
+
+// Prune deletes checks older than before
+func (s *Store) Prune(ctx context.Context, before time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM monitor_checks WHERE checked_at < ?`, before.UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (s *Store) query(ctx context.Context, query string, args ...interface{}) ([]Check, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	checks := make([]Check, 0)
+	for rows.Next() {
+		var (
+			check                    Check
+			status                   string
+			certExpiresAt, checkedAt int64
+		)
+		if err := rows.Scan(&status, &check.LatencyMs, &check.Error, &certExpiresAt, &checkedAt); err != nil {
+			return nil, err
+		}
+		check.Status = HealthStatus(status)
+		check.Time = time.UnixMilli(checkedAt)
+		if certExpiresAt > 0 {
+			t := time.UnixMilli(certExpiresAt)
+			check.CertExpiresAt = &t
+		}
+		checks = append(checks, check)
+	}
+	return checks, rows.Err()
+}
@@ -0,0 +1,100 @@
+package monitor
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"wa-server-go/internal/utils"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	db, err := utils.OpenSQLite(filepath.Join(t.TempDir(), "app.db"))
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	store, err := NewStore(db)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	return store
+}
+
+func TestStoreDownSince(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	for _, check := range []Check{
+		at(0, StatusDown),
+		at(5, StatusUp),
+		at(10, StatusDown),
+		at(15, StatusDown),
+		at(20, StatusSlow),
+		at(25, StatusDown),
+	} {
+		if err := store.Insert(ctx, "web", check); err != nil {
+			t.Fatalf("Insert: %v", err)
+		}
+	}
+	if err := store.Insert(ctx, "other", at(1, StatusDown)); err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		target   string
+		before   int // minutes after testStart
+		wantDown bool
+		want     int // minutes after testStart
+	}{
+		{"no checks before", "web", 0, false, 0},
+		{"first check down", "web", 1, true, 0},
+		{"last check up", "web", 6, false, 0},
+		{"run of down checks", "web", 16, true, 10},
+		{"the check at before is excluded", "web", 15, true, 10},
+		{"last check slow", "web", 21, false, 0},
+		{"new run after slow", "web", 30, true, 25},
+		{"other target", "other", 30, true, 1},
+		{"unknown target", "missing", 30, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			since, down, err := store.DownSince(ctx, tt.target, testStart.Add(time.Duration(tt.before)*time.Minute))
+			if err != nil {
+				t.Fatalf("DownSince: %v", err)
+			}
+			if down != tt.wantDown {
+				t.Fatalf("down = %v, want %v", down, tt.wantDown)
+			}
+			if want := testStart.Add(time.Duration(tt.want) * time.Minute); down && !since.Equal(want) {
+				t.Errorf("since = %v, want %v", since, want)
+			}
+		})
+	}
+}
+
+func TestStoreRecent(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	for _, check := range repeat(0, 5, StatusUp) {
+		if err := store.Insert(ctx, "web", check); err != nil {
+			t.Fatalf("Insert: %v", err)
+		}
+	}
+
+	checks, err := store.Recent(ctx, "web", 3)
+	if err != nil {
+		t.Fatalf("Recent: %v", err)
+	}
+	if len(checks) != 3 {
+		t.Fatalf("got %d checks, want 3", len(checks))
+	}
+	for i, check := range checks {
+		if want := at(2+i, StatusUp).Time; !check.Time.Equal(want) {
+			t.Errorf("check %d at %v, want %v", i, check.Time, want)
+		}
+	}
+}
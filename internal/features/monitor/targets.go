@@ -0,0 +1,144 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Target types
+const (
+	TargetHTTP = "http"
+	TargetTCP  = "tcp"
+	TargetTLS  = "tls"
+)
+
+// Defaults, matching the original single-URL monitor
+const (
+	defaultTimeout       = 10 * time.Second
+	defaultSlowThreshold = 5 * time.Second
+	defaultSlowAfter     = 3
+	defaultDownAfter     = 5 * time.Minute
+	defaultCertWarnDays  = 14
+)
+
+// Duration is a time.Duration that reads "5s"/"2m" strings (or seconds) from JSON
+type Duration time.Duration
+
+// UnmarshalJSON implements json.Unmarshaler
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		v, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		*d = Duration(v)
+		return nil
+	}
+	var secs float64
+	if err := json.Unmarshal(b, &secs); err != nil {
+		return fmt.Errorf("duration must be a string like \"5s\" or a number of seconds")
+	}
+	*d = Duration(secs * float64(time.Second))
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Target is one thing to check, with its own thresholds and alert recipients
+type Target struct {
+	Name string `json:"name"`
+	Type string `json:"type"` // http (default), tcp or tls
+
+	// http: URL to GET, accepted status codes (default: any below 400) and an optional body substring
+	URL          string `json:"url,omitempty"`
+	ExpectStatus []int  `json:"expectStatus,omitempty"`
+	ExpectBody   string `json:"expectBody,omitempty"`
+
+	// tcp/tls: host:port to connect to. tls also checks the certificate expiry
+	// (https URLs are checked too when certWarnDays is set).
+	Address      string `json:"address,omitempty"`
+	CertWarnDays int    `json:"certWarnDays,omitempty"`
+
+	Timeout       Duration `json:"timeout,omitempty"`
+	SlowThreshold Duration `json:"slowThreshold,omitempty"` // latency above this counts as slow
+	SlowAfter     int      `json:"slowAfter,omitempty"`     // consecutive slow checks before alerting
+	DownAfter     Duration `json:"downAfter,omitempty"`     // downtime before alerting
+
+	Recipients []string `json:"recipients,omitempty"` // phone numbers; default: the monitor alert phone
+}
+
+// DefaultTargets is the original behaviour: WEB_URL/api/health with the built-in thresholds
+func DefaultTargets(webURL string) []Target {
+	return []Target{{
+		Name: "web",
+		Type: TargetHTTP,
+		URL:  strings.TrimRight(webURL, "/") + "/api/health",
+	}}
+}
+
+// LoadTargets reads a JSON array of targets from path
+func LoadTargets(path string) ([]Target, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var targets []Target
+	if err := json.Unmarshal(data, &targets); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return targets, nil
+}
+
+// normalize fills in defaults and validates a target
+func (t *Target) normalize(alertPhone string) error {
+	if t.Type == "" {
+		t.Type = TargetHTTP
+	}
+	switch t.Type {
+	case TargetHTTP:
+		if !strings.HasPrefix(t.URL, "http://") && !strings.HasPrefix(t.URL, "https://") {
+			return fmt.Errorf("target %q: url must be an http(s) URL", t.Name)
+		}
+		if t.Name == "" {
+			t.Name = t.URL
+		}
+	case TargetTCP, TargetTLS:
+		if !strings.Contains(t.Address, ":") {
+			return fmt.Errorf("target %q: address must be host:port", t.Name)
+		}
+		if t.Name == "" {
+			t.Name = t.Address
+		}
+		if t.Type == TargetTLS && t.CertWarnDays == 0 {
+			t.CertWarnDays = defaultCertWarnDays
+		}
+	default:
+		return fmt.Errorf("target %q: unknown type %q", t.Name, t.Type)
+	}
+
+	if t.Timeout <= 0 {
+		t.Timeout = Duration(defaultTimeout)
+	}
+	if t.SlowThreshold <= 0 {
+		t.SlowThreshold = Duration(defaultSlowThreshold)
+	}
+	if t.SlowAfter <= 0 {
+		t.SlowAfter = defaultSlowAfter
+	}
+	if t.DownAfter < 0 {
+		t.DownAfter = 0
+	} else if t.DownAfter == 0 {
+		t.DownAfter = Duration(defaultDownAfter)
+	}
+	if len(t.Recipients) == 0 && alertPhone != "" {
+		t.Recipients = []string{alertPhone}
+	}
+	return nil
+}
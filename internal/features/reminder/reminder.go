@@ -0,0 +1,566 @@
+package reminder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"wa-server-go/internal/outbox"
+	"wa-server-go/internal/templates"
+
+	"github.com/robfig/cron/v3"
+)
+
+var (
+	// ErrAlreadyRunning is returned when a run is started while another one is in progress
+	ErrAlreadyRunning = errors.New("a reminder run is already in progress")
+	// ErrQuietHours is returned when reminders would be sent during quiet hours
+	ErrQuietHours = errors.New("reminders are not sent during quiet hours")
+)
+
+// StageDueTomorrow is the reminder sent the day before the due date. Overdue stages are
+// named overdue_<days>.
+const StageDueTomorrow = "due_tomorrow"
+
+const (
+	fetchTimeout = time.Minute
+	runTimeout   = 5 * time.Minute
+)
+
+// Config holds the reminder settings
+type Config struct {
+	SourceURL   string // returns the unpaid invoices
+	SourceToken string // sent as a Bearer token to SourceURL
+	Schedule    string // cron spec in Asia/Jakarta time, "" for manual runs only
+	OverdueDays []int  // days after the due date of each (escalating) overdue reminder, ascending
+	QuietStart  int    // hour (WIB) from which nothing is sent
+	QuietEnd    int    // hour (WIB) until which nothing is sent
+	Locale      string // template locale for invoices without one
+}
+
+// Invoice is an unpaid invoice as returned by the source endpoint
+type Invoice struct {
+	Number          string      `json:"invoiceNumber"`
+	ClientName      string      `json:"clientName"`
+	Phone           string      `json:"phone"`
+	DueDate         string      `json:"dueDate"` // YYYY-MM-DD (a full timestamp is cut to its date)
+	Status          string      `json:"status"`  // unpaid, partial, paid, ...
+	RemainingAmount interface{} `json:"remainingAmount"`
+	Locale          string      `json:"locale,omitempty"`
+}
+
+// Skip is an invoice a run did not remind, with the reason
+type Skip struct {
+	InvoiceNumber string `json:"invoiceNumber"`
+	Reason        string `json:"reason"`
+}
+
+// Result summarizes a run. In a dry run, Queued lists the reminders that would be sent.
+type Result struct {
+	StartedAt time.Time   `json:"startedAt"`
+	DryRun    bool        `json:"dryRun"`
+	Invoices  int         `json:"invoices"`
+	Queued    []*Reminder `json:"queued"`
+	Skipped   []Skip      `json:"skipped"`
+	Cancelled int         `json:"cancelled"`
+}
+
+// ReminderService sends invoice reminders through the outbox on a schedule
+type ReminderService struct {
+	outbox     *outbox.Outbox
+	templates  *templates.Service
+	clientID   string
+	cfg        Config
+	store      *Store
+	location   *time.Location
+	cron       *cron.Cron
+	httpClient *http.Client
+
+	mu      sync.Mutex
+	running bool
+	wg      sync.WaitGroup
+}
+
+// NewReminderService creates a reminder service. Reminders are queued for the WhatsApp client clientID.
+func NewReminderService(ob *outbox.Outbox, tmpl *templates.Service, clientID string, cfg Config, store *Store) *ReminderService {
+	location, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		location = time.FixedZone("WIB", 7*60*60)
+	}
+	if cfg.Locale == "" {
+		cfg.Locale = templates.DefaultLocale
+	}
+	return &ReminderService{
+		outbox:     ob,
+		templates:  tmpl,
+		clientID:   clientID,
+		cfg:        cfg,
+		store:      store,
+		location:   location,
+		cron:       cron.New(cron.WithLocation(location)),
+		httpClient: &http.Client{Timeout: fetchTimeout},
+	}
+}
+
+// Start schedules the reminder runs, and the hold of queued reminders when quiet hours start
+func (s *ReminderService) Start() error {
+	if s.cfg.QuietStart != s.cfg.QuietEnd {
+		if _, err := s.cron.AddFunc(fmt.Sprintf("0 %d * * *", s.cfg.QuietStart), s.holdQueued); err != nil {
+			return fmt.Errorf("failed to schedule quiet hours: %w", err)
+		}
+	}
+	if s.cfg.Schedule == "" {
+		s.cron.Start()
+		log.Println("⚠️ [REMINDER] REMINDER_SCHEDULE is off: reminders only run on demand")
+		return nil
+	}
+
+	_, err := s.cron.AddFunc(s.cfg.Schedule, func() {
+		if s.quiet(time.Now()) {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), runTimeout)
+		defer cancel()
+
+		result, err := s.Run(ctx, false)
+		if err != nil {
+			log.Printf("❌ [REMINDER] Run failed: %v", err)
+			return
+		}
+		if len(result.Queued) > 0 || result.Cancelled > 0 {
+			log.Printf("✅ [REMINDER] %d reminder(s) queued, %d cancelled (%d unpaid invoice(s))",
+				len(result.Queued), result.Cancelled, result.Invoices)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to schedule reminders: %w", err)
+	}
+
+	s.cron.Start()
+	log.Printf("✅ [REMINDER] Scheduler started (%s WIB, quiet %02d:00-%02d:00)", s.cfg.Schedule, s.cfg.QuietStart, s.cfg.QuietEnd)
+	return nil
+}
+
+// Stop stops the schedule and waits (until ctx expires) for a running run
+func (s *ReminderService) Stop(ctx context.Context) error {
+	s.cron.Stop()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("gave up waiting for the running reminder run: %w", ctx.Err())
+	}
+}
+
+// List returns recent ledger entries, newest first, optionally for one invoice
+func (s *ReminderService) List(ctx context.Context, invoiceNumber string, limit int) ([]*Reminder, error) {
+	return s.store.List(ctx, invoiceNumber, limit)
+}
+
+// Run fetches the unpaid invoices and queues the reminders that are due. Queued reminders of
+// invoices that are no longer unpaid are cancelled. A dry run only reports what would be sent.
+func (s *ReminderService) Run(ctx context.Context, dryRun bool) (*Result, error) {
+	now := time.Now().In(s.location)
+	if !dryRun && s.quiet(now) {
+		return nil, ErrQuietHours
+	}
+
+	if !s.acquire() {
+		return nil, ErrAlreadyRunning
+	}
+	defer s.release()
+
+	invoices, err := s.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{StartedAt: now, DryRun: dryRun, Invoices: len(invoices), Queued: []*Reminder{}, Skipped: []Skip{}}
+	unpaid := make(map[string]bool, len(invoices))
+	for _, inv := range invoices {
+		if !inv.paid() {
+			unpaid[inv.Number] = true
+		}
+	}
+	if !dryRun {
+		if result.Cancelled, err = s.syncQueued(ctx, unpaid, false); err != nil {
+			return nil, err
+		}
+	}
+
+	today := date(now)
+	for _, inv := range invoices {
+		if inv.Number == "" {
+			continue
+		}
+		if inv.paid() {
+			result.Skipped = append(result.Skipped, Skip{inv.Number, "paid"})
+			continue
+		}
+		if inv.Phone == "" {
+			result.Skipped = append(result.Skipped, Skip{inv.Number, "no phone number"})
+			continue
+		}
+		due, err := parseDate(inv.DueDate, s.location)
+		if err != nil {
+			result.Skipped = append(result.Skipped, Skip{inv.Number, "invalid due date " + strconv.Quote(inv.DueDate)})
+			continue
+		}
+
+		days := int(today.Sub(due).Hours() / 24)
+		stage, template, statusKey := s.stage(days)
+		if stage == "" {
+			continue
+		}
+
+		r, err := s.remind(ctx, &inv, due, days, stage, template, statusKey, dryRun)
+		if err == ErrDuplicate {
+			continue
+		}
+		if err != nil {
+			log.Printf("⚠️ [REMINDER] %s (%s): %v", inv.Number, stage, err)
+			result.Skipped = append(result.Skipped, Skip{inv.Number, err.Error()})
+			continue
+		}
+		result.Queued = append(result.Queued, r)
+	}
+	return result, nil
+}
+
+// acquire takes the slot of the running run (or quiet hours hold); false if it is taken
+func (s *ReminderService) acquire() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return false
+	}
+	s.running = true
+	s.wg.Add(1)
+	return true
+}
+
+func (s *ReminderService) release() {
+	s.mu.Lock()
+	s.running = false
+	s.mu.Unlock()
+	s.wg.Done()
+}
+
+// holdQueued takes back the reminders still waiting in the outbox (retrying, or held for a
+// disconnected session) when quiet hours start. Their stages are released, so the first run
+// after the quiet hours queues them again if they are still due.
+func (s *ReminderService) holdQueued() {
+	ctx, cancel := context.WithTimeout(context.Background(), runTimeout)
+	defer cancel()
+
+	// A run that started just before the quiet hours is waited for, so its reminders are held too
+	for !s.acquire() {
+		select {
+		case <-ctx.Done():
+			log.Println("⚠️ [REMINDER] Queued reminders not held: a run is still in progress")
+			return
+		case <-time.After(time.Second):
+		}
+	}
+	defer s.release()
+
+	held, err := s.syncQueued(ctx, nil, true)
+	if err != nil {
+		log.Printf("❌ [REMINDER] Failed to hold queued reminders: %v", err)
+	}
+	if held > 0 {
+		log.Printf("🌙 [REMINDER] %d queued reminder(s) held for the quiet hours", held)
+	}
+}
+
+// stage returns the reminder due days after the due date (negative before it): its stage,
+// template and the status recorded on the chat. The stage is "" when nothing is due.
+// With a single overdue day, its stage is the first overdue reminder, not the final one.
+func (s *ReminderService) stage(days int) (string, string, string) {
+	if days == -1 {
+		return StageDueTomorrow, templates.TemplateInvoiceReminder, "unpaid"
+	}
+
+	// The highest overdue stage reached; earlier stages that were missed are not sent late
+	level := -1
+	for i, d := range s.cfg.OverdueDays {
+		if days >= d {
+			level = i
+		}
+	}
+	switch {
+	case level < 0:
+		return "", "", ""
+	case level == 0:
+		return overdueStage(s.cfg.OverdueDays[0]), templates.TemplateInvoiceOverdue, "overdue"
+	case level == len(s.cfg.OverdueDays)-1:
+		return overdueStage(s.cfg.OverdueDays[level]), templates.TemplateInvoiceOverdueFinal, "overdue"
+	default:
+		return overdueStage(s.cfg.OverdueDays[level]), templates.TemplateInvoiceOverdueFollowUp, "overdue"
+	}
+}
+
+func overdueStage(days int) string {
+	return fmt.Sprintf("overdue_%d", days)
+}
+
+// remind records one reminder stage of inv in the ledger and queues its message
+func (s *ReminderService) remind(ctx context.Context, inv *Invoice, due time.Time, days int, stage, template, statusKey string, dryRun bool) (*Reminder, error) {
+	locale := inv.Locale
+	if locale == "" {
+		locale = s.cfg.Locale
+	}
+	data := templates.InvoiceTemplateData{
+		ClientName:      inv.ClientName,
+		InvoiceNumber:   inv.Number,
+		DueDate:         formatDate(due, locale),
+		Status:          templates.InvoiceStatusLabel(statusKey),
+		StatusKey:       statusKey,
+		RemainingAmount: formatAmount(inv.RemainingAmount),
+	}
+	vars := data.Vars()
+	vars["daysOverdue"] = strconv.Itoa(days)
+
+	message, err := s.templates.Render(ctx, template, locale, vars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render %s: %w", template, err)
+	}
+
+	r := &Reminder{
+		InvoiceNumber: inv.Number,
+		DueDate:       due.Format("2006-01-02"),
+		Stage:         stage,
+		Phone:         inv.Phone,
+		ClientName:    inv.ClientName,
+	}
+	if dryRun {
+		sent, err := s.store.Has(ctx, r.InvoiceNumber, r.DueDate, r.Stage)
+		if err != nil {
+			return nil, err
+		}
+		if sent {
+			return nil, ErrDuplicate
+		}
+		return r, nil
+	}
+
+	// Claim the stage first: a crash after queueing can then never send it twice
+	if err := s.store.Claim(ctx, r); err != nil {
+		return nil, err
+	}
+	job, err := s.outbox.Enqueue(ctx, s.clientID, outbox.KindInvoice, outbox.InvoicePayload{
+		Number:        inv.Phone,
+		Message:       message,
+		ClientName:    inv.ClientName,
+		InvoiceNumber: inv.Number,
+		InvoiceStatus: statusKey,
+	})
+	if err != nil {
+		if relErr := s.store.Release(ctx, r.ID); relErr != nil {
+			log.Printf("⚠️ [REMINDER] Failed to release %s (%s): %v", r.InvoiceNumber, r.Stage, relErr)
+		}
+		return nil, fmt.Errorf("failed to queue reminder: %w", err)
+	}
+
+	r.JobID = job.ID
+	if err := s.store.Update(ctx, r); err != nil {
+		log.Printf("⚠️ [REMINDER] Failed to record job of %s (%s): %v", r.InvoiceNumber, r.Stage, err)
+	}
+	log.Printf("📨 [REMINDER] %s queued for %s (job %s)", r.Stage, r.InvoiceNumber, job.ID)
+	return r, nil
+}
+
+// syncQueued copies the outcome of sent jobs into the ledger and cancels the queued
+// reminders of invoices that are no longer unpaid. With hold, every queued reminder is
+// cancelled and its ledger entry released. It returns how many were cancelled.
+func (s *ReminderService) syncQueued(ctx context.Context, unpaid map[string]bool, hold bool) (int, error) {
+	queued, err := s.store.Queued(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	cancelled := 0
+	for _, r := range queued {
+		job, err := s.outbox.Get(ctx, r.JobID)
+		if err == outbox.ErrNotFound {
+			r.Status = StatusFailed
+			_ = s.store.Update(ctx, r)
+			continue
+		}
+		if err != nil {
+			return cancelled, err
+		}
+
+		switch job.Status {
+		case outbox.StatusSent:
+			r.Status = StatusSent
+		case outbox.StatusFailed:
+			r.Status = StatusFailed
+		case outbox.StatusCancelled:
+			r.Status = StatusCancelled
+		case outbox.StatusQueued:
+			if unpaid[r.InvoiceNumber] && !hold {
+				continue
+			}
+			if _, err := s.outbox.Cancel(ctx, job.ID); err != nil {
+				continue // picked up for sending in the meantime
+			}
+			if hold {
+				if err := s.store.Release(ctx, r.ID); err != nil {
+					return cancelled, err
+				}
+				cancelled++
+				continue
+			}
+			log.Printf("🛑 [REMINDER] %s of %s cancelled: invoice is no longer unpaid", r.Stage, r.InvoiceNumber)
+			r.Status = StatusCancelled
+			cancelled++
+		default:
+			continue
+		}
+		if err := s.store.Update(ctx, r); err != nil {
+			return cancelled, err
+		}
+	}
+	return cancelled, nil
+}
+
+// fetch loads the unpaid invoices. The source returns a JSON array or {"invoices": [...]}.
+func (s *ReminderService) fetch(ctx context.Context) ([]Invoice, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.cfg.SourceURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch invoices: %w", err)
+	}
+	if s.cfg.SourceToken != "" {
+		req.Header.Set("Authorization", "Bearer "+s.cfg.SourceToken)
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch invoices: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch invoices: %s returned %d", s.cfg.SourceURL, resp.StatusCode)
+	}
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to parse invoices: %w", err)
+	}
+	raw = bytes.TrimSpace(raw)
+	if len(raw) > 0 && raw[0] == '{' {
+		var wrapped struct {
+			Invoices json.RawMessage `json:"invoices"`
+		}
+		if err := json.Unmarshal(raw, &wrapped); err != nil {
+			return nil, fmt.Errorf("failed to parse invoices: %w", err)
+		}
+		raw = wrapped.Invoices
+	}
+
+	var invoices []Invoice
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&invoices); err != nil {
+		return nil, fmt.Errorf("failed to parse invoices: %w", err)
+	}
+	return invoices, nil
+}
+
+// quiet reports whether t (in WIB) falls in the quiet hours
+func (s *ReminderService) quiet(t time.Time) bool {
+	start, end := s.cfg.QuietStart, s.cfg.QuietEnd
+	h := t.In(s.location).Hour()
+	switch {
+	case start == end:
+		return false
+	case start < end:
+		return h >= start && h < end
+	default: // wraps around midnight, e.g. 21-8
+		return h >= start || h < end
+	}
+}
+
+// paid reports whether an invoice needs no reminder: its status says so or nothing remains
+// to be paid. A remaining amount sent as a numeric string ("0", "0.00") counts like a number.
+func (inv *Invoice) paid() bool {
+	if strings.EqualFold(inv.Status, "paid") || strings.EqualFold(inv.Status, "lunas") {
+		return true
+	}
+	var remaining string
+	switch a := inv.RemainingAmount.(type) {
+	case json.Number:
+		remaining = a.String()
+	case string:
+		remaining = strings.TrimSpace(a)
+	default:
+		return false
+	}
+	f, err := strconv.ParseFloat(remaining, 64)
+	return err == nil && f <= 0
+}
+
+func date(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func parseDate(s string, location *time.Location) (time.Time, error) {
+	if len(s) > 10 {
+		s = s[:10]
+	}
+	return time.ParseInLocation("2006-01-02", s, location)
+}
+
+var indonesianMonths = [...]string{"Januari", "Februari", "Maret", "April", "Mei", "Juni",
+	"Juli", "Agustus", "September", "Oktober", "November", "Desember"}
+
+// formatDate formats a due date as "30 Oktober 2026" (or "30 October 2026" in English)
+func formatDate(t time.Time, locale string) string {
+	if locale == templates.LocaleEN {
+		return t.Format("02 January 2006")
+	}
+	return fmt.Sprintf("%02d %s %d", t.Day(), indonesianMonths[t.Month()-1], t.Year())
+}
+
+// formatAmount formats a number as "Rp 1.500.000"; strings are used as given
+func formatAmount(v interface{}) string {
+	var f float64
+	switch a := v.(type) {
+	case json.Number:
+		n, err := a.Float64()
+		if err != nil {
+			return a.String()
+		}
+		f = n
+	case string:
+		return a
+	default:
+		return ""
+	}
+
+	digits := strconv.FormatInt(int64(f+0.5), 10)
+	var sb strings.Builder
+	sb.WriteString("Rp ")
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			sb.WriteByte('.')
+		}
+		sb.WriteRune(d)
+	}
+	return sb.String()
+}
@@ -0,0 +1,161 @@
+package reminder
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"wa-server-go/internal/templates"
+)
+
+func newTestService(cfg Config) *ReminderService {
+	return NewReminderService(nil, nil, "bot", cfg, nil)
+}
+
+func TestStage(t *testing.T) {
+	tests := []struct {
+		name         string
+		overdueDays  []int
+		days         int
+		wantStage    string
+		wantTemplate string
+		wantStatus   string
+	}{
+		{"two days before", []int{1, 7, 14}, -2, "", "", ""},
+		{"day before", []int{1, 7, 14}, -1, StageDueTomorrow, templates.TemplateInvoiceReminder, "unpaid"},
+		{"due today", []int{1, 7, 14}, 0, "", "", ""},
+		{"first overdue", []int{1, 7, 14}, 1, "overdue_1", templates.TemplateInvoiceOverdue, "overdue"},
+		{"between stages", []int{1, 7, 14}, 3, "overdue_1", templates.TemplateInvoiceOverdue, "overdue"},
+		{"follow-up", []int{1, 7, 14}, 7, "overdue_7", templates.TemplateInvoiceOverdueFollowUp, "overdue"},
+		{"missed stage is not sent late", []int{1, 7, 14}, 10, "overdue_7", templates.TemplateInvoiceOverdueFollowUp, "overdue"},
+		{"final", []int{1, 7, 14}, 14, "overdue_14", templates.TemplateInvoiceOverdueFinal, "overdue"},
+		{"long after final", []int{1, 7, 14}, 90, "overdue_14", templates.TemplateInvoiceOverdueFinal, "overdue"},
+		{"single stage not reached", []int{3}, 2, "", "", ""},
+		{"single stage", []int{3}, 3, "overdue_3", templates.TemplateInvoiceOverdue, "overdue"},
+		{"single stage later", []int{3}, 30, "overdue_3", templates.TemplateInvoiceOverdue, "overdue"},
+		{"no overdue stages", nil, 5, "", "", ""},
+		{"no overdue stages, day before", nil, -1, StageDueTomorrow, templates.TemplateInvoiceReminder, "unpaid"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(Config{OverdueDays: tt.overdueDays})
+			stage, template, status := s.stage(tt.days)
+			if stage != tt.wantStage || template != tt.wantTemplate || status != tt.wantStatus {
+				t.Errorf("stage(%d) = (%q, %q, %q), want (%q, %q, %q)",
+					tt.days, stage, template, status, tt.wantStage, tt.wantTemplate, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestQuiet(t *testing.T) {
+	wib := time.FixedZone("WIB", 7*60*60)
+	at := func(hour, min int) time.Time { return time.Date(2026, 1, 5, hour, min, 0, 0, wib) }
+
+	tests := []struct {
+		name       string
+		start, end int
+		t          time.Time
+		want       bool
+	}{
+		{"wrapping, evening before", 21, 8, at(20, 59), false},
+		{"wrapping, start", 21, 8, at(21, 0), true},
+		{"wrapping, before midnight", 21, 8, at(23, 59), true},
+		{"wrapping, midnight", 21, 8, at(0, 0), true},
+		{"wrapping, before end", 21, 8, at(7, 59), true},
+		{"wrapping, end", 21, 8, at(8, 0), false},
+		{"wrapping, utc input", 21, 8, time.Date(2026, 1, 5, 14, 30, 0, 0, time.UTC), true},
+		{"same day, inside", 12, 13, at(12, 30), true},
+		{"same day, outside", 12, 13, at(13, 0), false},
+		{"disabled", 0, 0, at(3, 0), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(Config{QuietStart: tt.start, QuietEnd: tt.end})
+			if got := s.quiet(tt.t); got != tt.want {
+				t.Errorf("quiet(%s) with %d-%d = %v, want %v", tt.t.Format("15:04 MST"), tt.start, tt.end, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaid(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    string
+		remaining interface{}
+		want      bool
+	}{
+		{"status paid", "PAID", json.Number("150000"), true},
+		{"status lunas", "Lunas", nil, true},
+		{"unpaid", "unpaid", json.Number("150000"), false},
+		{"nothing remaining", "partial", json.Number("0"), true},
+		{"negative remaining", "partial", json.Number("-500"), true},
+		{"remaining as string", "partial", "150000", false},
+		{"zero as string", "partial", "0", true},
+		{"zero with decimals as string", "partial", "0.00", true},
+		{"zero with spaces as string", "partial", " 0 ", true},
+		{"text amount", "unpaid", "Rp 0", false},
+		{"no amount", "unpaid", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := &Invoice{Status: tt.status, RemainingAmount: tt.remaining}
+			if got := inv.paid(); got != tt.want {
+				t.Errorf("paid() with status %q and remaining %#v = %v, want %v", tt.status, tt.remaining, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	tests := []struct {
+		in   interface{}
+		want string
+	}{
+		{json.Number("0"), "Rp 0"},
+		{json.Number("999"), "Rp 999"},
+		{json.Number("1000"), "Rp 1.000"},
+		{json.Number("1500000"), "Rp 1.500.000"},
+		{json.Number("1500000.6"), "Rp 1.500.001"},
+		{json.Number("123456789"), "Rp 123.456.789"},
+		{json.Number("abc"), "abc"},
+		{"Rp 2 juta", "Rp 2 juta"},
+		{nil, ""},
+		{true, ""},
+	}
+	for _, tt := range tests {
+		if got := formatAmount(tt.in); got != tt.want {
+			t.Errorf("formatAmount(%#v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	wib := time.FixedZone("WIB", 7*60*60)
+
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{"2026-01-31", time.Date(2026, 1, 31, 0, 0, 0, 0, wib), false},
+		{"2026-01-31T17:00:00Z", time.Date(2026, 1, 31, 0, 0, 0, 0, wib), false},
+		{"2026-01-31 23:59:59", time.Date(2026, 1, 31, 0, 0, 0, 0, wib), false},
+		{"2026-02-30", time.Time{}, true},
+		{"31/01/2026", time.Time{}, true},
+		{"", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseDate(tt.in, wib)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseDate(%q) = %v, want an error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("parseDate(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package reminder
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Status represents the state of a ledger entry
+type Status string
+
+const (
+	StatusQueued    Status = "queued"    // handed to the outbox
+	StatusSent      Status = "sent"      // the outbox delivered it
+	StatusFailed    Status = "failed"    // the outbox gave up
+	StatusCancelled Status = "cancelled" // withdrawn before sending (invoice paid)
+)
+
+// ErrDuplicate is returned when a reminder was already recorded for the invoice, due date and stage
+var ErrDuplicate = errors.New("reminder already recorded")
+
+// Reminder is a ledger entry: one reminder stage of one invoice
+type Reminder struct {
+	ID            string    `json:"id"`
+	InvoiceNumber string    `json:"invoiceNumber"`
+	DueDate       string    `json:"dueDate"`
+	Stage         string    `json:"stage"`
+	Phone         string    `json:"phone"`
+	ClientName    string    `json:"clientName,omitempty"`
+	Status        Status    `json:"status"`
+	JobID         string    `json:"jobId,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// Store persists the reminder ledger in SQLite. An invoice gets each stage at most once
+// per due date, even across restarts.
+type Store struct {
+	db *sql.DB
+}
+
+const schema = `
+CREATE TABLE IF NOT EXISTS invoice_reminders (
+	seq            INTEGER PRIMARY KEY AUTOINCREMENT,
+	id             TEXT NOT NULL UNIQUE,
+	invoice_number TEXT NOT NULL,
+	due_date       TEXT NOT NULL,
+	stage          TEXT NOT NULL,
+	phone          TEXT NOT NULL,
+	client_name    TEXT NOT NULL DEFAULT '',
+	status         TEXT NOT NULL,
+	job_id         TEXT NOT NULL DEFAULT '',
+	created_at     INTEGER NOT NULL,
+	updated_at     INTEGER NOT NULL,
+	UNIQUE (invoice_number, due_date, stage)
+);
+CREATE INDEX IF NOT EXISTS idx_invoice_reminders_status ON invoice_reminders(status);
+`
+
+const reminderColumns = `id, invoice_number, due_date, stage, phone, client_name, status, job_id, created_at, updated_at`
+
+// NewStore creates the reminder tables if needed
+func NewStore(db *sql.DB) (*Store, error) {
+	if _, err := db.Exec(schema); err != nil {
+		return nil, fmt.Errorf("failed to create reminder schema: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Claim records r before it is sent. It returns ErrDuplicate if the stage was already recorded.
+func (s *Store) Claim(ctx context.Context, r *Reminder) error {
+	now := time.Now()
+	r.ID = newID()
+	r.Status = StatusQueued
+	r.CreatedAt = now
+	r.UpdatedAt = now
+	res, err := s.db.ExecContext(ctx, `INSERT INTO invoice_reminders (`+reminderColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (invoice_number, due_date, stage) DO NOTHING`,
+		r.ID, r.InvoiceNumber, r.DueDate, r.Stage, r.Phone, r.ClientName, string(r.Status), r.JobID, now.UnixMilli(), now.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("failed to insert reminder: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrDuplicate
+	}
+	return nil
+}
+
+// Release removes a claim whose message could not be queued, so a later run retries it
+func (s *Store) Release(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM invoice_reminders WHERE id = ?`, id)
+	return err
+}
+
+// Has reports whether a stage was already recorded for the invoice and due date
+func (s *Store) Has(ctx context.Context, invoiceNumber, dueDate, stage string) (bool, error) {
+	var n int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM invoice_reminders WHERE invoice_number = ? AND due_date = ? AND stage = ?`,
+		invoiceNumber, dueDate, stage).Scan(&n)
+	return n > 0, err
+}
+
+// Update saves the status and job ID of a reminder
+func (s *Store) Update(ctx context.Context, r *Reminder) error {
+	r.UpdatedAt = time.Now()
+	_, err := s.db.ExecContext(ctx, `UPDATE invoice_reminders SET status = ?, job_id = ?, updated_at = ? WHERE id = ?`,
+		string(r.Status), r.JobID, r.UpdatedAt.UnixMilli(), r.ID)
+	return err
+}
+
+// Queued returns the reminders still waiting in the outbox, oldest first
+func (s *Store) Queued(ctx context.Context) ([]*Reminder, error) {
+	return s.query(ctx, `SELECT `+reminderColumns+` FROM invoice_reminders WHERE status = ? ORDER BY seq`, string(StatusQueued))
+}
+
+// List returns recent reminders, newest first, optionally for one invoice
+func (s *Store) List(ctx context.Context, invoiceNumber string, limit int) ([]*Reminder, error) {
+	if invoiceNumber != "" {
+		return s.query(ctx, `SELECT `+reminderColumns+` FROM invoice_reminders WHERE invoice_number = ? ORDER BY seq DESC LIMIT ?`, invoiceNumber, limit)
+	}
+	return s.query(ctx, `SELECT `+reminderColumns+` FROM invoice_reminders ORDER BY seq DESC LIMIT ?`, limit)
+}
+
+func (s *Store) query(ctx context.Context, query string, args ...interface{}) ([]*Reminder, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reminders := []*Reminder{}
+	for rows.Next() {
+		var r Reminder
+		var status string
+		var createdAt, updatedAt int64
+		if err := rows.Scan(&r.ID, &r.InvoiceNumber, &r.DueDate, &r.Stage, &r.Phone, &r.ClientName, &status, &r.JobID, &createdAt, &updatedAt); err != nil {
+			return nil, err
+		}
+		r.Status = Status(status)
+		r.CreatedAt = time.UnixMilli(createdAt)
+		r.UpdatedAt = time.UnixMilli(updatedAt)
+		reminders = append(reminders, &r)
+	}
+	return reminders, rows.Err()
+}
+
+func newID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,64 @@
+package reminder
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"wa-server-go/internal/utils"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	db, err := utils.OpenSQLite(filepath.Join(t.TempDir(), "app.db"))
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	store, err := NewStore(db)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	return store
+}
+
+func TestStoreClaim(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	reminder := func(stage string) *Reminder {
+		return &Reminder{InvoiceNumber: "INV-1", DueDate: "2026-01-31", Stage: stage, Phone: "628123"}
+	}
+
+	first := reminder("overdue_1")
+	if err := store.Claim(ctx, first); err != nil {
+		t.Fatalf("Claim: %v", err)
+	}
+	if first.ID == "" || first.Status != StatusQueued {
+		t.Errorf("claimed reminder = %+v, want an ID and status queued", first)
+	}
+
+	if err := store.Claim(ctx, reminder("overdue_1")); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("second Claim of the same stage = %v, want ErrDuplicate", err)
+	}
+	if err := store.Claim(ctx, reminder("overdue_7")); err != nil {
+		t.Errorf("Claim of the next stage = %v, want nil", err)
+	}
+	other := reminder("overdue_1")
+	other.DueDate = "2026-02-28"
+	if err := store.Claim(ctx, other); err != nil {
+		t.Errorf("Claim for a new due date = %v, want nil", err)
+	}
+
+	// A released claim can be made again
+	if err := store.Release(ctx, first.ID); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if has, err := store.Has(ctx, "INV-1", "2026-01-31", "overdue_1"); err != nil || has {
+		t.Errorf("Has after Release = %v, %v, want false", has, err)
+	}
+	if err := store.Claim(ctx, reminder("overdue_1")); err != nil {
+		t.Errorf("Claim after Release = %v, want nil", err)
+	}
+}
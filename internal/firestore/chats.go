@@ -2,9 +2,11 @@ package firestore
 
 import (
 	"context"
-	"strings"
+	"errors"
 	"time"
 
+	"wa-server-go/internal/rules"
+
 	"cloud.google.com/go/firestore"
 	"google.golang.org/api/iterator"
 )
@@ -21,7 +23,11 @@ type WAChat struct {
 	LastMessageAt   time.Time `firestore:"lastMessageAt,omitempty"`
 	ProfilePicURL   string    `firestore:"profilePicUrl,omitempty"`
 	HasInvoice      bool      `firestore:"hasInvoice,omitempty"`
+	InvoiceNumber   string    `firestore:"invoiceNumber,omitempty"` // last invoice sent to the chat
+	InvoiceStatus   string    `firestore:"invoiceStatus,omitempty"` // its status key (unpaid, paid, overdue, ...)
 	IsOTP           bool      `firestore:"isOTP,omitempty"`
+	Tags            []string  `firestore:"tags,omitempty"`
+	Muted           bool      `firestore:"muted,omitempty"` // set by rules; new messages don't count as unread
 	UpdatedAt       time.Time `firestore:"updatedAt"`
 }
 
@@ -38,36 +44,104 @@ type WAMessage struct {
 	HasMedia  bool      `firestore:"hasMedia"`
 	MediaType string    `firestore:"mediaType,omitempty"`
 	MediaURL  string    `firestore:"mediaUrl,omitempty"`
-	Type      string    `firestore:"type"` // text, image, document, audio, video
+	MediaPath string    `firestore:"mediaPath,omitempty"` // key in the media store once the file is stored
+	Type      string    `firestore:"type"` // text, image, document, audio, video, sticker
 	Ack       int       `firestore:"ack"`
 	CreatedAt time.Time `firestore:"createdAt"`
+
+	// Download details so media can be fetched from WhatsApp on first access
+	MediaFileName      string `firestore:"mediaFileName,omitempty"`
+	MediaDirectPath    string `firestore:"mediaDirectPath,omitempty"`
+	MediaKey           []byte `firestore:"mediaKey,omitempty"`
+	MediaFileSHA256    []byte `firestore:"mediaFileSha256,omitempty"`
+	MediaFileEncSHA256 []byte `firestore:"mediaFileEncSha256,omitempty"`
+	MediaFileLength    int64  `firestore:"mediaFileLength,omitempty"`
 }
 
+// Message ack levels stored in WAMessage.Ack (same scale as whatsapp-web.js)
+const (
+	AckServer = 1 // Sent to the WhatsApp server (✓)
+	AckDevice = 2 // Delivered to the recipient's device (✓✓)
+	AckRead   = 3 // Read by the recipient (blue ✓✓)
+	AckPlayed = 4 // Voice note / view-once media played
+)
+
 // ChatsRepository provides access to the wa_chats and wa_messages collections
 type ChatsRepository struct {
 	client             *Client
 	chatsCollection    string
 	messagesCollection string
+	rules              *rules.Engine // classifies chats from their messages (nil: no rules)
+	session            string        // "" for the default session
+	onUpdate           func(ChatUpdate)
+	indexer            MessageIndexer
+}
+
+// MessageIndexer is given every saved message, e.g. to keep a search index up to date
+type MessageIndexer interface {
+	IndexMessage(session string, msg *WAMessage)
+}
+
+// ChatUpdate describes fields of a chat that changed outside the message flow
+// (tags added by rules or by hand, read state), so they can be pushed to clients
+type ChatUpdate struct {
+	Session string // "" for the default session
+	JID     string
+	Fields  map[string]interface{}
 }
 
+// ErrChatNotFound is returned when a chat does not exist
+var ErrChatNotFound = errors.New("chat not found")
+
 // NewChatsRepository creates a new chats repository
-func NewChatsRepository(client *Client) *ChatsRepository {
+func NewChatsRepository(client *Client, ruleEngine *rules.Engine) *ChatsRepository {
 	return &ChatsRepository{
 		client:             client,
 		chatsCollection:    "wa_chats_v3",
 		messagesCollection: "wa_messages_v3",
+		rules:              ruleEngine,
 	}
 }
 
-// GetRecentChats retrieves recent chats ordered by last message time
-func (r *ChatsRepository) GetRecentChats(ctx context.Context, limit int) ([]WAChat, error) {
-	query := r.client.Collection(r.chatsCollection).
-		Where("isOTP", "==", false).
-		OrderBy("lastMessageAt", firestore.Desc)
-	if limit > 0 {
-		query = query.Limit(limit)
+// ForSession returns a repository whose collections belong to another WhatsApp session
+func (r *ChatsRepository) ForSession(sessionID string) *ChatsRepository {
+	return &ChatsRepository{
+		client:             r.client,
+		chatsCollection:    r.chatsCollection + "_" + sessionID,
+		messagesCollection: r.messagesCollection + "_" + sessionID,
+		rules:              r.rules,
+		session:            sessionID,
+		onUpdate:           r.onUpdate,
+		indexer:            r.indexer,
 	}
+}
 
+// IndexMessages passes every saved message to indexer. Like OnChatUpdate, set it before
+// the repository is used.
+func (r *ChatsRepository) IndexMessages(indexer MessageIndexer) {
+	r.indexer = indexer
+}
+
+// OnChatUpdate registers fn to be called after a chat's tags or read state change. Set it before the
+// repository is used: ForSession copies it.
+func (r *ChatsRepository) OnChatUpdate(fn func(ChatUpdate)) {
+	r.onUpdate = fn
+}
+
+func (r *ChatsRepository) notify(jid string, fields map[string]interface{}) {
+	if r.onUpdate != nil {
+		r.onUpdate(ChatUpdate{Session: r.session, JID: jid, Fields: fields})
+	}
+}
+
+// GetRecentChats retrieves a page of recent chats ordered by last message time
+func (r *ChatsRepository) GetRecentChats(ctx context.Context, page Page) ([]WAChat, error) {
+	query := page.query(r.client.Collection(r.chatsCollection).Where("isOTP", "==", false), "lastMessageAt")
+	return r.queryChats(ctx, page, query)
+}
+
+// queryChats runs a chats query built by Page.query
+func (r *ChatsRepository) queryChats(ctx context.Context, page Page, query firestore.Query) ([]WAChat, error) {
 	iter := query.Documents(ctx)
 
 	var chats []WAChat
@@ -88,18 +162,17 @@ func (r *ChatsRepository) GetRecentChats(ctx context.Context, limit int) ([]WACh
 		chats = append(chats, chat)
 	}
 
-	return chats, nil
+	return finish(page, chats), nil
 }
 
-// GetChatMessages retrieves messages for a specific chat
-func (r *ChatsRepository) GetChatMessages(ctx context.Context, chatID string, limit int) ([]WAMessage, error) {
-	query := r.client.Collection(r.messagesCollection).
-		Where("chatId", "==", chatID).
-		OrderBy("timestamp", firestore.Desc)
-	if limit > 0 {
-		query = query.Limit(limit)
-	}
+// ChatCursor returns the position of a chat in lists ordered by last message time
+func ChatCursor(chat WAChat) Cursor {
+	return Cursor{Time: chat.LastMessageAt, ID: chat.ID}
+}
 
+// GetChatMessages retrieves a page of messages of a chat, newest first
+func (r *ChatsRepository) GetChatMessages(ctx context.Context, chatID string, page Page) ([]WAMessage, error) {
+	query := page.query(r.client.Collection(r.messagesCollection).Where("chatId", "==", chatID), "timestamp")
 	iter := query.Documents(ctx)
 
 	var messages []WAMessage
@@ -120,7 +193,64 @@ func (r *ChatsRepository) GetChatMessages(ctx context.Context, chatID string, li
 		messages = append(messages, msg)
 	}
 
-	return messages, nil
+	return finish(page, messages), nil
+}
+
+// EachMessage calls fn with every stored message, in no particular order, and stops at the
+// first error fn returns
+func (r *ChatsRepository) EachMessage(ctx context.Context, fn func(msg *WAMessage) error) error {
+	iter := r.client.Collection(r.messagesCollection).Documents(ctx)
+	defer iter.Stop()
+
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var msg WAMessage
+		if err := doc.DataTo(&msg); err != nil {
+			continue
+		}
+		msg.ID = doc.Ref.ID
+		if err := fn(&msg); err != nil {
+			return err
+		}
+	}
+}
+
+// MessageCursor returns the position of a message in its chat
+func MessageCursor(msg WAMessage) Cursor {
+	return Cursor{Time: msg.Timestamp, ID: msg.ID}
+}
+
+// GetMessage retrieves a single message by its WhatsApp message ID (nil if not found)
+func (r *ChatsRepository) GetMessage(ctx context.Context, messageID string) (*WAMessage, error) {
+	doc, err := r.client.Collection(r.messagesCollection).Doc(messageID).Get(ctx)
+	if doc != nil && !doc.Exists() {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var msg WAMessage
+	if err := doc.DataTo(&msg); err != nil {
+		return nil, err
+	}
+	msg.ID = doc.Ref.ID
+	return &msg, nil
+}
+
+// SetMessageMediaPath records where a message's attachment is kept in the media store
+func (r *ChatsRepository) SetMessageMediaPath(ctx context.Context, messageID, mediaPath string) error {
+	_, err := r.client.Collection(r.messagesCollection).Doc(messageID).Update(ctx, []firestore.Update{
+		{Path: "mediaPath", Value: mediaPath},
+	})
+	return err
 }
 
 // SaveMessage saves a new message and updates the chat
@@ -133,6 +263,9 @@ func (r *ChatsRepository) SaveMessage(ctx context.Context, msg *WAMessage) error
 	if err != nil {
 		return err
 	}
+	if r.indexer != nil {
+		r.indexer.IndexMessage(r.session, msg)
+	}
 
 	// Update or create chat
 	return r.updateChatFromMessage(ctx, msg)
@@ -149,6 +282,12 @@ func (r *ChatsRepository) updateChatFromMessage(ctx context.Context, msg *WAMess
 	doc, err := iter.Next()
 	now := time.Now()
 
+	// The sender a rule sees is the other side of the chat
+	sender := msg.From
+	if msg.FromMe {
+		sender = msg.To
+	}
+
 	if err == iterator.Done {
 		// Create new chat
 		newChat := WAChat{
@@ -159,25 +298,18 @@ func (r *ChatsRepository) updateChatFromMessage(ctx context.Context, msg *WAMess
 			LastMessageBody: truncateBody(msg.Body),
 			LastMessageAt:   msg.Timestamp,
 			UpdatedAt:       now,
-			HasInvoice:      strings.Contains(strings.ToUpper(msg.Body), "INV-") || strings.Contains(strings.ToLower(msg.Body), "invoice") || strings.Contains(strings.ToLower(msg.Body), "tagihan"),
-			IsOTP:           strings.Contains(strings.ToLower(msg.Body), "otp") || strings.Contains(strings.ToLower(msg.Body), "kode verifikasi") || strings.Contains(strings.ToLower(msg.Body), "verification code"),
 		}
 		if msg.FromMe {
 			newChat.Number = msg.To
-		} else {
-			newChat.UnreadCount = 1
-		}
-		
-		// Initial Filter Check for New Chat
-		// Check Body
-		bodyLower := strings.ToLower(msg.Body)
-		if strings.Contains(bodyLower, "otp") || strings.Contains(bodyLower, "kode verifikasi") || strings.Contains(bodyLower, "verification code") {
-			newChat.IsOTP = true
 		}
-		// Check Sender
-		sender := strings.ToLower(msg.From)
-		if strings.Contains(sender, "stockbit") || strings.Contains(sender, "628999800123") || strings.Contains(sender, "tri") {
-			newChat.IsOTP = true
+
+		result := r.rules.Evaluate(rules.Input{Body: msg.Body, Sender: sender})
+		newChat.HasInvoice = result.Flag(rules.FlagHasInvoice)
+		newChat.IsOTP = result.Flag(rules.FlagIsOTP)
+		newChat.Tags = result.Tags
+		newChat.Muted = result.Mute
+		if !msg.FromMe && !newChat.Muted {
+			newChat.UnreadCount = 1
 		}
 
 		_, _, err = r.client.Collection(r.chatsCollection).Add(ctx, newChat)
@@ -187,51 +319,126 @@ func (r *ChatsRepository) updateChatFromMessage(ctx context.Context, msg *WAMess
 		return err
 	}
 
+	var chat WAChat
+	_ = doc.DataTo(&chat)
+	result := r.rules.Evaluate(rules.Input{Body: msg.Body, Sender: sender, ChatName: chat.Name})
+
 	// Update existing chat
 	updates := []firestore.Update{
 		{Path: "lastMessageBody", Value: truncateBody(msg.Body)},
 		{Path: "lastMessageAt", Value: msg.Timestamp},
 		{Path: "updatedAt", Value: now},
 	}
-	if !msg.FromMe {
+	if !msg.FromMe && !chat.Muted && !result.Mute {
 		updates = append(updates, firestore.Update{Path: "unreadCount", Value: firestore.Increment(1)})
 	}
+	updates = append(updates, ruleUpdates(result)...)
 
-	// Check for invoice keywords to auto-mark as relevant
-	if strings.Contains(strings.ToUpper(msg.Body), "INV-") || strings.Contains(strings.ToLower(msg.Body), "invoice") || strings.Contains(strings.ToLower(msg.Body), "tagihan") {
-		updates = append(updates, firestore.Update{Path: "hasInvoice", Value: true})
+	if _, err := doc.Ref.Update(ctx, updates); err != nil {
+		return err
 	}
 
-	// Check for OTP keywords to auto-mark as OTP
-	bodyLower := strings.ToLower(msg.Body)
-	isOTP := strings.Contains(bodyLower, "otp") || strings.Contains(bodyLower, "kode verifikasi") || strings.Contains(bodyLower, "verification code")
-	
-	// Filter specific senders (Stockbit, TRI)
-	// We check the JID/Number or Name if available in the doc (but doc is 'doc', here we just have msg and updates).
-	// We can't easily check Name from 'updates' unless we read 'doc' data again or used 'doc.DataTo' earlier.
-	// However, usually these come from specific numbers or sender IDs.
-	// For now, let's filter by Number in the 'msg.From' (JID).
-	sender := strings.ToLower(msg.From) // 62899... or stockbit
-	if strings.Contains(sender, "stockbit") || strings.Contains(sender, "628999800123") || strings.Contains(sender, "tri") {
-		isOTP = true
+	// Let clients know when the rules tagged or muted the chat
+	fields := map[string]interface{}{}
+	if tags := mergeTags(chat.Tags, result.Tags, nil); len(tags) > len(chat.Tags) {
+		fields["tags"] = tags
+	}
+	if result.Mute && !chat.Muted {
+		fields["muted"] = true
 	}
+	if len(fields) > 0 {
+		r.notify(msg.ChatID, fields)
+	}
+	return nil
+}
 
-	if isOTP {
-		updates = append(updates, firestore.Update{Path: "isOTP", Value: true})
+// ruleUpdates turns a rules result into chat updates. Rules only ever set flags, add tags
+// and mute; clearing them is left to the user.
+func ruleUpdates(result rules.Result) []firestore.Update {
+	var updates []firestore.Update
+	for _, flag := range rules.Flags {
+		if result.Flag(flag) {
+			updates = append(updates, firestore.Update{Path: flag, Value: true})
+		}
+	}
+	if len(result.Tags) > 0 {
+		tags := make([]interface{}, len(result.Tags))
+		for i, tag := range result.Tags {
+			tags[i] = tag
+		}
+		updates = append(updates, firestore.Update{Path: "tags", Value: firestore.ArrayUnion(tags...)})
 	}
+	if result.Mute {
+		updates = append(updates, firestore.Update{Path: "muted", Value: true})
+	}
+	return updates
+}
 
-	_, err = doc.Ref.Update(ctx, updates)
-	return err
+// UpdateMessageAck raises the ack level of the given messages. Acks never go backwards,
+// since receipts can arrive out of order (e.g. "read" before "delivered").
+// It returns the IDs of the messages that were actually updated.
+func (r *ChatsRepository) UpdateMessageAck(ctx context.Context, messageIDs []string, ack int) ([]string, error) {
+	updated := make([]string, 0, len(messageIDs))
+	for _, id := range messageIDs {
+		ref := r.client.Collection(r.messagesCollection).Doc(id)
+		doc, err := ref.Get(ctx)
+		if doc != nil && !doc.Exists() {
+			continue // Not a message we stored (e.g. sent from the phone before history sync)
+		}
+		if err != nil {
+			return updated, err
+		}
+
+		var msg WAMessage
+		if err := doc.DataTo(&msg); err != nil {
+			continue
+		}
+		if msg.Ack >= ack {
+			continue
+		}
+
+		if _, err := ref.Update(ctx, []firestore.Update{
+			{Path: "ack", Value: ack},
+		}); err != nil {
+			return updated, err
+		}
+		updated = append(updated, id)
+	}
+	return updated, nil
 }
 
-// MarkChatAsRead marks a chat as read
-func (r *ChatsRepository) MarkChatAsRead(ctx context.Context, chatJID string) error {
+// GetChat retrieves a chat by its JID
+func (r *ChatsRepository) GetChat(ctx context.Context, jid string) (*WAChat, error) {
+	doc, err := r.findChat(ctx, jid)
+	if err != nil {
+		return nil, err
+	}
+
+	var chat WAChat
+	if err := doc.DataTo(&chat); err != nil {
+		return nil, err
+	}
+	chat.ID = doc.Ref.ID
+	return &chat, nil
+}
+
+// findChat returns the document of a chat, or ErrChatNotFound
+func (r *ChatsRepository) findChat(ctx context.Context, jid string) (*firestore.DocumentSnapshot, error) {
 	iter := r.client.Collection(r.chatsCollection).
-		Where("jid", "==", chatJID).
+		Where("jid", "==", jid).
 		Limit(1).
 		Documents(ctx)
 
 	doc, err := iter.Next()
+	if err == iterator.Done {
+		return nil, ErrChatNotFound
+	}
+	return doc, err
+}
+
+// MarkChatAsRead marks a chat as read
+func (r *ChatsRepository) MarkChatAsRead(ctx context.Context, chatJID string) error {
+	doc, err := r.findChat(ctx, chatJID)
 	if err != nil {
 		return err
 	}
@@ -240,39 +447,187 @@ func (r *ChatsRepository) MarkChatAsRead(ctx context.Context, chatJID string) er
 		{Path: "unreadCount", Value: 0},
 		{Path: "updatedAt", Value: time.Now()},
 	})
-	return err
+	if err != nil {
+		return err
+	}
+
+	r.notify(chatJID, map[string]interface{}{"unreadCount": 0})
+	return nil
 }
 
-// GetInvoiceChats retrieves chats that contain invoice messages
-func (r *ChatsRepository) GetInvoiceChats(ctx context.Context, limit int) ([]WAChat, error) {
-	query := r.client.Collection(r.chatsCollection).
-		Where("hasInvoice", "==", true).
-		OrderBy("lastMessageAt", firestore.Desc)
-	if limit > 0 {
-		query = query.Limit(limit)
+// SetChatRead applies a read or unread mark made on another device at time at.
+// A chat marked unread counts at least one unread message. Marks older than the
+// chat's last message are ignored, so messages that arrived since stay unread.
+func (r *ChatsRepository) SetChatRead(ctx context.Context, chatJID string, read bool, at time.Time) error {
+	doc, err := r.findChat(ctx, chatJID)
+	if err != nil {
+		return err
 	}
 
-	iter := query.Documents(ctx)
+	var chat WAChat
+	if err := doc.DataTo(&chat); err != nil {
+		return err
+	}
+	if chat.LastMessageAt.After(at) {
+		return nil
+	}
 
-	var chats []WAChat
+	count := 0
+	if !read {
+		if chat.UnreadCount > 0 {
+			return nil
+		}
+		count = 1
+	} else if chat.UnreadCount == 0 {
+		return nil
+	}
+
+	if _, err := doc.Ref.Update(ctx, []firestore.Update{
+		{Path: "unreadCount", Value: count},
+		{Path: "updatedAt", Value: time.Now()},
+	}); err != nil {
+		return err
+	}
+
+	r.notify(chatJID, map[string]interface{}{"unreadCount": count})
+	return nil
+}
+
+// GetChatsByTag retrieves a page of chats with a tag, ordered by last message time
+func (r *ChatsRepository) GetChatsByTag(ctx context.Context, tag string, page Page) ([]WAChat, error) {
+	query := page.query(r.client.Collection(r.chatsCollection).Where("tags", "array-contains", tag), "lastMessageAt")
+	return r.queryChats(ctx, page, query)
+}
+
+// GetInvoiceChats retrieves chats tagged as invoice chats
+func (r *ChatsRepository) GetInvoiceChats(ctx context.Context, limit int) ([]WAChat, error) {
+	return r.GetChatsByTag(ctx, rules.TagInvoice, Page{Limit: limit})
+}
+
+// BackfillInvoiceTags gives the invoice tag to chats flagged hasInvoice before tags existed,
+// so GetInvoiceChats finds them. It returns how many chats were tagged.
+func (r *ChatsRepository) BackfillInvoiceTags(ctx context.Context) (int, error) {
+	iter := r.client.Collection(r.chatsCollection).
+		Where("hasInvoice", "==", true).
+		Documents(ctx)
+
+	count := 0
+	batch := r.client.Batch()
+	operationCount := 0
 	for {
 		doc, err := iter.Next()
 		if err == iterator.Done {
 			break
 		}
 		if err != nil {
-			return nil, err
+			return count, err
 		}
 
 		var chat WAChat
-		if err := doc.DataTo(&chat); err != nil {
+		if err := doc.DataTo(&chat); err != nil || containsTag(chat.Tags, rules.TagInvoice) {
 			continue
 		}
-		chat.ID = doc.Ref.ID
-		chats = append(chats, chat)
+
+		batch.Update(doc.Ref, []firestore.Update{
+			{Path: "tags", Value: firestore.ArrayUnion(rules.TagInvoice)},
+		})
+		count++
+		operationCount++
+
+		if operationCount >= 500 {
+			if _, err := batch.Commit(ctx); err != nil {
+				return count, err
+			}
+			batch = r.client.Batch()
+			operationCount = 0
+		}
 	}
 
-	return chats, nil
+	if operationCount > 0 {
+		if _, err := batch.Commit(ctx); err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+// UpdateChatTags adds and removes tags of a chat and returns its tags afterwards.
+// The read and write run in a transaction so concurrent tag changes are not lost.
+func (r *ChatsRepository) UpdateChatTags(ctx context.Context, jid string, add, remove []string) ([]string, error) {
+	doc, err := r.findChat(ctx, jid)
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []string
+	err = r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		snap, err := tx.Get(doc.Ref)
+		if err != nil {
+			return err
+		}
+		var chat WAChat
+		if err := snap.DataTo(&chat); err != nil {
+			return err
+		}
+
+		tags = mergeTags(chat.Tags, add, remove)
+		return tx.Update(doc.Ref, []firestore.Update{
+			{Path: "tags", Value: tags},
+			{Path: "updatedAt", Value: time.Now()},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r.notify(jid, map[string]interface{}{"tags": tags})
+	return tags, nil
+}
+
+// SetChatMuted mutes or unmutes a chat. Messages of a muted chat don't count as unread.
+func (r *ChatsRepository) SetChatMuted(ctx context.Context, jid string, muted bool) error {
+	doc, err := r.findChat(ctx, jid)
+	if err != nil {
+		return err
+	}
+
+	if _, err := doc.Ref.Update(ctx, []firestore.Update{
+		{Path: "muted", Value: muted},
+		{Path: "updatedAt", Value: time.Now()},
+	}); err != nil {
+		return err
+	}
+
+	r.notify(jid, map[string]interface{}{"muted": muted})
+	return nil
+}
+
+// mergeTags returns tags with add appended and remove taken out, without duplicates
+func mergeTags(tags, add, remove []string) []string {
+	merged := make([]string, 0, len(tags)+len(add))
+	seen := make(map[string]bool, len(tags)+len(add))
+	for _, tag := range remove {
+		seen[tag] = true
+	}
+	for _, list := range [][]string{tags, add} {
+		for _, tag := range list {
+			if !seen[tag] {
+				seen[tag] = true
+				merged = append(merged, tag)
+			}
+		}
+	}
+	return merged
+}
+
+// containsTag reports whether tags holds tag
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
 }
 
 // SetChatHasInvoice marks a chat as relevant to invoices
@@ -294,8 +649,38 @@ func (r *ChatsRepository) SetChatHasInvoice(ctx context.Context, jid string, has
 		return err
 	}
 
+	var tag interface{} = firestore.ArrayUnion(rules.TagInvoice)
+	if !hasInvoice {
+		tag = firestore.ArrayRemove(rules.TagInvoice)
+	}
 	_, err = doc.Ref.Update(ctx, []firestore.Update{
 		{Path: "hasInvoice", Value: hasInvoice},
+		{Path: "tags", Value: tag},
+	})
+	return err
+}
+
+// SetChatInvoice marks a chat as an invoice chat and records the last invoice sent to it
+func (r *ChatsRepository) SetChatInvoice(ctx context.Context, jid, number, status string) error {
+	iter := r.client.Collection(r.chatsCollection).
+		Where("jid", "==", jid).
+		Limit(1).
+		Documents(ctx)
+
+	doc, err := iter.Next()
+	if err == iterator.Done {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = doc.Ref.Update(ctx, []firestore.Update{
+		{Path: "hasInvoice", Value: true},
+		{Path: "tags", Value: firestore.ArrayUnion(rules.TagInvoice)},
+		{Path: "invoiceNumber", Value: number},
+		{Path: "invoiceStatus", Value: status},
+		{Path: "updatedAt", Value: time.Now()},
 	})
 	return err
 }
@@ -350,7 +735,7 @@ func truncateBody(body string) string {
 	return body[:maxLen] + "..."
 }
 
-// ScanChatMetadata scans all chats and updates metadata flags (HasInvoice, IsOTP) based on keywords
+// ScanChatMetadata re-applies the rules to every chat's last message and name
 func (r *ChatsRepository) ScanChatMetadata(ctx context.Context) (int, error) {
 	iter := r.client.Collection(r.chatsCollection).Documents(ctx)
 	count := 0
@@ -372,49 +757,30 @@ func (r *ChatsRepository) ScanChatMetadata(ctx context.Context) (int, error) {
 			continue
 		}
 
-		body := strings.ToLower(chat.LastMessageBody)
-		
-		// 1. Invoice Check
-		hasInvoice := strings.Contains(body, "inv-") || strings.Contains(body, "invoice") || strings.Contains(body, "tagihan")
-		
-		// 2. OTP Check
-		isOTP := strings.Contains(body, "otp") || strings.Contains(body, "kode verifikasi") || strings.Contains(body, "verification code")
+		result := r.rules.Evaluate(rules.Input{
+			Body:     chat.LastMessageBody,
+			Sender:   chat.Number,
+			ChatName: chat.Name,
+		})
 
-		// 3. Sender Filter (Stockbit, TRI)
-		// Check Name and Number
-		nameLower := strings.ToLower(chat.Name)
-		number := chat.Number // usually just digits, but sometimes full JID
-		if strings.Contains(nameLower, "stockbit") || strings.Contains(nameLower, "tri indonesia") || strings.Contains(number, "628999800123") {
-			isOTP = true
+		// Chats flagged before tags existed get the invoice tag, so GetInvoiceChats finds them
+		if chat.HasInvoice {
+			result.Tags = mergeTags(result.Tags, []string{rules.TagInvoice}, nil)
 		}
 
+		// isOTP is written for every chat, even when false: GetRecentChats filters on
+		// "isOTP == false", which only matches documents that have the field
 		updates := []firestore.Update{}
-		needsUpdate := false
-
-		if hasInvoice && !chat.HasInvoice {
-			updates = append(updates, firestore.Update{Path: "hasInvoice", Value: true})
-			needsUpdate = true
-		}
-		
-		// Always ensure IsOTP is set (default to false if not found, to include in index)
-		// But here we only update if it CHANGED to true, or isn't set.
-		// Actually, to make "Where IsOTP == false" work, ALL documents MUST have IsOTP field.
-		// So we should set IsOTP = false if it's currently nil/missing (which in struct defaults to false, but in DB might be missing).
-		// We'll force update if IsOTP mismatch.
-		// BUT 'chat.IsOTP' from DataTo might be false if missing.
-		// We want to write 'false' explicitly if missing. 
-		// Simpler approach: Just write 'isOTP' = isOTP calculated value if it's currently different or we suspect it's missing.
-		// Since we can't easily know if it's missing vs false in Go struct without custom unmarshalling map, 
-		// we'll explicitly update 'isOTP' for ALL documents that we process to ensure consistency for the index.
-		
-		updates = append(updates, firestore.Update{Path: "isOTP", Value: isOTP})
-		needsUpdate = true // Force update to ensure backfill
-
-		if needsUpdate {
-			batch.Update(doc.Ref, updates)
-			count++
-			operationCount++
+		for _, u := range ruleUpdates(result) {
+			if u.Path != rules.FlagIsOTP {
+				updates = append(updates, u)
+			}
 		}
+		updates = append(updates, firestore.Update{Path: rules.FlagIsOTP, Value: result.Flag(rules.FlagIsOTP)})
+
+		batch.Update(doc.Ref, updates)
+		count++
+		operationCount++
 
 		// Commit batch if limit reached
 		if operationCount >= batchSize {
@@ -50,7 +50,7 @@ func NewClient(ctx context.Context, credentialsPath string, projectID string) (*
 
 // Close closes the Firestore client
 func (c *Client) Close() error {
-	if c.FS != nil {
+	if c != nil && c.FS != nil {
 		return c.FS.Close()
 	}
 	return nil
@@ -70,3 +70,8 @@ func (c *Client) Doc(path string) *firestore.DocumentRef {
 func (c *Client) Batch() *firestore.WriteBatch {
 	return c.FS.Batch()
 }
+
+// RunTransaction runs f in a transaction, retrying it on contention
+func (c *Client) RunTransaction(ctx context.Context, f func(context.Context, *firestore.Transaction) error) error {
+	return c.FS.RunTransaction(ctx, f)
+}
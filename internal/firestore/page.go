@@ -0,0 +1,82 @@
+package firestore
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+// ErrInvalidCursor is returned when a cursor cannot be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is a position in a list ordered by time: the time and document ID of an item.
+// The ID breaks ties between items with the same time.
+type Cursor struct {
+	Time time.Time
+	ID   string
+}
+
+// String encodes the cursor for use in URLs
+func (c Cursor) String() string {
+	raw := strconv.FormatInt(c.Time.UnixNano(), 10) + "|" + c.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseCursor decodes a cursor made by Cursor.String
+func ParseCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	ts, id, ok := strings.Cut(string(raw), "|")
+	if !ok || id == "" {
+		return nil, ErrInvalidCursor
+	}
+	nanos, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &Cursor{Time: time.Unix(0, nanos), ID: id}, nil
+}
+
+// Page selects a window of a list ordered newest first. With Before it continues
+// with older items, with After with newer ones; results are always newest first.
+type Page struct {
+	Limit  int
+	Before *Cursor
+	After  *Cursor
+}
+
+// query orders q by field (then document ID) and applies the cursor and limit
+func (p Page) query(q firestore.Query, field string) firestore.Query {
+	dir := firestore.Desc
+	if p.After != nil {
+		dir = firestore.Asc
+	}
+	q = q.OrderBy(field, dir).OrderBy(firestore.DocumentID, dir)
+
+	switch {
+	case p.After != nil:
+		q = q.StartAfter(p.After.Time, p.After.ID)
+	case p.Before != nil:
+		q = q.StartAfter(p.Before.Time, p.Before.ID)
+	}
+	if p.Limit > 0 {
+		q = q.Limit(p.Limit)
+	}
+	return q
+}
+
+// finish puts items fetched for an After page back in newest-first order
+func finish[T any](p Page, items []T) []T {
+	if p.After != nil {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+	return items
+}
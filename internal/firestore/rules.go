@@ -0,0 +1,213 @@
+package firestore
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"wa-server-go/internal/rules"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+// ruleDoc is a chat rule as stored in Firestore
+type ruleDoc struct {
+	Name       string         `firestore:"name"`
+	Enabled    bool           `firestore:"enabled"`
+	Priority   int            `firestore:"priority"`
+	Match      string         `firestore:"match"`
+	Conditions []conditionDoc `firestore:"conditions"`
+	Actions    []actionDoc    `firestore:"actions"`
+	CreatedAt  time.Time      `firestore:"createdAt"`
+	UpdatedAt  time.Time      `firestore:"updatedAt"`
+}
+
+type conditionDoc struct {
+	Field    string   `firestore:"field"`
+	Keywords []string `firestore:"keywords,omitempty"`
+	Pattern  string   `firestore:"pattern,omitempty"`
+}
+
+type actionDoc struct {
+	Type string `firestore:"type"`
+	Flag string `firestore:"flag,omitempty"`
+	Tag  string `firestore:"tag,omitempty"`
+}
+
+// RulesRepository keeps the chat classification rules in Firestore. It implements rules.Store.
+type RulesRepository struct {
+	client     *Client
+	collection string
+	seededDoc  string // marks that the default rules were stored once
+}
+
+// NewRulesRepository creates a rules repository, seeding the default rules on first use.
+// Defaults deleted later are not restored.
+func NewRulesRepository(ctx context.Context, client *Client) (*RulesRepository, error) {
+	r := &RulesRepository{
+		client:     client,
+		collection: "chat_rules",
+		seededDoc:  "settings/chat_rules",
+	}
+	if err := r.seed(ctx); err != nil {
+		return nil, fmt.Errorf("failed to seed chat rules: %w", err)
+	}
+	return r, nil
+}
+
+func (r *RulesRepository) seed(ctx context.Context) error {
+	marker := r.client.Doc(r.seededDoc)
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		snap, err := tx.Get(marker)
+		if snap == nil || snap.Exists() { // already seeded, or the read failed
+			return err
+		}
+
+		now := time.Now()
+		for i, rule := range rules.Defaults() {
+			// Spread the creation times so the seeded rules keep their order
+			doc := toRuleDoc(&rule, now.Add(time.Duration(i)*time.Millisecond))
+			if err := tx.Create(r.client.Collection(r.collection).Doc(rules.NewID()), doc); err != nil {
+				return err
+			}
+		}
+		return tx.Set(marker, map[string]interface{}{"seededAt": now})
+	})
+}
+
+// Create stores a new rule
+func (r *RulesRepository) Create(ctx context.Context, rule *rules.Rule) error {
+	id := rules.NewID()
+	if _, err := r.client.Collection(r.collection).Doc(id).Create(ctx, toRuleDoc(rule, time.Now())); err != nil {
+		return fmt.Errorf("failed to create rule: %w", err)
+	}
+	rule.ID = id
+	return nil
+}
+
+// Update saves an existing rule, keeping its creation time
+func (r *RulesRepository) Update(ctx context.Context, rule *rules.Rule) error {
+	ref := r.client.Collection(r.collection).Doc(rule.ID)
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		snap, err := tx.Get(ref)
+		if snap != nil && !snap.Exists() {
+			return rules.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		var existing ruleDoc
+		if err := snap.DataTo(&existing); err != nil {
+			return err
+		}
+		doc := toRuleDoc(rule, existing.CreatedAt)
+		doc.UpdatedAt = time.Now()
+		return tx.Set(ref, doc)
+	})
+}
+
+// Delete removes a rule
+func (r *RulesRepository) Delete(ctx context.Context, id string) error {
+	ref := r.client.Collection(r.collection).Doc(id)
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		snap, err := tx.Get(ref)
+		if snap != nil && !snap.Exists() {
+			return rules.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Delete(ref)
+	})
+}
+
+// Get returns a rule by ID
+func (r *RulesRepository) Get(ctx context.Context, id string) (*rules.Rule, error) {
+	snap, err := r.client.Collection(r.collection).Doc(id).Get(ctx)
+	if snap != nil && !snap.Exists() {
+		return nil, rules.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	rule, _, err := fromRuleDoc(snap)
+	return rule, err
+}
+
+// List returns all rules by priority, then creation order. The collection is small, so it is
+// sorted here rather than with a composite index.
+func (r *RulesRepository) List(ctx context.Context) ([]*rules.Rule, error) {
+	iter := r.client.Collection(r.collection).Documents(ctx)
+	defer iter.Stop()
+
+	list := []*rules.Rule{}
+	created := map[string]time.Time{}
+	for {
+		snap, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		rule, createdAt, err := fromRuleDoc(snap)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, rule)
+		created[rule.ID] = createdAt
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Priority != list[j].Priority {
+			return list[i].Priority < list[j].Priority
+		}
+		return created[list[i].ID].Before(created[list[j].ID])
+	})
+	return list, nil
+}
+
+func toRuleDoc(rule *rules.Rule, createdAt time.Time) ruleDoc {
+	doc := ruleDoc{
+		Name:       rule.Name,
+		Enabled:    rule.Enabled,
+		Priority:   rule.Priority,
+		Match:      rule.Match,
+		Conditions: make([]conditionDoc, len(rule.Conditions)),
+		Actions:    make([]actionDoc, len(rule.Actions)),
+		CreatedAt:  createdAt,
+		UpdatedAt:  createdAt,
+	}
+	for i, c := range rule.Conditions {
+		doc.Conditions[i] = conditionDoc{Field: c.Field, Keywords: c.Keywords, Pattern: c.Pattern}
+	}
+	for i, a := range rule.Actions {
+		doc.Actions[i] = actionDoc{Type: a.Type, Flag: a.Flag, Tag: a.Tag}
+	}
+	return doc
+}
+
+func fromRuleDoc(snap *firestore.DocumentSnapshot) (*rules.Rule, time.Time, error) {
+	var doc ruleDoc
+	if err := snap.DataTo(&doc); err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to decode rule %s: %w", snap.Ref.ID, err)
+	}
+	rule := &rules.Rule{
+		ID:         snap.Ref.ID,
+		Name:       doc.Name,
+		Enabled:    doc.Enabled,
+		Priority:   doc.Priority,
+		Match:      doc.Match,
+		Conditions: make([]rules.Condition, len(doc.Conditions)),
+		Actions:    make([]rules.Action, len(doc.Actions)),
+	}
+	for i, c := range doc.Conditions {
+		rule.Conditions[i] = rules.Condition{Field: c.Field, Keywords: c.Keywords, Pattern: c.Pattern}
+	}
+	for i, a := range doc.Actions {
+		rule.Actions[i] = rules.Action{Type: a.Type, Flag: a.Flag, Tag: a.Tag}
+	}
+	return rule, doc.CreatedAt, nil
+}
@@ -0,0 +1,113 @@
+package media
+
+import (
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signedParams returns the key, expires and sig of a URL made by LocalStore.SignedURL
+func signedParams(t *testing.T, signed string) (key, expires, sig string) {
+	t.Helper()
+	u, err := url.Parse(signed)
+	if err != nil {
+		t.Fatalf("parse %q: %v", signed, err)
+	}
+	if !strings.HasPrefix(u.Path, LocalRoute) {
+		t.Fatalf("URL %q is not under %s", signed, LocalRoute)
+	}
+	return strings.TrimPrefix(u.Path, LocalRoute), u.Query().Get("expires"), u.Query().Get("sig")
+}
+
+func TestLocalStoreSignedURL(t *testing.T) {
+	s := NewLocalStore(t.TempDir(), []byte("secret"))
+
+	signed, err := s.SignedURL("messages/ABC.jpg", time.Minute)
+	if err != nil {
+		t.Fatalf("SignedURL: %v", err)
+	}
+	key, expires, sig := signedParams(t, signed)
+	if key != "messages/ABC.jpg" {
+		t.Errorf("key = %q, want messages/ABC.jpg", key)
+	}
+	if !s.Verify(key, expires, sig) {
+		t.Errorf("Verify rejected a fresh URL: %s", signed)
+	}
+
+	later := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	tests := []struct {
+		name              string
+		key, expires, sig string
+	}{
+		{"tampered signature", key, expires, strings.Repeat("0", len(sig))},
+		{"truncated signature", key, expires, sig[:len(sig)-2]},
+		{"empty signature", key, expires, ""},
+		{"other key", "messages/XYZ.jpg", expires, sig},
+		{"extended expiry", key, later, sig},
+		{"invalid expiry", key, "soon", sig},
+		{"other signing key", key, expires, NewLocalStore(t.TempDir(), []byte("other")).sign(key, mustParseInt(t, expires))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if s.Verify(tt.key, tt.expires, tt.sig) {
+				t.Errorf("Verify(%q, %q, %q) = true, want false", tt.key, tt.expires, tt.sig)
+			}
+		})
+	}
+}
+
+func TestLocalStoreSignedURLExpires(t *testing.T) {
+	s := NewLocalStore(t.TempDir(), []byte("secret"))
+
+	signed, err := s.SignedURL("messages/ABC.jpg", -time.Second)
+	if err != nil {
+		t.Fatalf("SignedURL: %v", err)
+	}
+	key, expires, sig := signedParams(t, signed)
+	if s.Verify(key, expires, sig) {
+		t.Errorf("Verify accepted an expired URL: %s", signed)
+	}
+}
+
+func TestLocalStorePath(t *testing.T) {
+	root := t.TempDir()
+	s := NewLocalStore(root, []byte("secret"))
+
+	valid := map[string]string{
+		"messages/ABC.jpg":  filepath.Join(root, "messages", "ABC.jpg"),
+		"/messages/ABC.jpg": filepath.Join(root, "messages", "ABC.jpg"),
+		"a//b.jpg":          filepath.Join(root, "a", "b.jpg"),
+		"/etc/passwd":       filepath.Join(root, "etc", "passwd"), // absolute keys stay under the root
+	}
+	for key, want := range valid {
+		got, err := s.Path(key)
+		if err != nil {
+			t.Errorf("Path(%q): %v", key, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("Path(%q) = %s, want %s", key, got, want)
+		}
+	}
+
+	for _, key := range []string{"", "/", "..", "../secret.txt", "messages/../../secret.txt", "a/..", "..%2Fsecret", `..\secret.txt`} {
+		if got, err := s.Path(key); err == nil {
+			t.Errorf("Path(%q) = %s, want an error", key, got)
+		}
+		if _, err := s.SignedURL(key, time.Minute); err == nil {
+			t.Errorf("SignedURL(%q) signed an invalid key", key)
+		}
+	}
+}
+
+func mustParseInt(t *testing.T, s string) int64 {
+	t.Helper()
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		t.Fatalf("parse %q: %v", s, err)
+	}
+	return n
+}
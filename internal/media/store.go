@@ -0,0 +1,79 @@
+package media
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"wa-server-go/internal/config"
+)
+
+// ErrNotFound is returned when an object does not exist in the store
+var ErrNotFound = errors.New("media object not found")
+
+// Store persists media files and hands out signed, expiring URLs to them
+type Store interface {
+	// Put writes an object, replacing any existing one with the same key
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Open returns a reader for an object, or ErrNotFound
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Exists reports whether an object is present
+	Exists(ctx context.Context, key string) (bool, error)
+	// Delete removes an object (missing objects are not an error)
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a URL that grants read access to an object until ttl elapses
+	SignedURL(key string, ttl time.Duration) (string, error)
+}
+
+// MessageKey returns the storage key for the attachment of a WhatsApp message
+func MessageKey(messageID, ext string) string {
+	return "media/" + messageID + ext
+}
+
+// LegacyKey maps a MediaURL written before media stores existed ("/uploads/media/<file>")
+// to its storage key, or "" if the URL is not a legacy upload path
+func LegacyKey(mediaURL string) string {
+	if !strings.HasPrefix(mediaURL, "/uploads/") {
+		return ""
+	}
+	return strings.TrimPrefix(mediaURL, "/uploads/")
+}
+
+// NewFromConfig creates the media store selected by MEDIA_STORE
+func NewFromConfig(cfg *config.Config) (Store, error) {
+	switch cfg.MediaStore {
+	case "", "local":
+		signingKey := []byte(cfg.MediaSigningKey)
+		if len(signingKey) == 0 {
+			// Without a configured key, signed URLs stop working after a restart
+			log.Println("⚠️ [MEDIA] MEDIA_SIGNING_KEY not set, using a random key for this run")
+			signingKey = make([]byte, 32)
+			_, _ = rand.Read(signingKey)
+		}
+		log.Printf("✅ [MEDIA] Using local media store at %s", cfg.MediaLocalDir)
+		return NewLocalStore(cfg.MediaLocalDir, signingKey), nil
+
+	case "s3":
+		store, err := NewS3Store(S3Config{
+			Endpoint:        cfg.S3Endpoint,
+			Region:          cfg.S3Region,
+			Bucket:          cfg.S3Bucket,
+			AccessKeyID:     cfg.S3AccessKeyID,
+			SecretAccessKey: cfg.S3SecretAccessKey,
+			PathStyle:       cfg.S3PathStyle,
+		})
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("✅ [MEDIA] Using S3 media store %s/%s", cfg.S3Endpoint, cfg.S3Bucket)
+		return store, nil
+
+	default:
+		return nil, fmt.Errorf("unknown MEDIA_STORE %q (expected local or s3)", cfg.MediaStore)
+	}
+}
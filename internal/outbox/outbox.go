@@ -0,0 +1,251 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"wa-server-go/internal/media"
+	"wa-server-go/internal/whatsapp"
+)
+
+const (
+	// MaxAttempts is how many times a job is tried before it is marked failed
+	MaxAttempts = 5
+
+	idlePoll        = 30 * time.Second
+	minReadyBackoff = 2 * time.Second
+	maxReadyBackoff = time.Minute
+	sendTimeout     = 2 * time.Minute
+)
+
+// Outbox drains queued sends through the WhatsApp manager in order, per client
+type Outbox struct {
+	store        *Store
+	waManager    *whatsapp.Manager
+	media        media.Store
+	wake         chan struct{}
+	stop         chan struct{}
+	done         chan struct{}
+	readyBackoff time.Duration
+}
+
+// New creates an outbox on top of a job store. Sent media is kept in mediaStore for history display.
+func New(store *Store, waManager *whatsapp.Manager, mediaStore media.Store) *Outbox {
+	return &Outbox{
+		store:        store,
+		waManager:    waManager,
+		media:        mediaStore,
+		wake:         make(chan struct{}, 1),
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+		readyBackoff: minReadyBackoff,
+	}
+}
+
+// Start launches the background worker
+func (o *Outbox) Start() {
+	if n, err := o.store.RequeueInterrupted(context.Background()); err != nil {
+		log.Printf("⚠️ [OUTBOX] Failed to requeue interrupted jobs: %v", err)
+	} else if n > 0 {
+		log.Printf("🔁 [OUTBOX] Requeued %d job(s) interrupted by the last shutdown", n)
+	}
+
+	go o.run()
+	log.Println("✅ [OUTBOX] Worker started")
+}
+
+// Stop signals the worker to exit and waits (until ctx expires) for the current job to finish.
+// Jobs still queued stay in the store and are sent after the next start.
+func (o *Outbox) Stop(ctx context.Context) error {
+	close(o.stop)
+	select {
+	case <-o.done:
+		log.Println("✅ [OUTBOX] Worker stopped")
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("gave up waiting for the current send: %w", ctx.Err())
+	}
+}
+
+// Wake nudges the worker, e.g. after a new job or when a client (re)connects
+func (o *Outbox) Wake() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Enqueue persists a job and wakes the worker
+func (o *Outbox) Enqueue(ctx context.Context, clientID string, kind Kind, payload interface{}) (*Job, error) {
+	job, err := o.store.Insert(ctx, clientID, kind, payload)
+	if err != nil {
+		return nil, err
+	}
+	o.Wake()
+	return job, nil
+}
+
+// Get returns a job by ID
+func (o *Outbox) Get(ctx context.Context, id string) (*Job, error) {
+	return o.store.Get(ctx, id)
+}
+
+// List returns recent jobs, optionally filtered by status and client
+func (o *Outbox) List(ctx context.Context, status Status, clientID string, limit int) ([]*Job, error) {
+	return o.store.List(ctx, status, clientID, limit)
+}
+
+// Cancel cancels a queued job. Jobs already sending, sent or failed cannot be cancelled.
+func (o *Outbox) Cancel(ctx context.Context, id string) (*Job, error) {
+	job, err := o.store.Cancel(ctx, id)
+	if err == nil {
+		log.Printf("🛑 [OUTBOX] Job %s cancelled", id)
+	}
+	return job, err
+}
+
+// CancelClient cancels all queued jobs of a client, e.g. when its session is deleted
+func (o *Outbox) CancelClient(ctx context.Context, clientID string) (int64, error) {
+	n, err := o.store.CancelClient(ctx, clientID)
+	if err == nil && n > 0 {
+		log.Printf("🛑 [OUTBOX] Cancelled %d queued job(s) of %s", n, clientID)
+	}
+	return n, err
+}
+
+func (o *Outbox) run() {
+	defer close(o.done)
+
+	for {
+		wait := o.process()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-o.stop:
+			timer.Stop()
+			return
+		case <-o.wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// process sends at most one job and returns how long to wait before looking again
+func (o *Outbox) process() time.Duration {
+	ctx := context.Background()
+
+	heads, err := o.store.Heads(ctx)
+	if err != nil {
+		log.Printf("❌ [OUTBOX] Failed to load queue: %v", err)
+		return idlePoll
+	}
+	if len(heads) == 0 {
+		return idlePoll
+	}
+
+	now := time.Now()
+	wait := idlePoll
+	waitingForClient := false
+
+	for _, job := range heads {
+		if !o.waManager.IsReady(job.ClientID) {
+			waitingForClient = true
+			continue
+		}
+		if job.NextAttemptAt.After(now) {
+			if d := job.NextAttemptAt.Sub(now); d < wait {
+				wait = d
+			}
+			continue
+		}
+
+		o.readyBackoff = minReadyBackoff
+		o.send(ctx, job)
+		return 0
+	}
+
+	// Nothing could be sent: poll with backoff while a client is disconnected.
+	// The worker is also woken as soon as a client reports Connected.
+	if waitingForClient {
+		if o.readyBackoff < wait {
+			wait = o.readyBackoff
+		}
+		o.readyBackoff *= 2
+		if o.readyBackoff > maxReadyBackoff {
+			o.readyBackoff = maxReadyBackoff
+		}
+	}
+	return wait
+}
+
+// send runs a single attempt of a job and records the outcome
+func (o *Outbox) send(ctx context.Context, job *Job) {
+	if err := o.store.MarkSending(ctx, job); err != nil {
+		if err != ErrNotQueued {
+			log.Printf("❌ [OUTBOX] Failed to mark job %s as sending: %v", job.ID, err)
+		}
+		return
+	}
+
+	log.Printf("📤 [OUTBOX] Sending job %s (%s, attempt %d/%d)", job.ID, job.Kind, job.Attempts, MaxAttempts)
+
+	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+	err := o.deliver(sendCtx, job)
+	cancel()
+
+	if err == nil {
+		if err := o.store.MarkSent(ctx, job); err != nil {
+			log.Printf("❌ [OUTBOX] Failed to mark job %s as sent: %v", job.ID, err)
+		}
+		log.Printf("✅ [OUTBOX] Job %s sent", job.ID)
+		return
+	}
+
+	if job.Attempts >= MaxAttempts {
+		log.Printf("❌ [OUTBOX] Job %s failed permanently: %v", job.ID, err)
+		if err := o.store.MarkFailed(ctx, job, err); err != nil {
+			log.Printf("❌ [OUTBOX] Failed to mark job %s as failed: %v", job.ID, err)
+		}
+		return
+	}
+
+	next := time.Now().Add(retryDelay(job.Attempts))
+	log.Printf("⚠️ [OUTBOX] Job %s attempt %d failed, retrying at %s: %v", job.ID, job.Attempts, next.Format("15:04:05"), err)
+	if err := o.store.MarkRetry(ctx, job, err, next); err != nil {
+		log.Printf("❌ [OUTBOX] Failed to requeue job %s: %v", job.ID, err)
+	}
+}
+
+// deliver dispatches a job to the sender for its kind
+func (o *Outbox) deliver(ctx context.Context, job *Job) error {
+	client, ok := o.waManager.GetClient(job.ClientID)
+	if !ok {
+		return fmt.Errorf("client %s not found", job.ClientID)
+	}
+
+	switch job.Kind {
+	case KindText:
+		return o.deliverText(ctx, client, job)
+	case KindMedia:
+		return o.deliverMedia(ctx, client, job)
+	case KindInvoice:
+		return o.deliverInvoice(ctx, client, job)
+	default:
+		return fmt.Errorf("unknown job kind %q", job.Kind)
+	}
+}
+
+// retryDelay returns the exponential backoff after the given number of attempts
+func retryDelay(attempts int) time.Duration {
+	delay := 10 * time.Second
+	for i := 1; i < attempts; i++ {
+		delay *= 3
+	}
+	if delay > 30*time.Minute {
+		delay = 30 * time.Minute
+	}
+	return delay
+}
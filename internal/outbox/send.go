@@ -0,0 +1,433 @@
+package outbox
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"wa-server-go/internal/firestore"
+	"wa-server-go/internal/media"
+	"wa-server-go/internal/utils"
+	"wa-server-go/internal/whatsapp"
+
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+// TextPayload is the payload of a KindText job
+type TextPayload struct {
+	Number  string `json:"number"`
+	Message string `json:"message"`
+}
+
+// MediaPayload is the payload of a KindMedia job
+type MediaPayload struct {
+	Number    string `json:"number"`
+	MediaURL  string `json:"mediaUrl"`
+	Caption   string `json:"caption,omitempty"`
+	MediaType string `json:"mediaType,omitempty"`
+}
+
+// InvoicePayload is the payload of a KindInvoice job: a text message followed by an optional PDF.
+// InvoiceNumber and InvoiceStatus (a status key) are recorded on the chat when set.
+type InvoicePayload struct {
+	Number        string `json:"number"`
+	Message       string `json:"message"`
+	PdfURL        string `json:"pdfUrl,omitempty"`
+	PdfBase64     string `json:"pdfBase64,omitempty"`
+	FileName      string `json:"fileName,omitempty"`
+	ClientName    string `json:"clientName,omitempty"`
+	InvoiceNumber string `json:"invoiceNumber,omitempty"`
+	InvoiceStatus string `json:"invoiceStatus,omitempty"`
+}
+
+// deliverText sends a KindText job
+func (o *Outbox) deliverText(ctx context.Context, client *whatsapp.Client, job *Job) error {
+	var p TextPayload
+	if err := json.Unmarshal(job.Payload, &p); err != nil {
+		return fmt.Errorf("invalid payload: %w", err)
+	}
+	if partSent(job, 0) {
+		return nil
+	}
+
+	jid := utils.PhoneToJID(p.Number)
+	normalizedMessage := utils.NormalizeNewlines(p.Message)
+
+	// Anti-bot: Simulate typing indicator to appear more human-like
+	simulateTyping(ctx, client, jid, normalizedMessage, 40, 1000, 5000, 1000)
+
+	resp, err := client.WAClient.SendMessage(ctx, jid, &waProto.Message{
+		Conversation: proto.String(normalizedMessage),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	if err := o.store.AddMessageID(ctx, job, resp.ID); err != nil {
+		return fmt.Errorf("failed to record sent message %s: %w", resp.ID, err)
+	}
+
+	// Manual Save & Broadcast (Ensure "Live" Chat Visibility)
+	o.saveAndBroadcast(job.ClientID, &firestore.WAMessage{
+		MessageID: resp.ID,
+		ChatID:    jid.String(),
+		From:      client.WAClient.Store.ID.ToNonAD().String(),
+		To:        jid.String(),
+		Body:      normalizedMessage,
+		Timestamp: resp.Timestamp,
+		FromMe:    true,
+		HasMedia:  false,
+		Type:      "text",
+		Ack:       1,
+	}, utils.JIDToPhoneNumber(jid), nil)
+
+	return nil
+}
+
+// deliverMedia sends a KindMedia job as an image or a document depending on its content type
+func (o *Outbox) deliverMedia(ctx context.Context, client *whatsapp.Client, job *Job) error {
+	var p MediaPayload
+	if err := json.Unmarshal(job.Payload, &p); err != nil {
+		return fmt.Errorf("invalid payload: %w", err)
+	}
+	if partSent(job, 0) {
+		return nil
+	}
+
+	jid := utils.PhoneToJID(p.Number)
+
+	// Download media from URL
+	mediaData, contentType, err := download(ctx, p.MediaURL)
+	if err != nil {
+		return fmt.Errorf("failed to download media: %w", err)
+	}
+
+	// Anti-bot: Simulate media upload/typing presence
+	_ = client.WAClient.SendChatPresence(ctx, jid, types.ChatPresenceComposing, types.ChatPresenceMediaText)
+
+	// Anti-bot: Human-like delay
+	utils.HumanizeDelay(2000, 4000) // Increase slightly for media
+
+	_ = client.WAClient.SendChatPresence(ctx, jid, types.ChatPresencePaused, types.ChatPresenceMediaText)
+
+	// Determine media type and upload
+	msgType := "document"
+	mediaType := whatsmeow.MediaDocument
+	if isImageMime(contentType) {
+		msgType = "image"
+		mediaType = whatsmeow.MediaImage
+	}
+
+	uploaded, err := client.WAClient.Upload(ctx, mediaData, mediaType)
+	if err != nil {
+		return fmt.Errorf("failed to upload %s: %w", msgType, err)
+	}
+
+	var msg *waProto.Message
+	if msgType == "image" {
+		msg = &waProto.Message{
+			ImageMessage: &waProto.ImageMessage{
+				URL:           proto.String(uploaded.URL),
+				Mimetype:      proto.String(contentType),
+				Caption:       proto.String(p.Caption),
+				DirectPath:    proto.String(uploaded.DirectPath),
+				MediaKey:      uploaded.MediaKey,
+				FileEncSHA256: uploaded.FileEncSHA256,
+				FileSHA256:    uploaded.FileSHA256,
+				FileLength:    proto.Uint64(uint64(len(mediaData))),
+			},
+		}
+	} else {
+		msg = &waProto.Message{
+			DocumentMessage: &waProto.DocumentMessage{
+				URL:           proto.String(uploaded.URL),
+				Mimetype:      proto.String(contentType),
+				Caption:       proto.String(p.Caption),
+				DirectPath:    proto.String(uploaded.DirectPath),
+				MediaKey:      uploaded.MediaKey,
+				FileEncSHA256: uploaded.FileEncSHA256,
+				FileSHA256:    uploaded.FileSHA256,
+				FileLength:    proto.Uint64(uint64(len(mediaData))),
+			},
+		}
+	}
+
+	resp, err := client.WAClient.SendMessage(ctx, jid, msg)
+	if err != nil {
+		return fmt.Errorf("failed to send %s: %w", msgType, err)
+	}
+	if err := o.store.AddMessageID(ctx, job, resp.ID); err != nil {
+		return fmt.Errorf("failed to record sent message %s: %w", resp.ID, err)
+	}
+
+	// Keep a copy in the media store, typed by the same allow-list as received media:
+	// the remote server's Content-Type is not trusted either
+	storedType := whatsapp.MediaContentType(contentType, msgType)
+	mediaPath := o.storeMedia(ctx, resp.ID, whatsapp.MediaExtension(storedType), storedType, mediaData)
+
+	body := "[Document] " + p.Caption
+	if msgType == "image" {
+		body = "[Image] " + p.Caption
+	}
+
+	// Manual Save & Broadcast
+	o.saveAndBroadcast(job.ClientID, &firestore.WAMessage{
+		MessageID: resp.ID,
+		ChatID:    jid.String(),
+		From:      client.WAClient.Store.ID.ToNonAD().String(),
+		To:        jid.String(),
+		Body:      body,
+		Timestamp: resp.Timestamp,
+		FromMe:    true,
+		HasMedia:  true,
+		MediaType: storedType,
+		MediaURL:  "/get-media/" + resp.ID,
+		MediaPath: mediaPath,
+		Type:      msgType,
+		Ack:       1,
+	}, utils.JIDToPhoneNumber(jid), nil)
+
+	return nil
+}
+
+// deliverInvoice sends a KindInvoice job: the invoice text, then the PDF if one was provided.
+// Each part is recorded on the job as it is sent, so a retry only resends what is missing.
+func (o *Outbox) deliverInvoice(ctx context.Context, client *whatsapp.Client, job *Job) error {
+	var p InvoicePayload
+	if err := json.Unmarshal(job.Payload, &p); err != nil {
+		return fmt.Errorf("invalid payload: %w", err)
+	}
+
+	// Format phone number and create JID
+	jid := utils.PhoneToJID(p.Number)
+
+	// Update Chat Name if provided
+	chatName := jid.User // Default to phone number
+	if p.ClientName != "" {
+		chatName = p.ClientName
+	}
+
+	if !partSent(job, 0) {
+		// Normalize message newlines
+		normalizedMessage := utils.NormalizeNewlines(p.Message)
+
+		// Anti-bot: Simulate typing indicator to appear more human-like
+		simulateTyping(ctx, client, jid, normalizedMessage, 50, 2000, 8000, 2000)
+
+		resp, err := client.WAClient.SendMessage(ctx, jid, &waProto.Message{
+			Conversation: proto.String(normalizedMessage),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to send message: %w", err)
+		}
+		if err := o.store.AddMessageID(ctx, job, resp.ID); err != nil {
+			return fmt.Errorf("failed to record sent message %s: %w", resp.ID, err)
+		}
+
+		if repo := o.waManager.RepoFor(job.ClientID); p.ClientName != "" && repo != nil {
+			_ = repo.UpdateChatName(ctx, jid.String(), p.ClientName)
+		}
+
+		// Manual Save & Broadcast for Text (Fail-safe)
+		o.saveAndBroadcast(job.ClientID, &firestore.WAMessage{
+			MessageID: resp.ID,
+			ChatID:    jid.String(),
+			From:      client.WAClient.Store.ID.ToNonAD().String(),
+			To:        jid.String(),
+			Body:      normalizedMessage,
+			Timestamp: resp.Timestamp,
+			FromMe:    true,
+			HasMedia:  false,
+			Type:      "text",
+			Ack:       1,
+		}, chatName, &p)
+	}
+
+	// Send PDF if provided
+	if p.PdfBase64 == "" && p.PdfURL == "" {
+		return nil
+	}
+	return o.sendPDF(ctx, client, job, jid, &p, chatName)
+}
+
+// sendPDF uploads and sends a PDF document
+func (o *Outbox) sendPDF(ctx context.Context, client *whatsapp.Client, job *Job, jid types.JID, p *InvoicePayload, chatName string) error {
+	if partSent(job, 1) {
+		return nil
+	}
+
+	var pdfData []byte
+	var err error
+
+	if p.PdfBase64 != "" {
+		pdfData, err = base64.StdEncoding.DecodeString(p.PdfBase64)
+		if err != nil {
+			return fmt.Errorf("failed to decode PDF base64: %w", err)
+		}
+	} else if p.PdfURL != "" {
+		pdfData, _, err = download(ctx, p.PdfURL)
+		if err != nil {
+			return fmt.Errorf("failed to download PDF: %w", err)
+		}
+	}
+
+	if len(pdfData) == 0 {
+		return fmt.Errorf("PDF is empty")
+	}
+
+	// Upload to WhatsApp
+	uploaded, err := client.WAClient.Upload(ctx, pdfData, whatsmeow.MediaDocument)
+	if err != nil {
+		return fmt.Errorf("failed to upload PDF: %w", err)
+	}
+
+	// Set filename
+	fileName := p.FileName
+	if fileName == "" {
+		fileName = fmt.Sprintf("Invoice-%d.pdf", time.Now().Unix())
+	}
+
+	// Send document message
+	resp, err := client.WAClient.SendMessage(ctx, jid, &waProto.Message{
+		DocumentMessage: &waProto.DocumentMessage{
+			URL:           proto.String(uploaded.URL),
+			Mimetype:      proto.String("application/pdf"),
+			Title:         proto.String(fileName),
+			FileName:      proto.String(fileName),
+			DirectPath:    proto.String(uploaded.DirectPath),
+			MediaKey:      uploaded.MediaKey,
+			FileEncSHA256: uploaded.FileEncSHA256,
+			FileSHA256:    uploaded.FileSHA256,
+			FileLength:    proto.Uint64(uint64(len(pdfData))),
+			Caption:       proto.String("Berikut terlampir dokumen invoice Anda."),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send PDF: %w", err)
+	}
+	if err := o.store.AddMessageID(ctx, job, resp.ID); err != nil {
+		return fmt.Errorf("failed to record sent message %s: %w", resp.ID, err)
+	}
+
+	// Keep a copy in the media store for history display (using Message ID)
+	mediaPath := o.storeMedia(ctx, resp.ID, ".pdf", "application/pdf", pdfData)
+
+	// Manually Save & Broadcast to ensure visibility (Bypass missing Echo)
+	o.saveAndBroadcast(job.ClientID, &firestore.WAMessage{
+		MessageID: resp.ID,
+		ChatID:    jid.String(),
+		From:      client.WAClient.Store.ID.ToNonAD().String(),
+		To:        jid.String(),
+		Body:      "[Document] " + fileName,
+		Timestamp: resp.Timestamp,
+		FromMe:    true,
+		HasMedia:  true,
+		MediaType: "application/pdf",
+		MediaURL:  "/get-media/" + resp.ID,
+		MediaPath: mediaPath,
+		Type:      "document",
+		Ack:       1,
+	}, chatName, p)
+
+	fmt.Println("✅ PDF sent successfully")
+	return nil
+}
+
+// saveAndBroadcast stores an outgoing message in Firestore and pushes it to WebSocket clients.
+// Messages of an invoice job (invoice != nil) also mark the chat as an invoice chat.
+// It runs in the background, tracked by the manager so shutdown waits for the write.
+func (o *Outbox) saveAndBroadcast(clientID string, dbMsg *firestore.WAMessage, chatName string, invoice *InvoicePayload) {
+	o.waManager.Go(func() {
+		if repo := o.waManager.RepoFor(clientID); repo != nil {
+			_ = repo.SaveMessage(context.Background(), dbMsg)
+			if invoice != nil && invoice.InvoiceNumber != "" {
+				_ = repo.SetChatInvoice(context.Background(), dbMsg.ChatID, invoice.InvoiceNumber, invoice.InvoiceStatus)
+			} else if invoice != nil {
+				_ = repo.SetChatHasInvoice(context.Background(), dbMsg.ChatID, true)
+			}
+		}
+
+		o.waManager.BroadcastMessage(whatsapp.NewMessageEvent{
+			Client:    clientID,
+			ID:        dbMsg.MessageID,
+			From:      dbMsg.From,
+			To:        dbMsg.To,
+			Body:      dbMsg.Body,
+			Timestamp: dbMsg.Timestamp.Unix(),
+			FromMe:    true,
+			ChatID:    dbMsg.ChatID,
+			ChatName:  chatName,
+			HasMedia:  dbMsg.HasMedia,
+			Type:      dbMsg.Type,
+		})
+	})
+}
+
+// simulateTyping shows a typing indicator for a duration proportional to the message length
+func simulateTyping(ctx context.Context, client *whatsapp.Client, jid types.JID, text string, msPerChar, minMs, maxMs, jitterMs int) {
+	// 1. Send "composing" (typing) presence
+	_ = client.WAClient.SendChatPresence(ctx, jid, types.ChatPresenceComposing, types.ChatPresenceMediaText)
+
+	// 2. Wait based on message length (simulates typing time)
+	typingDelay := len(text) * msPerChar
+	if typingDelay < minMs {
+		typingDelay = minMs
+	}
+	if typingDelay > maxMs {
+		typingDelay = maxMs
+	}
+	utils.HumanizeDelay(typingDelay, typingDelay+jitterMs)
+
+	// 3. Stop typing indicator
+	_ = client.WAClient.SendChatPresence(ctx, jid, types.ChatPresencePaused, types.ChatPresenceMediaText)
+}
+
+// partSent reports whether part i (counting from 0) of a job went out on an earlier attempt.
+// Parts are sent in order and each message ID is recorded right after its send.
+func partSent(job *Job, i int) bool {
+	return len(job.MessageIDs) > i
+}
+
+// download fetches a remote file and returns its body and content type
+func download(ctx context.Context, url string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, resp.Header.Get("Content-Type"), nil
+}
+
+// storeMedia writes outgoing media to the media store and returns its key ("" if it could not be saved)
+func (o *Outbox) storeMedia(ctx context.Context, messageID, ext, contentType string, data []byte) string {
+	key := media.MessageKey(messageID, ext)
+	if err := o.media.Put(ctx, key, data, contentType); err != nil {
+		fmt.Printf("⚠️ Failed to store outgoing media: %v\n", err)
+		return ""
+	}
+	fmt.Printf("✅ Outgoing media stored: %s\n", key)
+	return key
+}
+
+func isImageMime(mime string) bool {
+	return mime == "image/jpeg" || mime == "image/png" || mime == "image/gif" || mime == "image/webp"
+}
@@ -0,0 +1,331 @@
+package outbox
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Kind identifies what a queued job sends
+type Kind string
+
+const (
+	KindText    Kind = "text"
+	KindMedia   Kind = "media"
+	KindInvoice Kind = "invoice"
+)
+
+// Status represents the lifecycle state of a job
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusSending   Status = "sending"
+	StatusSent      Status = "sent"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+var (
+	// ErrNotFound is returned when a job ID does not exist
+	ErrNotFound = errors.New("job not found")
+	// ErrNotQueued is returned when a job is no longer waiting in the queue
+	ErrNotQueued = errors.New("job is not queued")
+)
+
+// Job is a single outbound send persisted in the outbox
+type Job struct {
+	ID            string          `json:"id"`
+	ClientID      string          `json:"client"`
+	Kind          Kind            `json:"kind"`
+	Payload       json.RawMessage `json:"-"`
+	Status        Status          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	LastError     string          `json:"lastError,omitempty"`
+	MessageIDs    []string        `json:"messageIds"`
+	NextAttemptAt time.Time       `json:"nextAttemptAt"`
+	CreatedAt     time.Time       `json:"createdAt"`
+	UpdatedAt     time.Time       `json:"updatedAt"`
+	SentAt        *time.Time      `json:"sentAt,omitempty"`
+}
+
+// Store persists outbox jobs in SQLite
+type Store struct {
+	db *sql.DB
+}
+
+const schema = `
+CREATE TABLE IF NOT EXISTS outbox_jobs (
+	seq             INTEGER PRIMARY KEY AUTOINCREMENT,
+	id              TEXT NOT NULL UNIQUE,
+	client_id       TEXT NOT NULL,
+	kind            TEXT NOT NULL,
+	payload         TEXT NOT NULL,
+	status          TEXT NOT NULL,
+	attempts        INTEGER NOT NULL DEFAULT 0,
+	last_error      TEXT NOT NULL DEFAULT '',
+	message_ids     TEXT NOT NULL DEFAULT '[]',
+	next_attempt_at INTEGER NOT NULL,
+	created_at      INTEGER NOT NULL,
+	updated_at      INTEGER NOT NULL,
+	sent_at         INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_jobs_status ON outbox_jobs(status, client_id, seq);
+`
+
+const jobColumns = `id, client_id, kind, payload, status, attempts, last_error, message_ids, next_attempt_at, created_at, updated_at, sent_at`
+
+// NewStore creates the outbox tables if needed
+func NewStore(db *sql.DB) (*Store, error) {
+	if _, err := db.Exec(schema); err != nil {
+		return nil, fmt.Errorf("failed to create outbox schema: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Insert persists a new queued job
+func (s *Store) Insert(ctx context.Context, clientID string, kind Kind, payload interface{}) (*Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode payload: %w", err)
+	}
+
+	now := time.Now()
+	job := &Job{
+		ID:            newJobID(),
+		ClientID:      clientID,
+		Kind:          kind,
+		Payload:       data,
+		Status:        StatusQueued,
+		MessageIDs:    []string{},
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	_, err = s.db.ExecContext(ctx, `INSERT INTO outbox_jobs (id, client_id, kind, payload, status, next_attempt_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		job.ID, job.ClientID, string(job.Kind), string(job.Payload), string(job.Status),
+		now.UnixMilli(), now.UnixMilli(), now.UnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert job: %w", err)
+	}
+	return job, nil
+}
+
+// Get returns a job by ID
+func (s *Store) Get(ctx context.Context, id string) (*Job, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+jobColumns+` FROM outbox_jobs WHERE id = ?`, id)
+	job, err := scanJob(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return job, err
+}
+
+// Heads returns the oldest queued job of every client, so each client is drained in order
+func (s *Store) Heads(ctx context.Context) ([]*Job, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+jobColumns+` FROM outbox_jobs
+		WHERE seq IN (SELECT MIN(seq) FROM outbox_jobs WHERE status = ? GROUP BY client_id)
+		ORDER BY seq`, string(StatusQueued))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []*Job
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// List returns jobs newest first, optionally filtered by status and client
+func (s *Store) List(ctx context.Context, status Status, clientID string, limit int) ([]*Job, error) {
+	query := `SELECT ` + jobColumns + ` FROM outbox_jobs WHERE 1 = 1`
+	var args []interface{}
+	if status != "" {
+		query += ` AND status = ?`
+		args = append(args, string(status))
+	}
+	if clientID != "" {
+		query += ` AND client_id = ?`
+		args = append(args, clientID)
+	}
+	query += ` ORDER BY seq DESC`
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := make([]*Job, 0)
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// MarkSending claims a queued job for sending. It returns ErrNotQueued if the job
+// was cancelled in the meantime.
+func (s *Store) MarkSending(ctx context.Context, job *Job) error {
+	now := time.Now()
+	res, err := s.db.ExecContext(ctx, `UPDATE outbox_jobs SET status = ?, attempts = attempts + 1, updated_at = ?
+		WHERE id = ? AND status = ?`,
+		string(StatusSending), now.UnixMilli(), job.ID, string(StatusQueued))
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotQueued
+	}
+	job.Status = StatusSending
+	job.Attempts++
+	job.UpdatedAt = now
+	return nil
+}
+
+// Cancel cancels a job that has not been picked up for sending yet
+func (s *Store) Cancel(ctx context.Context, id string) (*Job, error) {
+	res, err := s.db.ExecContext(ctx, `UPDATE outbox_jobs SET status = ?, updated_at = ? WHERE id = ? AND status = ?`,
+		string(StatusCancelled), time.Now().UnixMilli(), id, string(StatusQueued))
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return job, ErrNotQueued
+	}
+	return job, nil
+}
+
+// CancelClient cancels every queued job of a client and returns how many were cancelled
+func (s *Store) CancelClient(ctx context.Context, clientID string) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `UPDATE outbox_jobs SET status = ?, last_error = ?, updated_at = ? WHERE client_id = ? AND status = ?`,
+		string(StatusCancelled), "session deleted", time.Now().UnixMilli(), clientID, string(StatusQueued))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// MarkSent flags a job as delivered
+func (s *Store) MarkSent(ctx context.Context, job *Job) error {
+	now := time.Now()
+	job.Status = StatusSent
+	job.LastError = ""
+	job.SentAt = &now
+	return s.update(ctx, job)
+}
+
+// MarkRetry puts a job back in the queue after a failed attempt
+func (s *Store) MarkRetry(ctx context.Context, job *Job, sendErr error, next time.Time) error {
+	job.Status = StatusQueued
+	job.LastError = sendErr.Error()
+	job.NextAttemptAt = next
+	return s.update(ctx, job)
+}
+
+// MarkFailed flags a job as permanently failed
+func (s *Store) MarkFailed(ctx context.Context, job *Job, sendErr error) error {
+	job.Status = StatusFailed
+	job.LastError = sendErr.Error()
+	return s.update(ctx, job)
+}
+
+// AddMessageID records a WhatsApp message ID produced by a job as soon as it is sent,
+// so a retry after a partial send does not deliver the same part twice
+func (s *Store) AddMessageID(ctx context.Context, job *Job, messageID string) error {
+	job.MessageIDs = append(job.MessageIDs, messageID)
+	return s.update(ctx, job)
+}
+
+// RequeueInterrupted returns jobs left in "sending" by a previous run to the queue
+func (s *Store) RequeueInterrupted(ctx context.Context) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `UPDATE outbox_jobs SET status = ?, updated_at = ? WHERE status = ?`,
+		string(StatusQueued), time.Now().UnixMilli(), string(StatusSending))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (s *Store) update(ctx context.Context, job *Job) error {
+	job.UpdatedAt = time.Now()
+	ids, err := json.Marshal(job.MessageIDs)
+	if err != nil {
+		return err
+	}
+	var sentAt int64
+	if job.SentAt != nil {
+		sentAt = job.SentAt.UnixMilli()
+	}
+	_, err = s.db.ExecContext(ctx, `UPDATE outbox_jobs
+		SET status = ?, attempts = ?, last_error = ?, message_ids = ?, next_attempt_at = ?, updated_at = ?, sent_at = ?
+		WHERE id = ?`,
+		string(job.Status), job.Attempts, job.LastError, string(ids),
+		job.NextAttemptAt.UnixMilli(), job.UpdatedAt.UnixMilli(), sentAt, job.ID)
+	return err
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanJob(row rowScanner) (*Job, error) {
+	var (
+		job                           Job
+		kind, status, payload, msgIDs string
+		nextAttempt, created, updated int64
+		sentAt                        int64
+	)
+	err := row.Scan(&job.ID, &job.ClientID, &kind, &payload, &status, &job.Attempts, &job.LastError,
+		&msgIDs, &nextAttempt, &created, &updated, &sentAt)
+	if err != nil {
+		return nil, err
+	}
+
+	job.Kind = Kind(kind)
+	job.Status = Status(status)
+	job.Payload = json.RawMessage(payload)
+	if err := json.Unmarshal([]byte(msgIDs), &job.MessageIDs); err != nil || job.MessageIDs == nil {
+		job.MessageIDs = []string{}
+	}
+	job.NextAttemptAt = time.UnixMilli(nextAttempt)
+	job.CreatedAt = time.UnixMilli(created)
+	job.UpdatedAt = time.UnixMilli(updated)
+	if sentAt > 0 {
+		t := time.UnixMilli(sentAt)
+		job.SentAt = &t
+	}
+	return &job, nil
+}
+
+func newJobID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
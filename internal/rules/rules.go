@@ -0,0 +1,358 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Fields a condition can look at
+const (
+	FieldBody     = "body"
+	FieldSender   = "sender"   // phone number of the sender (JIDs are reduced to their number)
+	FieldChatName = "chatName" // contact or group name, when known
+)
+
+// Action types
+const (
+	ActionSetFlag = "setFlag" // set a chat flag (Flag) to true
+	ActionAddTag  = "addTag"  // add Tag to the chat
+	ActionMute    = "mute"    // stop counting the chat's messages as unread
+)
+
+// Chat flags rules can set
+const (
+	FlagHasInvoice = "hasInvoice"
+	FlagIsOTP      = "isOTP"
+)
+
+// Flags lists the chat flags rules can set
+var Flags = []string{FlagHasInvoice, FlagIsOTP}
+
+// Tags implied by the flags, so flagged chats can be listed with a tag query
+const (
+	TagInvoice = "invoice"
+	TagOTP     = "otp"
+)
+
+// FlagTags maps each flag to the tag it adds
+var FlagTags = map[string]string{
+	FlagHasInvoice: TagInvoice,
+	FlagIsOTP:      TagOTP,
+}
+
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// NormalizeTag lowercases and trims a tag and checks that it is 1-32 letters, digits, '_' or '-'
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if !tagPattern.MatchString(tag) {
+		return "", fmt.Errorf("invalid tag %q: use 1-32 letters, digits, '_' or '-'", tag)
+	}
+	return tag, nil
+}
+
+// Match modes of a rule's conditions
+const (
+	MatchAny = "any"
+	MatchAll = "all"
+)
+
+// Rule is a named classifier: when its conditions match a message, its actions apply to the chat
+type Rule struct {
+	ID         string      `json:"id"`
+	Name       string      `json:"name"`
+	Enabled    bool        `json:"enabled"`
+	Priority   int         `json:"priority"` // lower runs first
+	Match      string      `json:"match"`    // any (default) or all conditions
+	Conditions []Condition `json:"conditions"`
+	Actions    []Action    `json:"actions"`
+}
+
+// Condition matches one field by keywords (case-insensitive; any of them) or a regular expression.
+// Body and chat name keywords match as substrings, sender keywords must equal the phone number.
+type Condition struct {
+	Field    string   `json:"field"`
+	Keywords []string `json:"keywords,omitempty"`
+	Pattern  string   `json:"pattern,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// Action is applied to the chat of a matching message
+type Action struct {
+	Type string `json:"type"`
+	Flag string `json:"flag,omitempty"`
+	Tag  string `json:"tag,omitempty"`
+}
+
+// Input is what rules are evaluated against
+type Input struct {
+	Body     string `json:"body"`
+	Sender   string `json:"sender"`
+	ChatName string `json:"chatName"`
+}
+
+// Result is the combined outcome of all matching rules
+type Result struct {
+	Flags   map[string]bool `json:"flags"`
+	Tags    []string        `json:"tags"` // including the tags of the flags set
+	Mute    bool            `json:"mute"`
+	Matched []string        `json:"matched"` // names of the matching rules
+}
+
+// Flag reports whether a matching rule set flag
+func (r Result) Flag(flag string) bool {
+	return r.Flags[flag]
+}
+
+func (r *Result) addTag(tag string) {
+	if !contains(r.Tags, tag) {
+		r.Tags = append(r.Tags, tag)
+	}
+}
+
+// Validate checks a rule and compiles its patterns
+func (r *Rule) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if r.Match == "" {
+		r.Match = MatchAny
+	}
+	if r.Match != MatchAny && r.Match != MatchAll {
+		return fmt.Errorf("match must be %q or %q", MatchAny, MatchAll)
+	}
+	if len(r.Conditions) == 0 {
+		return fmt.Errorf("at least one condition is required")
+	}
+	if len(r.Actions) == 0 {
+		return fmt.Errorf("at least one action is required")
+	}
+
+	for i := range r.Conditions {
+		c := &r.Conditions[i]
+		if c.Field != FieldBody && c.Field != FieldSender && c.Field != FieldChatName {
+			return fmt.Errorf("condition %d: field must be %s, %s or %s", i+1, FieldBody, FieldSender, FieldChatName)
+		}
+		if len(c.Keywords) == 0 && c.Pattern == "" {
+			return fmt.Errorf("condition %d: keywords or pattern is required", i+1)
+		}
+		for _, k := range c.Keywords {
+			if strings.TrimSpace(k) == "" {
+				return fmt.Errorf("condition %d: empty keyword", i+1)
+			}
+		}
+		c.re = nil
+		if c.Pattern != "" {
+			re, err := regexp.Compile("(?i)" + c.Pattern)
+			if err != nil {
+				return fmt.Errorf("condition %d: invalid pattern: %v", i+1, err)
+			}
+			c.re = re
+		}
+	}
+
+	for i, a := range r.Actions {
+		switch a.Type {
+		case ActionSetFlag:
+			if !isFlag(a.Flag) {
+				return fmt.Errorf("action %d: flag must be one of %v", i+1, Flags)
+			}
+		case ActionAddTag:
+			tag, err := NormalizeTag(a.Tag)
+			if err != nil {
+				return fmt.Errorf("action %d: %v", i+1, err)
+			}
+			r.Actions[i].Tag = tag
+		case ActionMute:
+		default:
+			return fmt.Errorf("action %d: type must be %s, %s or %s", i+1, ActionSetFlag, ActionAddTag, ActionMute)
+		}
+	}
+	return nil
+}
+
+// matches reports whether the rule's conditions hold for in
+func (r *Rule) matches(in Input) bool {
+	for _, c := range r.Conditions {
+		ok := c.matches(in)
+		if ok && r.Match != MatchAll {
+			return true
+		}
+		if !ok && r.Match == MatchAll {
+			return false
+		}
+	}
+	return r.Match == MatchAll
+}
+
+func (c *Condition) matches(in Input) bool {
+	var value string
+	switch c.Field {
+	case FieldBody:
+		value = in.Body
+	case FieldSender:
+		value = senderNumber(in.Sender)
+	case FieldChatName:
+		value = in.ChatName
+	}
+	if value == "" {
+		return false
+	}
+
+	lower := strings.ToLower(value)
+	for _, k := range c.Keywords {
+		k = strings.ToLower(strings.TrimSpace(k))
+		if c.Field == FieldSender {
+			if strings.TrimPrefix(k, "+") == lower {
+				return true
+			}
+		} else if strings.Contains(lower, k) {
+			return true
+		}
+	}
+	return c.re != nil && c.re.MatchString(value)
+}
+
+// senderNumber reduces a JID ("62812...:3@s.whatsapp.net") to its user part
+func senderNumber(sender string) string {
+	if i := strings.IndexByte(sender, '@'); i >= 0 {
+		sender = sender[:i]
+	}
+	if i := strings.IndexByte(sender, ':'); i >= 0 {
+		sender = sender[:i]
+	}
+	return sender
+}
+
+func isFlag(flag string) bool {
+	for _, f := range Flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// Engine evaluates the enabled rules. It keeps them in memory and reloads them after every change.
+type Engine struct {
+	store Store
+
+	mu    sync.RWMutex
+	rules []*Rule
+}
+
+// NewEngine creates an engine and loads its rules
+func NewEngine(store Store) (*Engine, error) {
+	e := &Engine{store: store}
+	if err := e.reload(context.Background()); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Evaluate applies the enabled rules to in. A nil engine matches nothing.
+func (e *Engine) Evaluate(in Input) Result {
+	result := Result{Flags: map[string]bool{}, Tags: []string{}, Matched: []string{}}
+	if e == nil {
+		return result
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for _, r := range e.rules {
+		if !r.Enabled || !r.matches(in) {
+			continue
+		}
+		result.Matched = append(result.Matched, r.Name)
+		for _, a := range r.Actions {
+			switch a.Type {
+			case ActionSetFlag:
+				result.Flags[a.Flag] = true
+				result.addTag(FlagTags[a.Flag])
+			case ActionAddTag:
+				result.addTag(a.Tag)
+			case ActionMute:
+				result.Mute = true
+			}
+		}
+	}
+	return result
+}
+
+// List returns all rules in evaluation order
+func (e *Engine) List() []Rule {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	list := make([]Rule, 0, len(e.rules))
+	for _, r := range e.rules {
+		list = append(list, *r)
+	}
+	return list
+}
+
+// Get returns a rule by ID
+func (e *Engine) Get(ctx context.Context, id string) (*Rule, error) {
+	return e.store.Get(ctx, id)
+}
+
+// Create validates and stores a new rule
+func (e *Engine) Create(ctx context.Context, r *Rule) error {
+	if err := r.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if err := e.store.Create(ctx, r); err != nil {
+		return err
+	}
+	return e.reload(ctx)
+}
+
+// Update validates and saves a rule
+func (e *Engine) Update(ctx context.Context, r *Rule) error {
+	if err := r.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if err := e.store.Update(ctx, r); err != nil {
+		return err
+	}
+	return e.reload(ctx)
+}
+
+// Delete removes a rule
+func (e *Engine) Delete(ctx context.Context, id string) error {
+	if err := e.store.Delete(ctx, id); err != nil {
+		return err
+	}
+	return e.reload(ctx)
+}
+
+func (e *Engine) reload(ctx context.Context) error {
+	rules, err := e.store.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load rules: %w", err)
+	}
+	for _, r := range rules {
+		if err := r.Validate(); err != nil {
+			return fmt.Errorf("rule %s (%s): %w", r.ID, r.Name, err)
+		}
+	}
+	sortByPriority(rules)
+
+	e.mu.Lock()
+	e.rules = rules
+	e.mu.Unlock()
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
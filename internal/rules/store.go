@@ -0,0 +1,179 @@
+package rules
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"sync"
+)
+
+var (
+	// ErrNotFound is returned when a rule does not exist
+	ErrNotFound = errors.New("rule not found")
+	// ErrInvalid is returned when a rule fails validation
+	ErrInvalid = errors.New("invalid rule")
+)
+
+// Store persists the rules. The server keeps them in Firestore (see firestore.RulesRepository).
+type Store interface {
+	// Create stores a new rule and sets its ID
+	Create(ctx context.Context, r *Rule) error
+	// Update saves an existing rule, or returns ErrNotFound
+	Update(ctx context.Context, r *Rule) error
+	// Delete removes a rule, or returns ErrNotFound
+	Delete(ctx context.Context, id string) error
+	// Get returns a rule by ID, or ErrNotFound
+	Get(ctx context.Context, id string) (*Rule, error)
+	// List returns all rules by priority, then creation order
+	List(ctx context.Context) ([]*Rule, error)
+}
+
+// defaults are the classifiers that used to be hard-coded. Stores seed them once and they
+// can be edited or deleted like any other rule afterwards.
+var defaults = []Rule{
+	{
+		Name:     "Invoice",
+		Enabled:  true,
+		Priority: 10,
+		Match:    MatchAny,
+		Conditions: []Condition{
+			{Field: FieldBody, Keywords: []string{"inv-", "invoice", "tagihan"}},
+		},
+		Actions: []Action{{Type: ActionSetFlag, Flag: FlagHasInvoice}},
+	},
+	{
+		Name:     "OTP",
+		Enabled:  true,
+		Priority: 20,
+		Match:    MatchAny,
+		Conditions: []Condition{
+			{Field: FieldBody, Keywords: []string{"otp", "kode verifikasi", "verification code"}},
+		},
+		Actions: []Action{{Type: ActionSetFlag, Flag: FlagIsOTP}},
+	},
+	{
+		Name:     "OTP senders",
+		Enabled:  true,
+		Priority: 30,
+		Match:    MatchAny,
+		Conditions: []Condition{
+			{Field: FieldSender, Keywords: []string{"628999800123"}},
+			{Field: FieldChatName, Keywords: []string{"stockbit", "tri indonesia"}},
+		},
+		Actions: []Action{{Type: ActionSetFlag, Flag: FlagIsOTP}},
+	},
+}
+
+// Defaults returns copies of the rules a new store is seeded with
+func Defaults() []Rule {
+	list := make([]Rule, len(defaults))
+	for i, r := range defaults {
+		list[i] = r.clone()
+	}
+	return list
+}
+
+// MemoryStore keeps the rules in memory. It is used when Firestore is unavailable, so changes
+// are lost on restart.
+type MemoryStore struct {
+	mu    sync.Mutex
+	rules []*Rule // creation order
+}
+
+// NewMemoryStore creates a store seeded with the default rules
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{}
+	for _, r := range Defaults() {
+		r := r
+		_ = s.Create(context.Background(), &r)
+	}
+	return s
+}
+
+// Create stores a new rule
+func (s *MemoryStore) Create(ctx context.Context, r *Rule) error {
+	r.ID = NewID()
+	stored := r.clone()
+	s.mu.Lock()
+	s.rules = append(s.rules, &stored)
+	s.mu.Unlock()
+	return nil
+}
+
+// Update saves an existing rule
+func (s *MemoryStore) Update(ctx context.Context, r *Rule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, existing := range s.rules {
+		if existing.ID == r.ID {
+			stored := r.clone()
+			s.rules[i] = &stored
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// Delete removes a rule
+func (s *MemoryStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, existing := range s.rules {
+		if existing.ID == id {
+			s.rules = append(s.rules[:i], s.rules[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// Get returns a rule by ID
+func (s *MemoryStore) Get(ctx context.Context, id string) (*Rule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.rules {
+		if existing.ID == id {
+			r := existing.clone()
+			return &r, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// List returns all rules by priority, then creation order
+func (s *MemoryStore) List(ctx context.Context) ([]*Rule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]*Rule, 0, len(s.rules))
+	for _, existing := range s.rules {
+		r := existing.clone()
+		list = append(list, &r)
+	}
+	sortByPriority(list)
+	return list, nil
+}
+
+// NewID returns a random rule ID
+func NewID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// clone copies r, including its condition and action slices
+func (r Rule) clone() Rule {
+	c := r
+	c.Conditions = make([]Condition, len(r.Conditions))
+	for i, cond := range r.Conditions {
+		cond.Keywords = append([]string(nil), cond.Keywords...)
+		c.Conditions[i] = cond
+	}
+	c.Actions = append([]Action(nil), r.Actions...)
+	return c
+}
+
+func sortByPriority(rules []*Rule) {
+	sort.SliceStable(rules, func(i, j int) bool { return rules[i].Priority < rules[j].Priority })
+}
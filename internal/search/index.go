@@ -0,0 +1,241 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+	"unicode"
+
+	"wa-server-go/internal/firestore"
+)
+
+// ErrEmptyQuery is returned when the search text has no words to look for
+var ErrEmptyQuery = errors.New("search text has no words")
+
+// Index is a full-text index of message bodies kept in SQLite (FTS5). Firestore stays the
+// source of truth; the index only holds what is needed to find a message and show a snippet.
+type Index struct {
+	db *sql.DB
+}
+
+// search_messages holds one row per message and session; the FTS5 table indexes its
+// bodies and is kept in step by triggers
+const schema = `
+CREATE TABLE IF NOT EXISTS search_messages (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	session    TEXT NOT NULL,
+	message_id TEXT NOT NULL,
+	chat_id    TEXT NOT NULL,
+	sender     TEXT NOT NULL DEFAULT '',
+	type       TEXT NOT NULL DEFAULT '',
+	from_me    INTEGER NOT NULL DEFAULT 0,
+	timestamp  INTEGER NOT NULL, -- unix microseconds, Firestore's precision
+	body       TEXT NOT NULL,
+	UNIQUE (session, message_id)
+);
+CREATE INDEX IF NOT EXISTS idx_search_messages_chat ON search_messages(session, chat_id, timestamp);
+CREATE VIRTUAL TABLE IF NOT EXISTS search_messages_fts USING fts5(
+	body,
+	content = 'search_messages',
+	content_rowid = 'id',
+	tokenize = 'unicode61 remove_diacritics 2'
+);
+CREATE TRIGGER IF NOT EXISTS search_messages_ai AFTER INSERT ON search_messages BEGIN
+	INSERT INTO search_messages_fts (rowid, body) VALUES (new.id, new.body);
+END;
+CREATE TRIGGER IF NOT EXISTS search_messages_ad AFTER DELETE ON search_messages BEGIN
+	INSERT INTO search_messages_fts (search_messages_fts, rowid, body) VALUES ('delete', old.id, old.body);
+END;
+CREATE TRIGGER IF NOT EXISTS search_messages_au AFTER UPDATE ON search_messages BEGIN
+	INSERT INTO search_messages_fts (search_messages_fts, rowid, body) VALUES ('delete', old.id, old.body);
+	INSERT INTO search_messages_fts (rowid, body) VALUES (new.id, new.body);
+END;
+`
+
+// NewIndex creates the search tables if needed
+func NewIndex(db *sql.DB) (*Index, error) {
+	if _, err := db.Exec(schema); err != nil {
+		return nil, fmt.Errorf("failed to create search schema: %w", err)
+	}
+	return &Index{db: db}, nil
+}
+
+// IndexMessage adds or updates a message. Session is "" for the default session, like
+// the chat repository's collections. Messages without text are skipped.
+func (x *Index) IndexMessage(session string, msg *firestore.WAMessage) {
+	if strings.TrimSpace(msg.Body) == "" {
+		return
+	}
+	if err := x.Add(context.Background(), session, msg); err != nil {
+		fmt.Printf("⚠️ Failed to index message %s: %v\n", msg.MessageID, err)
+	}
+}
+
+// Add adds or updates a message in the index
+func (x *Index) Add(ctx context.Context, session string, msg *firestore.WAMessage) error {
+	return add(ctx, x.db, session, msg)
+}
+
+// reindexBatch is how many messages Reindex writes per transaction
+const reindexBatch = 500
+
+// Reindex adds every message stored in repo, the chat repository of session, e.g. the
+// messages saved before the index existed. Indexed messages are updated in place, so it can
+// be run again at any time. It returns how many messages were indexed.
+func (x *Index) Reindex(ctx context.Context, session string, repo *firestore.ChatsRepository) (int, error) {
+	indexed := 0
+	batch := make([]*firestore.WAMessage, 0, reindexBatch)
+	err := repo.EachMessage(ctx, func(msg *firestore.WAMessage) error {
+		if strings.TrimSpace(msg.Body) == "" {
+			return nil
+		}
+		if batch = append(batch, msg); len(batch) < reindexBatch {
+			return nil
+		}
+		err := x.addAll(ctx, session, batch)
+		if err == nil {
+			indexed += len(batch)
+		}
+		batch = batch[:0]
+		return err
+	})
+	if err == nil {
+		if err = x.addAll(ctx, session, batch); err == nil {
+			indexed += len(batch)
+		}
+	}
+	return indexed, err
+}
+
+// addAll adds messages in one transaction. They are collected first, so the database is
+// not locked while Firestore is read.
+func (x *Index) addAll(ctx context.Context, session string, messages []*firestore.WAMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	tx, err := x.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, msg := range messages {
+		if err := add(ctx, tx, session, msg); err != nil {
+			return fmt.Errorf("failed to index message %s: %w", msg.MessageID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// execer is a *sql.DB or *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func add(ctx context.Context, db execer, session string, msg *firestore.WAMessage) error {
+	_, err := db.ExecContext(ctx, `INSERT INTO search_messages (session, message_id, chat_id, sender, type, from_me, timestamp, body)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (session, message_id) DO UPDATE SET body = excluded.body, type = excluded.type, timestamp = excluded.timestamp
+		WHERE search_messages.body <> excluded.body OR search_messages.type <> excluded.type OR search_messages.timestamp <> excluded.timestamp`,
+		session, msg.MessageID, msg.ChatID, msg.From, msg.Type, msg.FromMe, msg.Timestamp.UnixMicro(), msg.Body)
+	return err
+}
+
+// Query selects messages to search for
+type Query struct {
+	Session string // "" for the default session
+	Text    string // words to find, in any order; the last letters of a word may be missing
+	ChatID  string
+	From    time.Time // zero: no lower bound
+	To      time.Time // zero: no upper bound (exclusive)
+	Type    string    // text, image, document, ...
+	Limit   int
+}
+
+// Result is a message matching a query
+type Result struct {
+	MessageID string    `json:"messageId"`
+	ChatID    string    `json:"chatId"`
+	Sender    string    `json:"sender"`
+	Type      string    `json:"type"`
+	FromMe    bool      `json:"fromMe"`
+	Timestamp time.Time `json:"timestamp"`
+	Snippet   string    `json:"snippet"` // HTML-escaped text around the matches, which are wrapped in <mark>
+}
+
+// Search returns the best matches for q, most relevant first
+func (x *Index) Search(ctx context.Context, q Query) ([]Result, error) {
+	match := matchExpr(q.Text)
+	if match == "" {
+		return nil, ErrEmptyQuery
+	}
+
+	// char(2) and char(3) mark the matches until the snippet is escaped
+	query := `SELECT m.message_id, m.chat_id, m.sender, m.type, m.from_me, m.timestamp,
+			snippet(search_messages_fts, 0, char(2), char(3), '…', 16)
+		FROM search_messages_fts f
+		JOIN search_messages m ON m.id = f.rowid
+		WHERE search_messages_fts MATCH ? AND m.session = ?`
+	args := []interface{}{match, q.Session}
+	if q.ChatID != "" {
+		query += ` AND m.chat_id = ?`
+		args = append(args, q.ChatID)
+	}
+	if !q.From.IsZero() {
+		query += ` AND m.timestamp >= ?`
+		args = append(args, q.From.UnixMicro())
+	}
+	if !q.To.IsZero() {
+		query += ` AND m.timestamp < ?`
+		args = append(args, q.To.UnixMicro())
+	}
+	if q.Type != "" {
+		query += ` AND m.type = ?`
+		args = append(args, q.Type)
+	}
+	query += ` ORDER BY f.rank, m.timestamp DESC LIMIT ?`
+	args = append(args, q.Limit)
+
+	rows, err := x.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []Result{}
+	for rows.Next() {
+		var r Result
+		var ts int64
+		if err := rows.Scan(&r.MessageID, &r.ChatID, &r.Sender, &r.Type, &r.FromMe, &ts, &r.Snippet); err != nil {
+			return nil, err
+		}
+		r.Timestamp = time.UnixMicro(ts)
+		r.Snippet = highlight(r.Snippet)
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// matchExpr turns search text into an FTS5 query: every word is quoted (so "INV-2024-031"
+// is searched as written rather than parsed as query syntax) and may be a prefix
+func matchExpr(text string) string {
+	var terms []string
+	for _, word := range strings.Fields(text) {
+		if strings.IndexFunc(word, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) < 0 {
+			continue // punctuation only: nothing the tokenizer would index
+		}
+		terms = append(terms, `"`+strings.ReplaceAll(word, `"`, `""`)+`"*`)
+	}
+	return strings.Join(terms, " ")
+}
+
+// highlight escapes a snippet and turns the match markers into <mark> tags
+func highlight(snippet string) string {
+	snippet = html.EscapeString(snippet)
+	snippet = strings.ReplaceAll(snippet, "\x02", "<mark>")
+	return strings.ReplaceAll(snippet, "\x03", "</mark>")
+}
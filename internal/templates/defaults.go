@@ -0,0 +1,416 @@
+package templates
+
+// Names of the built-in templates
+const (
+	TemplateInvoiceUnpaid   = "invoice_unpaid"
+	TemplateInvoicePaid     = "invoice_paid"
+	TemplateInvoiceOverdue  = "invoice_overdue"
+	TemplateInvoicePartial  = "invoice_partial"
+	TemplateInvoiceDraft    = "invoice_draft"
+	TemplateInvoiceReminder = "invoice_reminder"
+	TemplateOTP             = "otp"
+
+	// Follow-ups of invoice_overdue sent by the reminder scheduler
+	TemplateInvoiceOverdueFollowUp = "invoice_overdue_followup"
+	TemplateInvoiceOverdueFinal    = "invoice_overdue_final"
+)
+
+// invoiceVariables are the variables of the invoice templates (see InvoiceTemplateData)
+var invoiceVariables = []Variable{
+	{Name: "clientName", Description: "Client name", Required: true, Example: "PT Contoh Jaya"},
+	{Name: "invoiceNumber", Description: "Invoice number", Required: true, Example: "INV-2026-001"},
+	{Name: "dueDate", Description: "Due date, already formatted", Example: "30 Oktober 2026"},
+	{Name: "status", Description: "Displayed status", Example: "Belum Lunas"},
+	{Name: "remainingAmount", Description: "Amount due, already formatted", Example: "Rp 1.500.000"},
+}
+
+// overdueVariables are the variables of the overdue follow-up templates
+var overdueVariables = append(append([]Variable{}, invoiceVariables...),
+	Variable{Name: "daysOverdue", Description: "Days since the due date", Required: true, Example: "7"})
+
+// builtins are the default templates. They apply until a template is saved through the API,
+// and "rollback to version 0" restores them.
+var builtins = []Template{
+	{
+		Name:        TemplateInvoiceUnpaid,
+		Locale:      LocaleID,
+		Description: "Invoice sent with its PDF (unpaid or unknown status)",
+		Variables:   invoiceVariables,
+		Body: `Yth. {{.clientName}},
+
+Terlampir dokumen tagihan dari *{{.companyName}}*.
+📄 No. Invoice: {{.invoiceNumber}}
+📅 Jatuh Tempo: {{.dueDate}}
+📊 Status: {{.status}}
+💰 Sisa Tagihan: {{.remainingAmount}}
+Mohon segera diselesaikan. Terima kasih atas kepercayaan Anda.
+
+━━━━━━━━━━━━━━━━━━━
+🤖 _Pesan ini dikirim secara otomatis oleh {{.companyName}} System_
+
+📞 Info lebih lanjut hubungi:
+🌐 {{.companyWebsite}}
+📧 {{.companyEmail}}
+📱 {{.companyPhone}}`,
+	},
+	{
+		Name:        TemplateInvoiceUnpaid,
+		Locale:      LocaleEN,
+		Description: "Invoice sent with its PDF (unpaid or unknown status)",
+		Variables:   invoiceVariables,
+		Body: `Dear {{.clientName}},
+
+Please find attached the invoice from *{{.companyName}}*.
+📄 Invoice No.: {{.invoiceNumber}}
+📅 Due Date: {{.dueDate}}
+📊 Status: {{.status}}
+💰 Amount Due: {{.remainingAmount}}
+We kindly ask you to settle it on time. Thank you for your trust.
+
+━━━━━━━━━━━━━━━━━━━
+🤖 _This message was sent automatically by {{.companyName}} System_
+
+📞 For more information:
+🌐 {{.companyWebsite}}
+📧 {{.companyEmail}}
+📱 {{.companyPhone}}`,
+	},
+	{
+		Name:        TemplateInvoicePaid,
+		Locale:      LocaleID,
+		Description: "Payment confirmation",
+		Variables:   invoiceVariables,
+		Body: `Yth. {{.clientName}},
+
+✅ *PEMBAYARAN BERHASIL DIKONFIRMASI*
+
+Terima kasih! Pembayaran untuk Invoice *{{.invoiceNumber}}* telah berhasil kami terima.
+📋 Status: *LUNAS* ✅
+📅 Tanggal: {{.today}}
+Dokumen lunas terlampir sebagai arsip Anda. Terima kasih atas kepercayaan Anda memilih {{.companyName}}!
+
+━━━━━━━━━━━━━━━━━━━
+🤖 _Pesan otomatis dari {{.companyName}} System_
+
+🌐 Kunjungi: {{.companyWebsite}}
+💼 Layanan lainnya tersedia di website kami`,
+	},
+	{
+		Name:        TemplateInvoicePaid,
+		Locale:      LocaleEN,
+		Description: "Payment confirmation",
+		Variables:   invoiceVariables,
+		Body: `Dear {{.clientName}},
+
+✅ *PAYMENT CONFIRMED*
+
+Thank you! We have received the payment for Invoice *{{.invoiceNumber}}*.
+📋 Status: *PAID* ✅
+📅 Date: {{.today}}
+The paid invoice is attached for your records. Thank you for choosing {{.companyName}}!
+
+━━━━━━━━━━━━━━━━━━━
+🤖 _Automated message from {{.companyName}} System_
+
+🌐 Visit: {{.companyWebsite}}
+💼 More services are available on our website`,
+	},
+	{
+		Name:        TemplateInvoiceOverdue,
+		Locale:      LocaleID,
+		Description: "Invoice past its due date",
+		Variables:   invoiceVariables,
+		Body: `🚨 *TAGIHAN MELEWATI JATUH TEMPO* 🚨
+
+Yth. {{.clientName}},
+
+Kami ingin mengingatkan bahwa invoice berikut telah melewati tanggal jatuh tempo:
+📄 No. Invoice: *{{.invoiceNumber}}*
+📅 Jatuh Tempo: {{.dueDate}} ❌
+💰 Sisa Tagihan: *{{.remainingAmount}}*
+⚠️ Status: *TERLAMBAT*
+
+*Mohon segera lakukan pembayaran* untuk menyelesaikan tagihan ini.
+
+Jika pembayaran sudah dilakukan, mohon konfirmasi dengan mengirimkan bukti transfer.
+
+Jika ada kendala, silakan hubungi kami untuk diskusi solusi pembayaran.
+
+━━━━━━━━━━━━━━━━━━━
+🤖 _Pesan otomatis dari {{.companyName}} System_
+
+📞 Pertanyaan? Hubungi kami:
+🌐 {{.companyWebsite}}
+📧 {{.companyEmail}}
+📱 {{.companyPhone}}
+
+_Terima kasih atas perhatian dan kerjasamanya._`,
+	},
+	{
+		Name:        TemplateInvoiceOverdue,
+		Locale:      LocaleEN,
+		Description: "Invoice past its due date",
+		Variables:   invoiceVariables,
+		Body: `🚨 *INVOICE OVERDUE* 🚨
+
+Dear {{.clientName}},
+
+This is a reminder that the following invoice is past its due date:
+📄 Invoice No.: *{{.invoiceNumber}}*
+📅 Due Date: {{.dueDate}} ❌
+💰 Amount Due: *{{.remainingAmount}}*
+⚠️ Status: *OVERDUE*
+
+*Please make the payment as soon as possible* to settle this invoice.
+
+If you have already paid, please confirm by sending us the transfer receipt.
+
+If you are facing any difficulties, please contact us to discuss a payment arrangement.
+
+━━━━━━━━━━━━━━━━━━━
+🤖 _Automated message from {{.companyName}} System_
+
+📞 Questions? Contact us:
+🌐 {{.companyWebsite}}
+📧 {{.companyEmail}}
+📱 {{.companyPhone}}
+
+_Thank you for your attention and cooperation._`,
+	},
+	{
+		Name:        TemplateInvoicePartial,
+		Locale:      LocaleID,
+		Description: "Partial payment received",
+		Variables:   invoiceVariables,
+		Body: `Yth. {{.clientName}},
+
+💳 *PEMBAYARAN SEBAGIAN DITERIMA*
+
+Terima kasih atas pembayaran sebagian yang telah kami terima.
+📄 No. Invoice: {{.invoiceNumber}}
+📅 Jatuh Tempo: {{.dueDate}}
+📊 Status: {{.status}}
+💰 Sisa Tagihan: {{.remainingAmount}}
+Mohon segera melunasi sisa tagihan sebelum tanggal jatuh tempo.
+
+━━━━━━━━━━━━━━━━━━━
+🤖 _Pesan otomatis dari {{.companyName}} System_
+
+🌐 Info: {{.companyWebsite}}
+📧 {{.companyEmail}}`,
+	},
+	{
+		Name:        TemplateInvoicePartial,
+		Locale:      LocaleEN,
+		Description: "Partial payment received",
+		Variables:   invoiceVariables,
+		Body: `Dear {{.clientName}},
+
+💳 *PARTIAL PAYMENT RECEIVED*
+
+Thank you for the partial payment we have received.
+📄 Invoice No.: {{.invoiceNumber}}
+📅 Due Date: {{.dueDate}}
+📊 Status: {{.status}}
+💰 Amount Due: {{.remainingAmount}}
+Please settle the remaining balance before the due date.
+
+━━━━━━━━━━━━━━━━━━━
+🤖 _Automated message from {{.companyName}} System_
+
+🌐 Info: {{.companyWebsite}}
+📧 {{.companyEmail}}`,
+	},
+	{
+		Name:        TemplateInvoiceDraft,
+		Locale:      LocaleID,
+		Description: "Draft invoice for review",
+		Variables:   invoiceVariables,
+		Body: `Yth. {{.clientName}},
+
+📋 *DRAFT INVOICE*
+
+Berikut draft invoice untuk direview.
+📄 No. Invoice: {{.invoiceNumber}}
+📅 Jatuh Tempo: {{.dueDate}}
+📊 Status: {{.status}}
+💰 Total: {{.remainingAmount}}
+Mohon konfirmasinya apabila sudah sesuai.
+
+━━━━━━━━━━━━━━━━━━━
+🤖 _Pesan otomatis dari {{.companyName}} System_
+
+🌐 {{.companyWebsite}}
+📧 {{.companyEmail}}`,
+	},
+	{
+		Name:        TemplateInvoiceDraft,
+		Locale:      LocaleEN,
+		Description: "Draft invoice for review",
+		Variables:   invoiceVariables,
+		Body: `Dear {{.clientName}},
+
+📋 *DRAFT INVOICE*
+
+Please review the following draft invoice.
+📄 Invoice No.: {{.invoiceNumber}}
+📅 Due Date: {{.dueDate}}
+📊 Status: {{.status}}
+💰 Total: {{.remainingAmount}}
+Kindly let us know if everything is in order.
+
+━━━━━━━━━━━━━━━━━━━
+🤖 _Automated message from {{.companyName}} System_
+
+🌐 {{.companyWebsite}}
+📧 {{.companyEmail}}`,
+	},
+	{
+		Name:        TemplateInvoiceReminder,
+		Locale:      LocaleID,
+		Description: "Reminder the day before the due date",
+		Variables:   invoiceVariables,
+		Body: `🔔 *REMINDER PEMBAYARAN* 🔔
+
+Yth. {{.clientName}},
+Mengingatkan kembali bahwa Invoice *{{.invoiceNumber}}* akan jatuh tempo besok ({{.dueDate}}).
+
+💰 Sisa Tagihan: {{.remainingAmount}}
+Mohon segera dilakukan pembayaran. Abaikan pesan ini jika sudah membayar.
+
+━━━━━━━━━━━━━━━━━━━
+🤖 _Pesan otomatis dari {{.companyName}} System_
+
+🌐 {{.companyWebsite}}
+📱 Butuh bantuan? {{.companyPhone}}`,
+	},
+	{
+		Name:        TemplateInvoiceReminder,
+		Locale:      LocaleEN,
+		Description: "Reminder the day before the due date",
+		Variables:   invoiceVariables,
+		Body: `🔔 *PAYMENT REMINDER* 🔔
+
+Dear {{.clientName}},
+This is a friendly reminder that Invoice *{{.invoiceNumber}}* is due tomorrow ({{.dueDate}}).
+
+💰 Amount Due: {{.remainingAmount}}
+Please make the payment on time. Kindly ignore this message if you have already paid.
+
+━━━━━━━━━━━━━━━━━━━
+🤖 _Automated message from {{.companyName}} System_
+
+🌐 {{.companyWebsite}}
+📱 Need help? {{.companyPhone}}`,
+	},
+	{
+		Name:        TemplateInvoiceOverdueFollowUp,
+		Locale:      LocaleID,
+		Description: "Follow-up while the invoice stays overdue",
+		Variables:   overdueVariables,
+		Body: `⚠️ *PENGINGAT KEDUA: TAGIHAN BELUM DIBAYAR*
+
+Yth. {{.clientName}},
+
+Invoice *{{.invoiceNumber}}* telah melewati jatuh tempo selama *{{.daysOverdue}} hari* dan hingga saat ini kami belum menerima pembayarannya.
+📅 Jatuh Tempo: {{.dueDate}}
+💰 Sisa Tagihan: *{{.remainingAmount}}*
+
+Mohon segera lakukan pembayaran atau hubungi kami apabila ada kendala. Abaikan pesan ini jika pembayaran sudah dilakukan.
+
+━━━━━━━━━━━━━━━━━━━
+🤖 _Pesan otomatis dari {{.companyName}} System_
+
+📧 {{.companyEmail}}
+📱 {{.companyPhone}}`,
+	},
+	{
+		Name:        TemplateInvoiceOverdueFollowUp,
+		Locale:      LocaleEN,
+		Description: "Follow-up while the invoice stays overdue",
+		Variables:   overdueVariables,
+		Body: `⚠️ *SECOND REMINDER: INVOICE UNPAID*
+
+Dear {{.clientName}},
+
+Invoice *{{.invoiceNumber}}* has been overdue for *{{.daysOverdue}} days* and we have not received the payment yet.
+📅 Due Date: {{.dueDate}}
+💰 Amount Due: *{{.remainingAmount}}*
+
+Please make the payment as soon as possible or contact us if you are facing any difficulties. Kindly ignore this message if you have already paid.
+
+━━━━━━━━━━━━━━━━━━━
+🤖 _Automated message from {{.companyName}} System_
+
+📧 {{.companyEmail}}
+📱 {{.companyPhone}}`,
+	},
+	{
+		Name:        TemplateInvoiceOverdueFinal,
+		Locale:      LocaleID,
+		Description: "Last reminder of an overdue invoice",
+		Variables:   overdueVariables,
+		Body: `🚨 *PEMBERITAHUAN TERAKHIR* 🚨
+
+Yth. {{.clientName}},
+
+Invoice *{{.invoiceNumber}}* telah melewati jatuh tempo selama *{{.daysOverdue}} hari*.
+📅 Jatuh Tempo: {{.dueDate}}
+💰 Sisa Tagihan: *{{.remainingAmount}}*
+
+Ini adalah pengingat otomatis terakhir dari kami. *Mohon segera selesaikan pembayaran* atau hubungi kami hari ini untuk membicarakan solusi pembayaran, agar layanan Anda tidak terganggu.
+
+━━━━━━━━━━━━━━━━━━━
+🤖 _Pesan otomatis dari {{.companyName}} System_
+
+🌐 {{.companyWebsite}}
+📧 {{.companyEmail}}
+📱 {{.companyPhone}}`,
+	},
+	{
+		Name:        TemplateInvoiceOverdueFinal,
+		Locale:      LocaleEN,
+		Description: "Last reminder of an overdue invoice",
+		Variables:   overdueVariables,
+		Body: `🚨 *FINAL NOTICE* 🚨
+
+Dear {{.clientName}},
+
+Invoice *{{.invoiceNumber}}* has been overdue for *{{.daysOverdue}} days*.
+📅 Due Date: {{.dueDate}}
+💰 Amount Due: *{{.remainingAmount}}*
+
+This is our last automated reminder. *Please settle the payment as soon as possible* or contact us today to discuss a payment arrangement, so your service is not interrupted.
+
+━━━━━━━━━━━━━━━━━━━
+🤖 _Automated message from {{.companyName}} System_
+
+🌐 {{.companyWebsite}}
+📧 {{.companyEmail}}
+📱 {{.companyPhone}}`,
+	},
+	{
+		Name:        TemplateOTP,
+		Locale:      LocaleID,
+		Description: "Login one-time code",
+		Variables:   []Variable{{Name: "otp", Description: "One-time login code", Required: true, Example: "123456"}},
+		Body: `🔐 *Kode Login {{.companyName}}*
+
+Kode OTP Anda: *{{.otp}}*
+
+Jangan berikan kode ini kepada siapapun.
+Berlaku 5 menit.`,
+	},
+	{
+		Name:        TemplateOTP,
+		Locale:      LocaleEN,
+		Description: "Login one-time code",
+		Variables:   []Variable{{Name: "otp", Description: "One-time login code", Required: true, Example: "123456"}},
+		Body: `🔐 *{{.companyName}} Login Code*
+
+Your OTP code: *{{.otp}}*
+
+Do not share this code with anyone.
+Valid for 5 minutes.`,
+	},
+}
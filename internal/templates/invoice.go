@@ -2,73 +2,79 @@ package templates
 
 import (
 	"fmt"
+	"strings"
 	"time"
 )
 
 // InvoiceTemplateData holds data for invoice message templates
 type InvoiceTemplateData struct {
-	ClientName      string
-	InvoiceNumber   string
-	DueDate         string
-	Status          string // Display status (e.g., "Belum Lunas")
-	StatusKey       string // Raw key (e.g., "unpaid", "overdue")
-	RemainingAmount string // Formatted amount (e.g., "Rp 1.000.000")
+	ClientName      string `json:"clientName"`
+	InvoiceNumber   string `json:"invoiceNumber"`
+	DueDate         string `json:"dueDate"`
+	Status          string `json:"status,omitempty"`    // Display status (e.g., "Belum Lunas")
+	StatusKey       string `json:"statusKey,omitempty"` // Raw key (e.g., "unpaid", "overdue")
+	RemainingAmount string `json:"remainingAmount"`     // Formatted amount (e.g., "Rp 1.000.000")
 }
 
-// GenerateInvoiceMessage generates a WhatsApp message based on invoice status
-func GenerateInvoiceMessage(data InvoiceTemplateData) string {
-	var header, body, footer string
-
-	switch data.StatusKey {
+// InvoiceStatusLabel returns the display status for a status key ("unpaid" for unknown keys)
+func InvoiceStatusLabel(statusKey string) string {
+	switch statusKey {
 	case "paid":
-		header = fmt.Sprintf("Yth. %s,\n\n✅ *PEMBAYARAN BERHASIL DIKONFIRMASI*\n\nTerima kasih! Pembayaran untuk Invoice *%s* telah berhasil kami terima.",
-			data.ClientName, data.InvoiceNumber)
-		body = fmt.Sprintf("\n📋 Status: *LUNAS* ✅\n📅 Tanggal: %s",
-			time.Now().Format("02 January 2006"))
-		footer = "\nDokumen lunas terlampir sebagai arsip Anda. Terima kasih atas kepercayaan Anda memilih Valpro Intertech!\n\n━━━━━━━━━━━━━━━━━━━\n🤖 _Pesan otomatis dari Valpro Intertech System_\n\n🌐 Kunjungi: valprointertech.com\n💼 Layanan lainnya tersedia di website kami"
-
+		return "Lunas"
 	case "overdue":
-		header = fmt.Sprintf("🚨 *TAGIHAN MELEWATI JATUH TEMPO* 🚨\n\nYth. %s,\n\nKami ingin mengingatkan bahwa invoice berikut telah melewati tanggal jatuh tempo:",
-			data.ClientName)
-		body = fmt.Sprintf("\n📄 No. Invoice: *%s*\n📅 Jatuh Tempo: %s ❌\n💰 Sisa Tagihan: *%s*\n⚠️ Status: *TERLAMBAT*",
-			data.InvoiceNumber, data.DueDate, data.RemainingAmount)
-		footer = "\n\n*Mohon segera lakukan pembayaran* untuk menyelesaikan tagihan ini.\n\nJika pembayaran sudah dilakukan, mohon konfirmasi dengan mengirimkan bukti transfer.\n\nJika ada kendala, silakan hubungi kami untuk diskusi solusi pembayaran.\n\n━━━━━━━━━━━━━━━━━━━\n🤖 _Pesan otomatis dari Valpro Intertech System_\n\n📞 Pertanyaan? Hubungi kami:\n🌐 valprointertech.com\n📧 mail@valprointertech.com\n📱 +62 813-9971-0085\n\n_Terima kasih atas perhatian dan kerjasamanya._"
-
+		return "Terlambat"
 	case "partial":
-		header = fmt.Sprintf("Yth. %s,\n\n💳 *PEMBAYARAN SEBAGIAN DITERIMA*\n\nTerima kasih atas pembayaran sebagian yang telah kami terima.",
-			data.ClientName)
-		body = fmt.Sprintf("\n📄 No. Invoice: %s\n📅 Jatuh Tempo: %s\n📊 Status: %s\n💰 Sisa Tagihan: %s",
-			data.InvoiceNumber, data.DueDate, data.Status, data.RemainingAmount)
-		footer = "\nMohon segera melunasi sisa tagihan sebelum tanggal jatuh tempo.\n\n━━━━━━━━━━━━━━━━━━━\n🤖 _Pesan otomatis dari Valpro Intertech System_\n\n🌐 Info: valprointertech.com\n📧 mail@valprointertech.com"
-
+		return "Dibayar Sebagian"
 	case "draft":
-		header = fmt.Sprintf("Yth. %s,\n\n📋 *DRAFT INVOICE*\n\nBerikut draft invoice untuk direview.",
-			data.ClientName)
-		body = fmt.Sprintf("\n📄 No. Invoice: %s\n📅 Jatuh Tempo: %s\n📊 Status: %s\n💰 Total: %s",
-			data.InvoiceNumber, data.DueDate, data.Status, data.RemainingAmount)
-		footer = "\nMohon konfirmasinya apabila sudah sesuai.\n\n━━━━━━━━━━━━━━━━━━━\n🤖 _Pesan otomatis dari Valpro Intertech System_\n\n🌐 valprointertech.com\n📧 mail@valprointertech.com"
-
-	case "reminder":
-		header = fmt.Sprintf("🔔 *REMINDER PEMBAYARAN* 🔔\n\nYth. %s,",
-			data.ClientName)
-		body = fmt.Sprintf("\nMengingatkan kembali bahwa Invoice *%s* akan jatuh tempo besok (%s).\n\n💰 Sisa Tagihan: %s",
-			data.InvoiceNumber, data.DueDate, data.RemainingAmount)
-		footer = "\nMohon segera dilakukan pembayaran. Abaikan pesan ini jika sudah membayar.\n\n━━━━━━━━━━━━━━━━━━━\n🤖 _Pesan otomatis dari Valpro Intertech System_\n\n🌐 valprointertech.com\n📱 Butuh bantuan? +62 813-9971-0085"
-
-	default: // unpaid, or any other status
-		header = fmt.Sprintf("Yth. %s,\n\nTerlampir dokumen tagihan dari *Valpro Intertech*.",
-			data.ClientName)
-		body = fmt.Sprintf("\n📄 No. Invoice: %s\n📅 Jatuh Tempo: %s\n📊 Status: %s\n💰 Sisa Tagihan: %s",
-			data.InvoiceNumber, data.DueDate, data.Status, data.RemainingAmount)
-		footer = "\nMohon segera diselesaikan. Terima kasih atas kepercayaan Anda.\n\n━━━━━━━━━━━━━━━━━━━\n🤖 _Pesan ini dikirim secara otomatis oleh Valpro Intertech System_\n\n📞 Info lebih lanjut hubungi:\n🌐 valprointertech.com\n📧 mail@valprointertech.com\n📱 +62 813-9971-0085"
+		return "Draft"
+	default:
+		return "Belum Lunas"
 	}
+}
 
-	return header + body + footer
+// Vars returns the template variables of an invoice message
+func (d InvoiceTemplateData) Vars() map[string]interface{} {
+	return map[string]interface{}{
+		"clientName":      d.ClientName,
+		"invoiceNumber":   d.InvoiceNumber,
+		"dueDate":         d.DueDate,
+		"status":          d.Status,
+		"remainingAmount": d.RemainingAmount,
+	}
+}
+
+// InvoiceTemplateName returns the template used for a status key (invoice_unpaid for unknown keys)
+func InvoiceTemplateName(statusKey string) string {
+	switch statusKey {
+	case "paid", "overdue", "partial", "draft", "reminder":
+		return "invoice_" + statusKey
+	default:
+		return TemplateInvoiceUnpaid
+	}
+}
+
+// GenerateInvoiceMessage generates a WhatsApp message based on invoice status, from the
+// built-in Indonesian template (use Service.RenderInvoice for edited templates)
+func GenerateInvoiceMessage(data InvoiceTemplateData) string {
+	return renderBuiltin(InvoiceTemplateName(data.StatusKey), data.Vars())
 }
 
 // GenerateOTPMessage generates an OTP message for authentication
 func GenerateOTPMessage(otp string) string {
-	return fmt.Sprintf("🔐 *Kode Login Valpro*\n\nKode OTP Anda: *%s*\n\nJangan berikan kode ini kepada siapapun.\nBerlaku 5 menit.", otp)
+	return renderBuiltin(TemplateOTP, map[string]interface{}{"otp": otp})
+}
+
+// renderBuiltin renders a built-in Indonesian template with the default company details
+func renderBuiltin(name string, vars map[string]interface{}) string {
+	t, err := builtin(name, DefaultLocale)
+	if err != nil {
+		return ""
+	}
+	msg, err := (&Service{company: DefaultCompany}).Execute(t, vars, false)
+	if err != nil {
+		return ""
+	}
+	return msg
 }
 
 // GenerateBroadcastMessage generates a broadcast message with optional variations
@@ -111,3 +117,42 @@ func GenerateHealthAlert(status string, latency int, timestamp time.Time) string
 			timestamp.Format("02 Jan 2006 15:04"), status, latency)
 	}
 }
+
+// HealthReportTarget is one monitored target in the weekly health report
+type HealthReportTarget struct {
+	Name          string
+	UptimePercent *float64 // nil if the target had no checks
+	P95LatencyMs  *int
+	Incidents     int
+	Downtime      time.Duration
+}
+
+// GenerateHealthReport generates the weekly system health summary message
+func GenerateHealthReport(from, to time.Time, targets []HealthReportTarget) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("📊 *LAPORAN MINGGUAN SISTEM*\n\n🗓️ %s - %s\n",
+		from.Format("02 Jan 2006"), to.Format("02 Jan 2006")))
+
+	for _, t := range targets {
+		if t.UptimePercent == nil {
+			sb.WriteString(fmt.Sprintf("\nℹ️ *%s*\nTidak ada data pemeriksaan.\n", t.Name))
+			continue
+		}
+		icon := "✅"
+		if t.Incidents > 0 {
+			icon = "⚠️"
+		}
+		sb.WriteString(fmt.Sprintf("\n%s *%s*\n📈 Uptime: %.2f%%\n", icon, t.Name, *t.UptimePercent))
+		if t.P95LatencyMs != nil {
+			sb.WriteString(fmt.Sprintf("⏱️ Latency P95: %dms\n", *t.P95LatencyMs))
+		}
+		if t.Incidents > 0 {
+			sb.WriteString(fmt.Sprintf("🚨 Gangguan: %d (total %s)\n", t.Incidents, t.Downtime.Round(time.Minute)))
+		} else {
+			sb.WriteString("🚨 Gangguan: -\n")
+		}
+	}
+
+	sb.WriteString("\n🤖 _Laporan otomatis dari Valpro Intertech System_")
+	return sb.String()
+}
@@ -0,0 +1,334 @@
+package templates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Supported locales. Rendering falls back to DefaultLocale when a template has no variant
+// in the requested locale.
+const (
+	LocaleID      = "id"
+	LocaleEN      = "en"
+	DefaultLocale = LocaleID
+)
+
+// Locales lists the supported locales
+var Locales = []string{LocaleID, LocaleEN}
+
+var (
+	// ErrInvalidTemplate is returned when a template cannot be saved or rendered as written
+	ErrInvalidTemplate = errors.New("invalid template")
+	// ErrMissingVariables is returned when a required variable is not provided
+	ErrMissingVariables = errors.New("missing variables")
+)
+
+// Template is a message template written in Go text/template syntax ({{.clientName}})
+type Template struct {
+	Name        string     `json:"name"`
+	Locale      string     `json:"locale"`
+	Description string     `json:"description,omitempty"`
+	Body        string     `json:"body"`
+	Variables   []Variable `json:"variables"`
+	Version     int        `json:"version"` // 0 for the built-in default
+	Note        string     `json:"note,omitempty"`
+	UpdatedBy   string     `json:"updatedBy,omitempty"`
+	UpdatedAt   *time.Time `json:"updatedAt,omitempty"`
+}
+
+// Variable is a value a template expects from the caller
+type Variable struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	Default     string `json:"default,omitempty"`
+	Example     string `json:"example,omitempty"` // used by previews when no value is given
+}
+
+// Company holds the shared variables available to every template
+type Company struct {
+	Name    string
+	Website string
+	Email   string
+	Phone   string
+}
+
+// DefaultCompany is used when no company details are configured
+var DefaultCompany = Company{
+	Name:    "Valpro Intertech",
+	Website: "valprointertech.com",
+	Email:   "mail@valprointertech.com",
+	Phone:   "+62 813-9971-0085",
+}
+
+// SharedVariables lists the variables set for every template
+var SharedVariables = []Variable{
+	{Name: "companyName", Description: "Company name (COMPANY_NAME)"},
+	{Name: "companyWebsite", Description: "Company website (COMPANY_WEBSITE)"},
+	{Name: "companyEmail", Description: "Company email (COMPANY_EMAIL)"},
+	{Name: "companyPhone", Description: "Company phone (COMPANY_PHONE)"},
+	{Name: "today", Description: "Today's date, e.g. 02 January 2006"},
+}
+
+var (
+	namePattern     = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+	variablePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+var funcs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"trim":  strings.TrimSpace,
+}
+
+// Service renders templates, preferring the latest saved version over the built-in default
+type Service struct {
+	store   *Store
+	company Company
+}
+
+// NewService creates a template service
+func NewService(store *Store, company Company) *Service {
+	return &Service{store: store, company: company}
+}
+
+// Get returns the current version of a template in exactly that locale
+func (s *Service) Get(ctx context.Context, name, locale string) (*Template, error) {
+	t, err := s.store.Latest(ctx, name, locale)
+	if err == ErrNotFound {
+		return builtin(name, locale)
+	}
+	return t, err
+}
+
+// resolve returns the template to render for locale, falling back to DefaultLocale
+func (s *Service) resolve(ctx context.Context, name, locale string) (*Template, error) {
+	if locale == "" {
+		locale = DefaultLocale
+	}
+	t, err := s.Get(ctx, name, locale)
+	if err == ErrNotFound && locale != DefaultLocale {
+		return s.Get(ctx, name, DefaultLocale)
+	}
+	return t, err
+}
+
+// List returns the current version of every template, built-in or saved, by name and locale
+func (s *Service) List(ctx context.Context) ([]Template, error) {
+	saved, err := s.store.LatestAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := make(map[string]Template, len(builtins)+len(saved))
+	for _, t := range builtins {
+		byKey[t.Name+"/"+t.Locale] = t
+	}
+	for _, t := range saved {
+		byKey[t.Name+"/"+t.Locale] = t
+	}
+
+	list := make([]Template, 0, len(byKey))
+	for _, t := range byKey {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Name != list[j].Name {
+			return list[i].Name < list[j].Name
+		}
+		return list[i].Locale < list[j].Locale
+	})
+	return list, nil
+}
+
+// Versions returns the saved versions of a template, newest first, followed by the
+// built-in default (version 0) if there is one
+func (s *Service) Versions(ctx context.Context, name, locale string) ([]Template, error) {
+	versions, err := s.store.Versions(ctx, name, locale)
+	if err != nil {
+		return nil, err
+	}
+	if t, err := builtin(name, locale); err == nil {
+		versions = append(versions, *t)
+	}
+	if len(versions) == 0 {
+		return nil, ErrNotFound
+	}
+	return versions, nil
+}
+
+// Save validates t and stores it as the next version of the template
+func (s *Service) Save(ctx context.Context, t *Template) error {
+	if err := s.validate(t); err != nil {
+		return err
+	}
+	return s.store.Add(ctx, t)
+}
+
+// Rollback saves a copy of an earlier version as the new current version.
+// Version 0 restores the built-in default.
+func (s *Service) Rollback(ctx context.Context, name, locale string, version int, by string) (*Template, error) {
+	var old *Template
+	var err error
+	if version == 0 {
+		old, err = builtin(name, locale)
+	} else {
+		old, err = s.store.Version(ctx, name, locale, version)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Template{
+		Name:        name,
+		Locale:      locale,
+		Description: old.Description,
+		Body:        old.Body,
+		Variables:   old.Variables,
+		Note:        fmt.Sprintf("rollback to version %d", version),
+		UpdatedBy:   by,
+	}
+	if err := s.store.Add(ctx, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Render renders the current version of a template. vars are checked against the
+// template's variables; declared variables that are not given use their default.
+func (s *Service) Render(ctx context.Context, name, locale string, vars map[string]interface{}) (string, error) {
+	t, err := s.resolve(ctx, name, locale)
+	if err != nil {
+		return "", err
+	}
+	return s.Execute(t, vars, false)
+}
+
+// Execute renders t. With examples set, missing variables use their example value
+// instead of failing (for previews).
+func (s *Service) Execute(t *Template, vars map[string]interface{}, examples bool) (string, error) {
+	tmpl, err := parse(t)
+	if err != nil {
+		return "", err
+	}
+
+	data := s.shared()
+	var missing []string
+	for _, v := range t.Variables {
+		value, ok := vars[v.Name]
+		if !ok || value == nil || value == "" {
+			switch {
+			case v.Default != "":
+				value = v.Default
+			case examples && v.Example != "":
+				value = v.Example
+			case v.Required && !examples:
+				missing = append(missing, v.Name)
+			default:
+				value = ""
+			}
+		}
+		data[v.Name] = value
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("%w: %s", ErrMissingVariables, strings.Join(missing, ", "))
+	}
+	for k, v := range vars {
+		if _, ok := data[k]; !ok {
+			data[k] = v
+		}
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+	return sb.String(), nil
+}
+
+// RenderInvoice renders the invoice template matching data.StatusKey
+func (s *Service) RenderInvoice(ctx context.Context, data InvoiceTemplateData, locale string) (string, error) {
+	return s.Render(ctx, InvoiceTemplateName(data.StatusKey), locale, data.Vars())
+}
+
+// validate checks the name, locale and variables of t and that its body renders
+// with example values (so it only uses declared and shared variables)
+func (s *Service) validate(t *Template) error {
+	if !namePattern.MatchString(t.Name) {
+		return fmt.Errorf("%w: name must be 1-64 lowercase letters, digits, '_' or '-'", ErrInvalidTemplate)
+	}
+	if !isLocale(t.Locale) {
+		return fmt.Errorf("%w: locale must be one of %v", ErrInvalidTemplate, Locales)
+	}
+	if strings.TrimSpace(t.Body) == "" {
+		return fmt.Errorf("%w: body is empty", ErrInvalidTemplate)
+	}
+
+	seen := make(map[string]bool, len(t.Variables))
+	for _, v := range t.Variables {
+		if !variablePattern.MatchString(v.Name) {
+			return fmt.Errorf("%w: invalid variable name %q", ErrInvalidTemplate, v.Name)
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("%w: duplicate variable %q", ErrInvalidTemplate, v.Name)
+		}
+		seen[v.Name] = true
+	}
+
+	vars := make(map[string]interface{}, len(t.Variables))
+	for _, v := range t.Variables {
+		vars[v.Name] = "x"
+	}
+	_, err := s.Execute(t, vars, true)
+	return err
+}
+
+// shared returns the variables set for every template
+func (s *Service) shared() map[string]interface{} {
+	return sharedVars(s.company)
+}
+
+func sharedVars(c Company) map[string]interface{} {
+	return map[string]interface{}{
+		"companyName":    c.Name,
+		"companyWebsite": c.Website,
+		"companyEmail":   c.Email,
+		"companyPhone":   c.Phone,
+		"today":          time.Now().Format("02 January 2006"),
+	}
+}
+
+// parse compiles a template; referencing a variable that is not set is an error
+func parse(t *Template) (*template.Template, error) {
+	tmpl, err := template.New(t.Name).Option("missingkey=error").Funcs(funcs).Parse(t.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+	return tmpl, nil
+}
+
+// builtin returns a copy of a built-in template
+func builtin(name, locale string) (*Template, error) {
+	for _, t := range builtins {
+		if t.Name == name && t.Locale == locale {
+			c := t
+			return &c, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func isLocale(locale string) bool {
+	for _, l := range Locales {
+		if l == locale {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package templates
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotFound is returned when a template or template version does not exist
+var ErrNotFound = errors.New("not found")
+
+// Store keeps every saved version of the edited templates in SQLite.
+// The current version of a template is the one with the highest number.
+type Store struct {
+	db *sql.DB
+}
+
+const schema = `
+CREATE TABLE IF NOT EXISTS message_templates (
+	seq         INTEGER PRIMARY KEY AUTOINCREMENT,
+	name        TEXT NOT NULL,
+	locale      TEXT NOT NULL,
+	version     INTEGER NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	body        TEXT NOT NULL,
+	variables   TEXT NOT NULL DEFAULT '[]',
+	note        TEXT NOT NULL DEFAULT '',
+	created_by  TEXT NOT NULL DEFAULT '',
+	created_at  INTEGER NOT NULL,
+	UNIQUE (name, locale, version)
+);
+`
+
+const templateColumns = `name, locale, version, description, body, variables, note, created_by, created_at`
+
+// NewStore creates the template tables if needed
+func NewStore(db *sql.DB) (*Store, error) {
+	if _, err := db.Exec(schema); err != nil {
+		return nil, fmt.Errorf("failed to create template schema: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Add saves t as the next version of its template and sets t.Version and t.UpdatedAt
+func (s *Store) Add(ctx context.Context, t *Template) error {
+	variables, err := json.Marshal(t.Variables)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	res, err := s.db.ExecContext(ctx, `INSERT INTO message_templates (`+templateColumns+`)
+		SELECT ?, ?, COALESCE(MAX(version), 0) + 1, ?, ?, ?, ?, ?, ?
+		FROM message_templates WHERE name = ? AND locale = ?`,
+		t.Name, t.Locale, t.Description, t.Body, string(variables), t.Note, t.UpdatedBy, now.UnixMilli(),
+		t.Name, t.Locale)
+	if err != nil {
+		return fmt.Errorf("failed to insert template: %w", err)
+	}
+	seq, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	if err := s.db.QueryRowContext(ctx, `SELECT version FROM message_templates WHERE seq = ?`, seq).Scan(&t.Version); err != nil {
+		return err
+	}
+	t.UpdatedAt = &now
+	return nil
+}
+
+// Latest returns the current version of a template
+func (s *Store) Latest(ctx context.Context, name, locale string) (*Template, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+templateColumns+` FROM message_templates
+		WHERE name = ? AND locale = ? ORDER BY version DESC LIMIT 1`, name, locale)
+	return scanTemplate(row)
+}
+
+// Version returns a single version of a template
+func (s *Store) Version(ctx context.Context, name, locale string, version int) (*Template, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+templateColumns+` FROM message_templates
+		WHERE name = ? AND locale = ? AND version = ?`, name, locale, version)
+	return scanTemplate(row)
+}
+
+// Versions returns all saved versions of a template, newest first
+func (s *Store) Versions(ctx context.Context, name, locale string) ([]Template, error) {
+	return s.query(ctx, `SELECT `+templateColumns+` FROM message_templates
+		WHERE name = ? AND locale = ? ORDER BY version DESC`, name, locale)
+}
+
+// LatestAll returns the current version of every saved template
+func (s *Store) LatestAll(ctx context.Context) ([]Template, error) {
+	return s.query(ctx, `SELECT `+templateColumns+` FROM message_templates t
+		WHERE version = (SELECT MAX(version) FROM message_templates WHERE name = t.name AND locale = t.locale)
+		ORDER BY name, locale`)
+}
+
+func (s *Store) query(ctx context.Context, query string, args ...interface{}) ([]Template, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []Template{}
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *t)
+	}
+	return list, rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTemplate(row rowScanner) (*Template, error) {
+	var t Template
+	var variables string
+	var createdAt int64
+	err := row.Scan(&t.Name, &t.Locale, &t.Version, &t.Description, &t.Body, &variables, &t.Note, &t.UpdatedBy, &createdAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(variables), &t.Variables); err != nil {
+		return nil, fmt.Errorf("invalid variables of template %s/%s: %w", t.Name, t.Locale, err)
+	}
+	updatedAt := time.UnixMilli(createdAt)
+	t.UpdatedAt = &updatedAt
+	return &t, nil
+}
@@ -0,0 +1,385 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// MaxAttempts is how many times a delivery is tried before it goes to the dead-letter list
+	MaxAttempts = 8
+
+	idlePoll        = 30 * time.Second
+	batchSize       = 50
+	deliveryTimeout = 10 * time.Second
+	keepDelivered   = 24 * time.Hour
+	pruneInterval   = time.Hour
+)
+
+// Dispatcher fans events out to the configured webhooks and delivers them in the background.
+// Deliveries are persisted first, so events survive endpoint outages and restarts.
+type Dispatcher struct {
+	store  *Store
+	client *http.Client
+
+	mu    sync.RWMutex
+	hooks []*Webhook
+
+	wake       chan struct{}
+	stop       chan struct{}
+	done       chan struct{}
+	lastPruned time.Time
+}
+
+// NewDispatcher creates a dispatcher on top of a webhook store
+func NewDispatcher(store *Store) *Dispatcher {
+	return &Dispatcher{
+		store:  store,
+		client: &http.Client{Timeout: deliveryTimeout},
+		wake:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// Start loads the webhooks and launches the delivery worker
+func (d *Dispatcher) Start() error {
+	if err := d.reload(context.Background()); err != nil {
+		return err
+	}
+	go d.run()
+	log.Printf("✅ [WEBHOOK] Dispatcher started (%d webhook(s))", len(d.snapshot()))
+	return nil
+}
+
+// Stop signals the worker to exit and waits (until ctx expires) for the current delivery.
+// Pending deliveries stay in the store and are sent after the next start.
+func (d *Dispatcher) Stop(ctx context.Context) error {
+	close(d.stop)
+	select {
+	case <-d.done:
+		log.Println("✅ [WEBHOOK] Dispatcher stopped")
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("gave up waiting for the current delivery: %w", ctx.Err())
+	}
+}
+
+// Dispatch queues an event for every enabled webhook subscribed to it
+func (d *Dispatcher) Dispatch(event string, data interface{}) {
+	queued := 0
+	for _, w := range d.snapshot() {
+		if !w.Enabled || !w.Wants(event) {
+			continue
+		}
+
+		delivery := &Delivery{ID: newID(), WebhookID: w.ID, Event: event}
+		payload, err := envelope(delivery.ID, event, data)
+		if err != nil {
+			log.Printf("❌ [WEBHOOK] Failed to encode %s event for %s: %v", event, w.ID, err)
+			continue
+		}
+		delivery.Payload = payload
+
+		if err := d.store.InsertDelivery(context.Background(), delivery); err != nil {
+			log.Printf("❌ [WEBHOOK] Failed to queue %s for %s: %v", event, w.ID, err)
+			continue
+		}
+		queued++
+	}
+
+	if queued > 0 {
+		d.Wake()
+	}
+}
+
+// Wake nudges the worker, e.g. after new deliveries were queued
+func (d *Dispatcher) Wake() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// List returns all webhooks
+func (d *Dispatcher) List(ctx context.Context) ([]*Webhook, error) {
+	return d.store.ListWebhooks(ctx)
+}
+
+// Get returns a webhook by ID
+func (d *Dispatcher) Get(ctx context.Context, id string) (*Webhook, error) {
+	return d.store.GetWebhook(ctx, id)
+}
+
+// Create adds a webhook. A signing secret is generated if none is set.
+func (d *Dispatcher) Create(ctx context.Context, w *Webhook) error {
+	if w.Secret == "" {
+		w.Secret = NewSecret()
+	}
+	if err := d.store.CreateWebhook(ctx, w); err != nil {
+		return err
+	}
+	log.Printf("🪝 [WEBHOOK] Added %s -> %s %v", w.ID, w.URL, w.Events)
+	return d.reload(ctx)
+}
+
+// Update saves changes to a webhook
+func (d *Dispatcher) Update(ctx context.Context, w *Webhook) error {
+	if err := d.store.UpdateWebhook(ctx, w); err != nil {
+		return err
+	}
+	return d.reload(ctx)
+}
+
+// Delete removes a webhook and drops its pending and dead deliveries
+func (d *Dispatcher) Delete(ctx context.Context, id string) error {
+	if err := d.store.DeleteWebhook(ctx, id); err != nil {
+		return err
+	}
+	log.Printf("🗑️ [WEBHOOK] Removed %s", id)
+	return d.reload(ctx)
+}
+
+// DeadLetters returns deliveries that ran out of attempts, newest first
+func (d *Dispatcher) DeadLetters(ctx context.Context, webhookID string, limit int) ([]*Delivery, error) {
+	return d.store.ListDeliveries(ctx, StatusDead, webhookID, limit)
+}
+
+// Retry requeues a dead delivery
+func (d *Dispatcher) Retry(ctx context.Context, id string) (*Delivery, error) {
+	delivery, err := d.store.Requeue(ctx, id)
+	if err == nil {
+		d.Wake()
+	}
+	return delivery, err
+}
+
+// Test sends a test event to a webhook right away, bypassing the queue and the event filter
+func (d *Dispatcher) Test(ctx context.Context, w *Webhook) (int, error) {
+	id := newID()
+	payload, err := envelope(id, EventTest, map[string]interface{}{
+		"webhookId": w.ID,
+		"message":   "Webhook test from WhatsApp Server",
+	})
+	if err != nil {
+		return 0, err
+	}
+	return d.post(ctx, w, &Delivery{ID: id, WebhookID: w.ID, Event: EventTest, Payload: payload})
+}
+
+// Sign returns the X-Webhook-Signature value for a body sent at timestamp (unix seconds):
+// "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body))
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (d *Dispatcher) run() {
+	defer close(d.done)
+
+	for {
+		wait := d.process()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-d.stop:
+			timer.Stop()
+			return
+		case <-d.wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// process attempts the due deliveries and returns how long to wait before looking again
+func (d *Dispatcher) process() time.Duration {
+	ctx := context.Background()
+	d.prune(ctx)
+
+	due, err := d.store.Due(ctx, time.Now(), batchSize)
+	if err != nil {
+		log.Printf("❌ [WEBHOOK] Failed to load deliveries: %v", err)
+		return idlePoll
+	}
+
+	hooks := make(map[string]*Webhook)
+	for _, w := range d.snapshot() {
+		hooks[w.ID] = w
+	}
+
+	// Once an endpoint fails, its other deliveries wait until it is retried instead of
+	// each running into the same timeout
+	failing := make(map[string]time.Time)
+	for _, delivery := range due {
+		select {
+		case <-d.stop:
+			return 0
+		default:
+		}
+		if resume, ok := failing[delivery.WebhookID]; ok {
+			if err := d.store.Postpone(ctx, delivery, resume); err != nil {
+				log.Printf("❌ [WEBHOOK] Failed to postpone delivery %s: %v", delivery.ID, err)
+			}
+			continue
+		}
+		if resume := d.attempt(ctx, hooks[delivery.WebhookID], delivery); !resume.IsZero() {
+			failing[delivery.WebhookID] = resume
+		}
+	}
+
+	if len(due) == batchSize {
+		return 0
+	}
+	next, err := d.store.NextAttempt(ctx)
+	if err != nil || next.IsZero() {
+		return idlePoll
+	}
+	if wait := time.Until(next); wait < idlePoll {
+		if wait < 0 {
+			return 0
+		}
+		return wait
+	}
+	return idlePoll
+}
+
+// attempt runs a single attempt of a delivery and records the outcome. When the endpoint
+// failed, it returns when the endpoint should be tried again; otherwise the zero time.
+func (d *Dispatcher) attempt(ctx context.Context, w *Webhook, delivery *Delivery) time.Time {
+	delivery.Attempts++
+
+	var err error
+	switch {
+	case w == nil:
+		err = fmt.Errorf("webhook %s no longer exists", delivery.WebhookID)
+	case !w.Enabled:
+		err = fmt.Errorf("webhook is disabled")
+	default:
+		_, err = d.post(ctx, w, delivery)
+	}
+
+	if err == nil {
+		if err := d.store.MarkDelivered(ctx, delivery); err != nil {
+			log.Printf("❌ [WEBHOOK] Failed to mark delivery %s as delivered: %v", delivery.ID, err)
+		}
+		return time.Time{}
+	}
+
+	if delivery.Attempts >= MaxAttempts || w == nil || !w.Enabled {
+		log.Printf("❌ [WEBHOOK] Delivery %s (%s) moved to dead letters: %v", delivery.ID, delivery.Event, err)
+		if err := d.store.MarkDead(ctx, delivery, err); err != nil {
+			log.Printf("❌ [WEBHOOK] Failed to mark delivery %s as dead: %v", delivery.ID, err)
+		}
+		// A missing or disabled webhook fails its deliveries without reaching any endpoint
+		if w == nil || !w.Enabled {
+			return time.Time{}
+		}
+		return time.Now().Add(retryDelay(1))
+	}
+
+	next := time.Now().Add(retryDelay(delivery.Attempts))
+	log.Printf("⚠️ [WEBHOOK] Delivery %s attempt %d/%d failed, retrying at %s: %v",
+		delivery.ID, delivery.Attempts, MaxAttempts, next.Format("15:04:05"), err)
+	if err := d.store.MarkRetry(ctx, delivery, err, next); err != nil {
+		log.Printf("❌ [WEBHOOK] Failed to requeue delivery %s: %v", delivery.ID, err)
+	}
+	return next
+}
+
+// post sends a delivery to its endpoint. Any 2xx response counts as delivered.
+func (d *Dispatcher) post(ctx context.Context, w *Webhook, delivery *Delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "wa-server-go-webhook/1.0")
+	req.Header.Set("X-Webhook-Id", delivery.ID)
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", Sign(w.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint returned %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	return resp.StatusCode, nil
+}
+
+// prune drops old delivered rows at most once per pruneInterval
+func (d *Dispatcher) prune(ctx context.Context) {
+	if time.Since(d.lastPruned) < pruneInterval {
+		return
+	}
+	d.lastPruned = time.Now()
+
+	if n, err := d.store.PruneDelivered(ctx, time.Now().Add(-keepDelivered)); err != nil {
+		log.Printf("⚠️ [WEBHOOK] Failed to prune deliveries: %v", err)
+	} else if n > 0 {
+		log.Printf("🧹 [WEBHOOK] Pruned %d delivered event(s)", n)
+	}
+}
+
+func (d *Dispatcher) reload(ctx context.Context) error {
+	hooks, err := d.store.ListWebhooks(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load webhooks: %w", err)
+	}
+	d.mu.Lock()
+	d.hooks = hooks
+	d.mu.Unlock()
+	return nil
+}
+
+func (d *Dispatcher) snapshot() []*Webhook {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.hooks
+}
+
+// envelope wraps event data in the body posted to endpoints. The ID stays the same across
+// retries so receivers can deduplicate.
+func envelope(id string, event string, data interface{}) (json.RawMessage, error) {
+	return json.Marshal(map[string]interface{}{
+		"id":        id,
+		"event":     event,
+		"timestamp": time.Now().UnixMilli(),
+		"data":      data,
+	})
+}
+
+// retryDelay returns the exponential backoff after the given number of attempts
+// (15s, 30s, 1m, ... capped at one hour)
+func retryDelay(attempts int) time.Duration {
+	delay := 15 * time.Second
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+	}
+	if delay > time.Hour {
+		delay = time.Hour
+	}
+	return delay
+}
@@ -0,0 +1,405 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Events that can be subscribed to. "*" subscribes to all of them.
+const (
+	EventNewMessage   = "new-message"
+	EventMessageAck   = "message-ack"
+	EventStatusUpdate = "status-update"
+	EventQR           = "qr"
+	EventAll          = "*"
+	// EventTest is only sent by POST /webhooks/:id/test
+	EventTest = "test"
+)
+
+// Events lists the event names a webhook can subscribe to
+var Events = []string{EventNewMessage, EventMessageAck, EventStatusUpdate, EventQR}
+
+// Status represents the lifecycle state of a delivery
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusDelivered Status = "delivered"
+	StatusDead      Status = "dead"
+)
+
+var (
+	// ErrNotFound is returned when a webhook or delivery ID does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrNotDead is returned when retrying a delivery that is not in the dead-letter list
+	ErrNotDead = errors.New("delivery is not dead")
+)
+
+// Webhook is an outbound endpoint that receives events
+type Webhook struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"-"`
+	Events    []string  `json:"events"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Wants reports whether the webhook is subscribed to event
+func (w *Webhook) Wants(event string) bool {
+	for _, e := range w.Events {
+		if e == event || e == EventAll {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivery is a single event queued for one webhook
+type Delivery struct {
+	ID            string          `json:"id"`
+	WebhookID     string          `json:"webhookId"`
+	Event         string          `json:"event"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        Status          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	LastError     string          `json:"lastError,omitempty"`
+	NextAttemptAt time.Time       `json:"nextAttemptAt"`
+	CreatedAt     time.Time       `json:"createdAt"`
+	UpdatedAt     time.Time       `json:"updatedAt"`
+}
+
+// Store persists webhooks and their deliveries in SQLite
+type Store struct {
+	db *sql.DB
+}
+
+const schema = `
+CREATE TABLE IF NOT EXISTS webhooks (
+	id         TEXT PRIMARY KEY,
+	url        TEXT NOT NULL,
+	secret     TEXT NOT NULL,
+	events     TEXT NOT NULL,
+	enabled    INTEGER NOT NULL DEFAULT 1,
+	created_at INTEGER NOT NULL,
+	updated_at INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	seq             INTEGER PRIMARY KEY AUTOINCREMENT,
+	id              TEXT NOT NULL UNIQUE,
+	webhook_id      TEXT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+	event           TEXT NOT NULL,
+	payload         TEXT NOT NULL,
+	status          TEXT NOT NULL,
+	attempts        INTEGER NOT NULL DEFAULT 0,
+	last_error      TEXT NOT NULL DEFAULT '',
+	next_attempt_at INTEGER NOT NULL,
+	created_at      INTEGER NOT NULL,
+	updated_at      INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_status ON webhook_deliveries(status, next_attempt_at);
+`
+
+const webhookColumns = `id, url, secret, events, enabled, created_at, updated_at`
+
+const deliveryColumns = `id, webhook_id, event, payload, status, attempts, last_error, next_attempt_at, created_at, updated_at`
+
+// NewStore creates the webhook tables if needed
+func NewStore(db *sql.DB) (*Store, error) {
+	if _, err := db.Exec(schema); err != nil {
+		return nil, fmt.Errorf("failed to create webhook schema: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// CreateWebhook persists a new webhook
+func (s *Store) CreateWebhook(ctx context.Context, w *Webhook) error {
+	events, err := json.Marshal(w.Events)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	w.ID = newID()
+	w.CreatedAt = now
+	w.UpdatedAt = now
+	_, err = s.db.ExecContext(ctx, `INSERT INTO webhooks (`+webhookColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		w.ID, w.URL, w.Secret, string(events), w.Enabled, now.UnixMilli(), now.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("failed to insert webhook: %w", err)
+	}
+	return nil
+}
+
+// UpdateWebhook saves the URL, secret, events and enabled flag of a webhook
+func (s *Store) UpdateWebhook(ctx context.Context, w *Webhook) error {
+	events, err := json.Marshal(w.Events)
+	if err != nil {
+		return err
+	}
+
+	w.UpdatedAt = time.Now()
+	res, err := s.db.ExecContext(ctx, `UPDATE webhooks SET url = ?, secret = ?, events = ?, enabled = ?, updated_at = ? WHERE id = ?`,
+		w.URL, w.Secret, string(events), w.Enabled, w.UpdatedAt.UnixMilli(), w.ID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteWebhook removes a webhook together with its deliveries
+func (s *Store) DeleteWebhook(ctx context.Context, id string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM webhooks WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetWebhook returns a webhook by ID
+func (s *Store) GetWebhook(ctx context.Context, id string) (*Webhook, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE id = ?`, id)
+	w, err := scanWebhook(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return w, err
+}
+
+// ListWebhooks returns all webhooks, oldest first
+func (s *Store) ListWebhooks(ctx context.Context) ([]*Webhook, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+webhookColumns+` FROM webhooks ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hooks := make([]*Webhook, 0)
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, w)
+	}
+	return hooks, rows.Err()
+}
+
+// InsertDelivery queues an event for a webhook
+func (s *Store) InsertDelivery(ctx context.Context, d *Delivery) error {
+	now := time.Now()
+	d.Status = StatusPending
+	d.NextAttemptAt = now
+	d.CreatedAt = now
+	d.UpdatedAt = now
+	_, err := s.db.ExecContext(ctx, `INSERT INTO webhook_deliveries (id, webhook_id, event, payload, status, next_attempt_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		d.ID, d.WebhookID, d.Event, string(d.Payload), string(d.Status),
+		now.UnixMilli(), now.UnixMilli(), now.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("failed to insert delivery: %w", err)
+	}
+	return nil
+}
+
+// GetDelivery returns a delivery by ID
+func (s *Store) GetDelivery(ctx context.Context, id string) (*Delivery, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE id = ?`, id)
+	d, err := scanDelivery(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return d, err
+}
+
+// Due returns pending deliveries whose next attempt is due, oldest first
+func (s *Store) Due(ctx context.Context, now time.Time, limit int) ([]*Delivery, error) {
+	return s.queryDeliveries(ctx, `SELECT `+deliveryColumns+` FROM webhook_deliveries
+		WHERE status = ? AND next_attempt_at <= ? ORDER BY seq LIMIT ?`,
+		string(StatusPending), now.UnixMilli(), limit)
+}
+
+// NextAttempt returns when the earliest pending delivery is due, or the zero time if none is pending
+func (s *Store) NextAttempt(ctx context.Context) (time.Time, error) {
+	var next sql.NullInt64
+	err := s.db.QueryRowContext(ctx, `SELECT MIN(next_attempt_at) FROM webhook_deliveries WHERE status = ?`,
+		string(StatusPending)).Scan(&next)
+	if err != nil || !next.Valid {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(next.Int64), nil
+}
+
+// ListDeliveries returns deliveries newest first, optionally filtered by status and webhook
+func (s *Store) ListDeliveries(ctx context.Context, status Status, webhookID string, limit int) ([]*Delivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries WHERE 1 = 1`
+	var args []interface{}
+	if status != "" {
+		query += ` AND status = ?`
+		args = append(args, string(status))
+	}
+	if webhookID != "" {
+		query += ` AND webhook_id = ?`
+		args = append(args, webhookID)
+	}
+	query += ` ORDER BY seq DESC`
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+	return s.queryDeliveries(ctx, query, args...)
+}
+
+// MarkDelivered flags a delivery as accepted by the endpoint
+func (s *Store) MarkDelivered(ctx context.Context, d *Delivery) error {
+	d.Status = StatusDelivered
+	d.LastError = ""
+	return s.update(ctx, d)
+}
+
+// MarkRetry schedules the next attempt after a failed one
+func (s *Store) MarkRetry(ctx context.Context, d *Delivery, sendErr error, next time.Time) error {
+	d.Status = StatusPending
+	d.LastError = sendErr.Error()
+	d.NextAttemptAt = next
+	return s.update(ctx, d)
+}
+
+// Postpone moves the next attempt of a pending delivery to next without counting an attempt
+func (s *Store) Postpone(ctx context.Context, d *Delivery, next time.Time) error {
+	d.NextAttemptAt = next
+	return s.update(ctx, d)
+}
+
+// MarkDead moves a delivery to the dead-letter list
+func (s *Store) MarkDead(ctx context.Context, d *Delivery, sendErr error) error {
+	d.Status = StatusDead
+	d.LastError = sendErr.Error()
+	return s.update(ctx, d)
+}
+
+// Requeue puts a dead delivery back in the queue with a fresh set of attempts
+func (s *Store) Requeue(ctx context.Context, id string) (*Delivery, error) {
+	now := time.Now().UnixMilli()
+	res, err := s.db.ExecContext(ctx, `UPDATE webhook_deliveries SET status = ?, attempts = 0, next_attempt_at = ?, updated_at = ?
+		WHERE id = ? AND status = ?`,
+		string(StatusPending), now, now, id, string(StatusDead))
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := s.GetDelivery(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return d, ErrNotDead
+	}
+	return d, nil
+}
+
+// PruneDelivered deletes delivered rows older than before
+func (s *Store) PruneDelivered(ctx context.Context, before time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM webhook_deliveries WHERE status = ? AND updated_at < ?`,
+		string(StatusDelivered), before.UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (s *Store) update(ctx context.Context, d *Delivery) error {
+	d.UpdatedAt = time.Now()
+	_, err := s.db.ExecContext(ctx, `UPDATE webhook_deliveries
+		SET status = ?, attempts = ?, last_error = ?, next_attempt_at = ?, updated_at = ?
+		WHERE id = ?`,
+		string(d.Status), d.Attempts, d.LastError, d.NextAttemptAt.UnixMilli(), d.UpdatedAt.UnixMilli(), d.ID)
+	return err
+}
+
+func (s *Store) queryDeliveries(ctx context.Context, query string, args ...interface{}) ([]*Delivery, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]*Delivery, 0)
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanWebhook(row rowScanner) (*Webhook, error) {
+	var (
+		w                Webhook
+		events           string
+		created, updated int64
+	)
+	if err := row.Scan(&w.ID, &w.URL, &w.Secret, &events, &w.Enabled, &created, &updated); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(events), &w.Events); err != nil || w.Events == nil {
+		w.Events = []string{}
+	}
+	w.CreatedAt = time.UnixMilli(created)
+	w.UpdatedAt = time.UnixMilli(updated)
+	return &w, nil
+}
+
+func scanDelivery(row rowScanner) (*Delivery, error) {
+	var (
+		d                             Delivery
+		status, payload               string
+		nextAttempt, created, updated int64
+	)
+	err := row.Scan(&d.ID, &d.WebhookID, &d.Event, &payload, &status, &d.Attempts, &d.LastError,
+		&nextAttempt, &created, &updated)
+	if err != nil {
+		return nil, err
+	}
+
+	d.Status = Status(status)
+	d.Payload = json.RawMessage(payload)
+	d.NextAttemptAt = time.UnixMilli(nextAttempt)
+	d.CreatedAt = time.UnixMilli(created)
+	d.UpdatedAt = time.UnixMilli(updated)
+	return &d, nil
+}
+
+func newID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// NewSecret returns a random signing secret
+func NewSecret() string {
+	b := make([]byte, 24)
+	_, _ = rand.Read(b)
+	return "whsec_" + hex.EncodeToString(b)
+}
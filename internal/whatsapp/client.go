@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/skip2/go-qrcode"
 	"go.mau.fi/whatsmeow"
@@ -26,6 +27,21 @@ type Client struct {
 	Ready     bool
 	QRCode    string
 	mu        sync.RWMutex
+
+	// qrOffered is closed when the current connection offers a QR code, i.e. accepts pairing
+	qrOffered chan struct{}
+
+	// Reconnect supervisor state and connection history (see supervisor.go)
+	stopped              bool
+	reconnecting         bool
+	reconnectDelay       time.Duration
+	nextReconnectAt      time.Time
+	lastConnectedAt      time.Time
+	lastDisconnectedAt   time.Time
+	lastDisconnectReason string
+	reconnectCount       int
+	reconnectAttempts    int
+	lastError            string
 }
 
 // NewClient creates a new WhatsApp client with SQLite session storage
@@ -52,39 +68,53 @@ func NewClient(ctx context.Context, clientID string, dbPath string) (*Client, er
 	// CRITICAL: Enable emitting AppState events during full sync to get existing labels
 	waClient.EmitAppStateEventsOnFullSync = true
 
+	// Reconnects are handled by the Manager's supervisor (with backoff and health tracking)
+	waClient.EnableAutoReconnect = false
+
 	client := &Client{
 		WAClient:  waClient,
 		Container: container,
 		Device:    deviceStore,
 		ID:        clientID,
 		Ready:     false,
+		qrOffered: make(chan struct{}),
 	}
 
 	return client, nil
 }
 
-// Connect initiates the WhatsApp connection
+// Connect initiates the WhatsApp connection. qrChan receives the QR codes to pair with and is
+// closed once no more will come: right away for a paired session, otherwise when pairing ends.
 func (c *Client) Connect(ctx context.Context, qrChan chan<- string, statusChan chan<- StatusUpdate) error {
+	c.setStopped(false)
+	c.ClearQRCode()
+
 	// Check if already logged in
 	if c.WAClient.Store.ID == nil {
 		// Need to pair with QR code
 		qrChannel, _ := c.WAClient.GetQRChannel(ctx)
 		err := c.WAClient.Connect()
 		if err != nil {
+			close(qrChan)
 			return fmt.Errorf("failed to connect: %w", err)
 		}
+		if qrChannel == nil {
+			close(qrChan)
+			return nil
+		}
 
-		// Handle QR codes
+		// Handle QR codes (whatsmeow closes qrChannel on success, timeout or disconnect)
 		go func() {
+			defer close(qrChan)
+			// A code from an ended pairing attempt can no longer be scanned or paired against
+			defer c.ClearQRCode()
 			for evt := range qrChannel {
 				if evt.Event == "code" {
 					// Generate QR code as data URL (Optional: Keep for terminal log only)
 					// qrPNG, err := qrcode.Encode(evt.Code, qrcode.Medium, 256)
 					
 					// Store the RAW code (Critical for Frontend QRCodeSVG)
-					c.mu.Lock()
-					c.QRCode = evt.Code // Save Raw String!
-					c.mu.Unlock()
+					c.setQRCode(evt.Code) // Save Raw String!
 					
 					// Send raw code to channel
 					qrChan <- evt.Code
@@ -97,6 +127,7 @@ func (c *Client) Connect(ctx context.Context, qrChan chan<- string, statusChan c
 		}()
 	} else {
 		// Already logged in, just connect
+		close(qrChan)
 		err := c.WAClient.Connect()
 		if err != nil {
 			return fmt.Errorf("failed to connect: %w", err)
@@ -106,6 +137,29 @@ func (c *Client) Connect(ctx context.Context, qrChan chan<- string, statusChan c
 	return nil
 }
 
+// pairCodeWait is how long PairPhone waits for the connection to be ready for pairing
+const pairCodeWait = 20 * time.Second
+
+// PairPhone requests a pairing code for a phone number (digits with country code).
+// whatsmeow only accepts it once the server has offered QR pairing, so wait for the current
+// connection's first QR code.
+func (c *Client) PairPhone(ctx context.Context, phone string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, pairCodeWait)
+	defer cancel()
+
+	c.mu.RLock()
+	offered := c.qrOffered
+	c.mu.RUnlock()
+
+	select {
+	case <-ctx.Done():
+		return "", fmt.Errorf("timed out waiting for WhatsApp to accept pairing")
+	case <-offered:
+	}
+
+	return c.WAClient.PairPhone(ctx, phone, true, whatsmeow.PairClientChrome, "Chrome (Linux)")
+}
+
 // IsReady returns whether the client is connected and ready
 func (c *Client) IsReady() bool {
 	c.mu.RLock()
@@ -127,8 +181,46 @@ func (c *Client) GetQRCode() string {
 	return c.QRCode
 }
 
-// Disconnect closes the WhatsApp connection
+// setQRCode stores a QR code offered by the server and wakes PairPhone
+func (c *Client) setQRCode(code string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.QRCode = code
+	select {
+	case <-c.qrOffered:
+	default:
+		close(c.qrOffered)
+	}
+}
+
+// ClearQRCode forgets the last QR code once pairing is done or the connection offering it is gone
+func (c *Client) ClearQRCode() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.QRCode = ""
+	select {
+	case <-c.qrOffered:
+		c.qrOffered = make(chan struct{})
+	default:
+	}
+}
+
+// IsLoggedIn returns whether the session is paired with a WhatsApp account
+func (c *Client) IsLoggedIn() bool {
+	return c.WAClient.Store.ID != nil
+}
+
+// PhoneNumber returns the phone number of the paired account, or "" if not paired
+func (c *Client) PhoneNumber() string {
+	if id := c.WAClient.Store.ID; id != nil {
+		return id.User
+	}
+	return ""
+}
+
+// Disconnect closes the WhatsApp connection and keeps it closed (no automatic reconnect)
 func (c *Client) Disconnect() {
+	c.setStopped(true)
 	c.WAClient.Disconnect()
 	c.SetReady(false)
 }
@@ -0,0 +1,81 @@
+package whatsapp
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// Command is a text message received from a sender registered with HandleCommands
+type Command struct {
+	Client    string    // session that received it
+	Sender    string    // sender phone number
+	Chat      types.JID // chat to reply to
+	MessageID string
+	QuotedID  string // ID of the message it replies to, if any
+	Body      string
+	Timestamp time.Time
+}
+
+// CommandHandler handles commands. It is called from the event loop and must not block.
+type CommandHandler func(Command)
+
+// HandleCommands routes the text messages phone sends to session clientID (in direct chats)
+// to handler. The messages are still stored and broadcast as usual.
+func (m *Manager) HandleCommands(clientID, phone string, handler CommandHandler) {
+	m.cmdMu.Lock()
+	defer m.cmdMu.Unlock()
+	m.commands[clientID+"/"+phone] = handler
+}
+
+// dispatchCommand passes an incoming direct message to the handler registered for its sender
+func (m *Manager) dispatchCommand(clientID string, client *Client, v *events.Message, body string) {
+	if v.Info.IsFromMe || v.Info.IsGroup || strings.TrimSpace(body) == "" {
+		return
+	}
+	// Text only: media captions are not commands
+	if v.Message.GetConversation() == "" && v.Message.GetExtendedTextMessage().GetText() == "" {
+		return
+	}
+
+	m.cmdMu.RLock()
+	empty := len(m.commands) == 0
+	m.cmdMu.RUnlock()
+	if empty {
+		return
+	}
+
+	// Senders may be addressed by LID; commands are registered by phone number
+	sender := v.Info.Sender.ToNonAD()
+	if sender.Server == types.HiddenUserServer {
+		if v.Info.SenderAlt.Server == types.DefaultUserServer {
+			sender = v.Info.SenderAlt.ToNonAD()
+		} else if pn, err := client.WAClient.Store.LIDs.GetPNForLID(context.Background(), sender); err == nil && !pn.IsEmpty() {
+			sender = pn
+		}
+	}
+
+	m.cmdMu.RLock()
+	handler, ok := m.commands[clientID+"/"+sender.User]
+	m.cmdMu.RUnlock()
+	if !ok {
+		return
+	}
+
+	var quotedID string
+	if ext := v.Message.GetExtendedTextMessage(); ext != nil {
+		quotedID = ext.GetContextInfo().GetStanzaID()
+	}
+	handler(Command{
+		Client:    clientID,
+		Sender:    sender.User,
+		Chat:      v.Info.Chat,
+		MessageID: v.Info.ID,
+		QuotedID:  quotedID,
+		Body:      strings.TrimSpace(body),
+		Timestamp: v.Info.Timestamp,
+	})
+}
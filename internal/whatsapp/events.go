@@ -3,14 +3,10 @@ package whatsapp
 import (
 	"context"
 	"fmt"
-	"mime"
-	"os"
-	"path/filepath"
 	"strings"
 	"time"
 	"wa-server-go/internal/firestore"
 
-	waProto "go.mau.fi/whatsmeow/proto/waE2E"
 	"go.mau.fi/whatsmeow/types"
 	"go.mau.fi/whatsmeow/types/events"
 )
@@ -22,13 +18,15 @@ func (m *Manager) handleEvent(clientID string, client *Client, evt interface{})
 		if v.Codes != nil && len(v.Codes) > 0 {
 			code := v.Codes[0]
 			fmt.Printf("📸 [%s] QR Code received\n", clientID)
-			m.qrChan <- QRImageEvent{Client: clientID, URL: code}
+			m.emitQR(QRImageEvent{Client: clientID, URL: code})
 		}
 
 	case *events.Connected:
 		fmt.Printf("✅ [%s] Connected to WhatsApp\n", clientID)
 		client.SetReady(true)
-		m.statusChan <- StatusUpdate{Client: clientID, Ready: true}
+		client.ClearQRCode()
+		client.recordConnected()
+		m.emitStatus(StatusUpdate{Client: clientID, Ready: true})
 		
 		// For leads client, trigger app state sync to get labels
 		if clientID == "leads" {
@@ -44,19 +42,42 @@ func (m *Manager) handleEvent(clientID string, client *Client, evt interface{})
 		}
 
 	case *events.Disconnected:
+		// Transient: the supervisor reconnects with backoff
 		fmt.Printf("⚠️ [%s] Disconnected from WhatsApp\n", clientID)
 		client.SetReady(false)
-		m.statusChan <- StatusUpdate{Client: clientID, Ready: false, Reason: "disconnected"}
+		client.recordDisconnected("disconnected")
+		m.emitStatus(StatusUpdate{Client: clientID, Ready: false, Reason: "disconnected"})
+		m.superviseReconnect(clientID, client, "disconnected")
+
+	case *events.KeepAliveTimeout:
+		// whatsmeow only forces a reconnect on dead keepalives when its own auto-reconnect is on
+		if time.Since(v.LastSuccess) > keepAliveMaxFail && client.WAClient.IsConnected() {
+			fmt.Printf("⚠️ [%s] Keepalive failing since %s, replacing connection\n", clientID, v.LastSuccess.Format("15:04:05"))
+			client.WAClient.Disconnect()
+			client.SetReady(false)
+			client.recordDisconnected("keepalive_timeout")
+			m.emitStatus(StatusUpdate{Client: clientID, Ready: false, Reason: "keepalive_timeout"})
+			m.superviseReconnect(clientID, client, "keepalive_timeout")
+		}
 
 	case *events.LoggedOut:
-		fmt.Printf("🚪 [%s] Logged out from WhatsApp\n", clientID)
+		// Permanent: the device was unlinked, reconnecting would not help
+		fmt.Printf("🚪 [%s] Logged out from WhatsApp (%s)\n", clientID, v.Reason)
 		client.SetReady(false)
-		m.statusChan <- StatusUpdate{Client: clientID, Ready: false, Reason: "logged_out"}
+		client.recordDisconnected("logged_out")
+		m.emitStatus(StatusUpdate{Client: clientID, Ready: false, Reason: "logged_out"})
+		go m.handleLoggedOut(clientID, client, v.Reason.String())
 
 	case *events.StreamReplaced:
+		// Another connection took over this session; back off before taking it back
 		fmt.Printf("⚠️ [%s] Stream replaced (another session took over)\n", clientID)
 		client.SetReady(false)
-		m.statusChan <- StatusUpdate{Client: clientID, Ready: false, Reason: "stream_replaced"}
+		client.recordDisconnected("stream_replaced")
+		m.emitStatus(StatusUpdate{Client: clientID, Ready: false, Reason: "stream_replaced"})
+		m.superviseReconnect(clientID, client, "stream_replaced")
+
+	case *events.ConnectFailure:
+		client.recordError(fmt.Errorf("connect failure: %s", v.Reason))
 
 	case *events.PushName:
 		fmt.Printf("📝 [%s] Push name set: %s\n", clientID, v.NewPushName)
@@ -70,47 +91,21 @@ func (m *Manager) handleEvent(clientID string, client *Client, evt interface{})
 		
 		fmt.Printf("📩 [%s] New message from %s: %s (FromMe: %v)\n", clientID, v.Info.Sender.User, v.Info.ID, v.Info.IsFromMe)
 
-		// Determine message type and download media
+		// Determine message type. Media is not downloaded here: the download details are
+		// stored with the message and fetched on first access via /get-media/:messageId
 		msg := v.Message
 		msgType := "text"
-		hasMedia := false
-		var mediaURL, mediaTypeStr string
-		var err error
-
-		if msg.ImageMessage != nil {
-			msgType = "image"
-			hasMedia = true
-			mediaURL, mediaTypeStr, err = saveMedia(client, msg, v.Info.ID, v.Info.IsFromMe)
-		} else if msg.DocumentMessage != nil {
-			msgType = "document"
-			hasMedia = true
-			mediaURL, mediaTypeStr, err = saveMedia(client, msg, v.Info.ID, v.Info.IsFromMe)
-		} else if msg.AudioMessage != nil {
-			msgType = "audio"
-			hasMedia = true
-		} else if msg.VideoMessage != nil {
-			msgType = "video"
-			hasMedia = true
-		} else if msg.StickerMessage != nil {
-			msgType = "sticker"
-			hasMedia = true
-		}
-		
-		if err != nil {
-			fmt.Printf("⚠️ Failed to download media: %v\n", err)
+		media := ExtractMedia(msg)
+		if media != nil {
+			msgType = media.Type
 		}
+		hasMedia := media != nil
 
 		// Extract body
-		body := ""
-		if msg.Conversation != nil {
-			body = *msg.Conversation
-		} else if msg.ExtendedTextMessage != nil && msg.ExtendedTextMessage.Text != nil {
-			body = *msg.ExtendedTextMessage.Text
-		} else if msg.ImageMessage != nil {
-			body = "[Image] " + msg.ImageMessage.GetCaption()
-		} else if msg.DocumentMessage != nil {
-			body = "[Document] " + msg.DocumentMessage.GetFileName()
-		}
+		body := MessageBody(msg)
+
+		// Replies from registered senders (e.g. blog review) are handled as commands
+		m.dispatchCommand(clientID, client, v, body)
 
 		// Resolve Contact Name
 		senderName := resolveContactName(client, v.Info.Sender)
@@ -119,7 +114,7 @@ func (m *Manager) handleEvent(clientID string, client *Client, evt interface{})
 		}
 
 		// Send to websocket
-		m.msgChan <- NewMessageEvent{
+		m.emitMessage(NewMessageEvent{
 			Client:    clientID,
 			ID:        v.Info.ID,
 			From:      v.Info.Sender.String(),
@@ -131,11 +126,11 @@ func (m *Manager) handleEvent(clientID string, client *Client, evt interface{})
 			ChatName:  senderName,
 			HasMedia:  hasMedia,
 			Type:      msgType,
-		}
+		})
 
 		// Save to Firestore if Repo is configured
-		if m.Repo != nil {
-			go func() {
+		if repo := m.RepoFor(clientID); repo != nil {
+			m.Go(func() {
 				waMsg := &firestore.WAMessage{
 					MessageID: v.Info.ID,
 					ChatID:    v.Info.Chat.String(),
@@ -143,11 +138,10 @@ func (m *Manager) handleEvent(clientID string, client *Client, evt interface{})
 					Timestamp: v.Info.Timestamp,
 					FromMe:    v.Info.IsFromMe,
 					HasMedia:  hasMedia,
-					MediaType: mediaTypeStr,
-					MediaURL:  mediaURL,
 					Type:      msgType,
 					Ack:       1,
 				}
+				applyMedia(waMsg, media)
 
 				if v.Info.IsFromMe {
 					waMsg.From = v.Info.Sender.ToNonAD().String() // Use actual sender ID
@@ -164,20 +158,60 @@ func (m *Manager) handleEvent(clientID string, client *Client, evt interface{})
 					waMsg.To = client.WAClient.Store.ID.ToNonAD().String()
 				}
 
-				if err := m.Repo.SaveMessage(context.Background(), waMsg); err != nil {
+				if err := repo.SaveMessage(context.Background(), waMsg); err != nil {
 					fmt.Printf("❌ Failed to save message to Firestore: %v\n", err)
 				} else {
 					fmt.Printf("💾 Message saved to Firestore: %s\n", waMsg.MessageID)
 					// Update Chat Name if provided
 					if senderName != "" {
-						_ = m.Repo.UpdateChatName(context.Background(), waMsg.ChatID, senderName)
+						_ = repo.UpdateChatName(context.Background(), waMsg.ChatID, senderName)
 					}
 				}
-			}()
+			})
 		}
 
 	case *events.Receipt:
 		// Message delivery/read receipts
+		if clientID == "leads" {
+			return
+		}
+		ack := receiptToAck(v.Type)
+		if ack == 0 || v.IsFromMe {
+			// Receipts from our own devices (sender, read-self, ...) don't change what the recipient saw
+			return
+		}
+
+		m.Go(func() {
+			ids := make([]string, 0, len(v.MessageIDs))
+			for _, id := range v.MessageIDs {
+				ids = append(ids, string(id))
+			}
+
+			if repo := m.RepoFor(clientID); repo != nil {
+				updated, err := repo.UpdateMessageAck(context.Background(), ids, ack)
+				if err != nil {
+					fmt.Printf("⚠️ [%s] Failed to update message ack: %v\n", clientID, err)
+				}
+				if len(updated) == 0 {
+					return
+				}
+				ids = updated
+			}
+
+			fmt.Printf("✔️ [%s] %d message(s) in %s now at ack %d\n", clientID, len(ids), v.Chat.String(), ack)
+			select {
+			case m.ackChan <- MessageAckEvent{
+				Client:    clientID,
+				ChatID:    v.Chat.String(),
+				IDs:       ids,
+				Ack:       ack,
+				Timestamp: v.Timestamp.Unix(),
+			}:
+			case <-m.done:
+			default:
+				fmt.Println("⚠️ Ack channel full, dropping broadcast")
+			}
+		})
 
 	case *events.HistorySync:
 		// PRIVACY UPDATE: Ignore history sync from "leads" client
@@ -187,8 +221,8 @@ func (m *Manager) handleEvent(clientID string, client *Client, evt interface{})
 
 		fmt.Printf("📜 [%s] History sync received. Processing past messages...\n", clientID)
 
-		if m.Repo != nil && v.Data != nil {
-			go func() {
+		if repo := m.RepoFor(clientID); repo != nil && v.Data != nil {
+			m.Go(func() {
 				count := 0
 				for _, conv := range v.Data.Conversations {
 					for _, histMsg := range conv.Messages {
@@ -198,31 +232,15 @@ func (m *Manager) handleEvent(clientID string, client *Client, evt interface{})
 						}
 						msg := webMsg.Message
 
-						// Type & Media
+						// Type & Media (downloaded lazily, like live messages)
 						msgType := "text"
-						hasMedia := false
-						var mediaURL, mediaTypeStr string
-
-						// We don't download history media automatically to save bandwidth
-						if msg.ImageMessage != nil {
-							msgType = "image"
-							hasMedia = true
-						} else if msg.DocumentMessage != nil {
-							msgType = "document"
-							hasMedia = true
+						media := ExtractMedia(msg)
+						if media != nil {
+							msgType = media.Type
 						}
 
 						// Body
-						body := ""
-						if msg.Conversation != nil {
-							body = *msg.Conversation
-						} else if msg.ExtendedTextMessage != nil && msg.ExtendedTextMessage.Text != nil {
-							body = *msg.ExtendedTextMessage.Text
-						} else if msg.ImageMessage != nil {
-							body = "[Image] " + msg.ImageMessage.GetCaption()
-						} else if msg.DocumentMessage != nil {
-							body = "[Document] " + msg.DocumentMessage.GetFileName()
-						}
+						body := MessageBody(msg)
 
 						ts := int64(webMsg.GetMessageTimestamp())
 						waMsg := &firestore.WAMessage{
@@ -231,28 +249,42 @@ func (m *Manager) handleEvent(clientID string, client *Client, evt interface{})
 							Body:      body,
 							Timestamp: time.Unix(ts, 0),
 							FromMe:    webMsg.Key.GetFromMe(),
-							HasMedia:  hasMedia,
-							MediaType: mediaTypeStr,
-							MediaURL:  mediaURL,
+							HasMedia:  media != nil,
 							Type:      msgType,
 							Ack:       3, // Read/Played
 						}
+						applyMedia(waMsg, media)
 
 						if waMsg.FromMe {
-							waMsg.From = m.clients[clientID].WAClient.Store.ID.ToNonAD().String()
+							waMsg.From = client.WAClient.Store.ID.ToNonAD().String()
 							waMsg.To = conv.GetID()
 						} else {
 							waMsg.From = conv.GetID()
-							waMsg.To = m.clients[clientID].WAClient.Store.ID.ToNonAD().String()
+							waMsg.To = client.WAClient.Store.ID.ToNonAD().String()
 						}
 
 						// Save without waiting
-						_ = m.Repo.SaveMessage(context.Background(), waMsg)
+						_ = repo.SaveMessage(context.Background(), waMsg)
 						count++
 					}
 				}
 				fmt.Printf("✅ [%s] History sync processed: Saved %d past messages\n", clientID, count)
-			}()
+			})
+		}
+
+	case *events.MarkChatAsRead:
+		// Chat marked read or unread on the phone (or another linked device)
+		if clientID == "leads" {
+			return
+		}
+		if repo := m.RepoFor(clientID); repo != nil {
+			m.Go(func() {
+				read := v.Action.GetRead()
+				err := repo.SetChatRead(context.Background(), v.JID.String(), read, v.Timestamp)
+				if err != nil && err != firestore.ErrChatNotFound {
+					fmt.Printf("⚠️ [%s] Failed to update read state of %s: %v\n", clientID, v.JID.String(), err)
+				}
+			})
 		}
 
 	case *events.AppState:
@@ -320,6 +352,20 @@ func (m *Manager) handleEvent(clientID string, client *Client, evt interface{})
 	}
 }
 
+// receiptToAck maps a receipt type to a WAMessage.Ack level (0 = ignore)
+func receiptToAck(receiptType types.ReceiptType) int {
+	switch receiptType {
+	case types.ReceiptTypeDelivered:
+		return firestore.AckDevice
+	case types.ReceiptTypeRead:
+		return firestore.AckRead
+	case types.ReceiptTypePlayed:
+		return firestore.AckPlayed
+	default:
+		return 0
+	}
+}
+
 // resolveContactName looks up a JID in the store
 func resolveContactName(client *Client, jid types.JID) string {
 	contacts, err := client.WAClient.Store.Contacts.GetContact(context.Background(), jid)
@@ -338,83 +384,19 @@ func resolveContactName(client *Client, jid types.JID) string {
 	return ""
 }
 
-// saveMedia downloads media from message and saves to disk
-func saveMedia(client *Client, msg *waProto.Message, id string, isFromMe bool) (string, string, error) {
-	var ext string
-	var mimeType string
-
-	// 1. Determine extension and mime type
-	if msg.ImageMessage != nil {
-		ext = ".jpg"
-		mimeType = msg.ImageMessage.GetMimetype()
-	} else if msg.DocumentMessage != nil {
-		// Extract extension from filename or mimetype
-		fileName := msg.DocumentMessage.GetFileName()
-		ext = filepath.Ext(fileName)
-		if ext == "" {
-			exts, _ := mime.ExtensionsByType(msg.DocumentMessage.GetMimetype())
-			if len(exts) > 0 {
-				ext = exts[0]
-			} else {
-				ext = ".bin"
-			}
-		}
-		mimeType = msg.DocumentMessage.GetMimetype()
-	} else {
-		return "", "", fmt.Errorf("unsupported media type")
+// applyMedia copies attachment download details onto a stored message
+func applyMedia(waMsg *firestore.WAMessage, media *MediaInfo) {
+	if media == nil {
+		return
 	}
-
-	// 2. Prepare paths
-	uploadsDir := "./uploads/media"
-	if err := os.MkdirAll(uploadsDir, 0755); err != nil {
-		return "", "", err
-	}
-
-	filename := fmt.Sprintf("%s%s", id, ext)
-	localPath := filepath.Join(uploadsDir, filename)
-
-	// 3. Check if file already exists (e.g. from outgoing send)
-	// Retry logic for outgoing messages to handle race condition
-	attempts := 1
-	if isFromMe {
-		attempts = 3
-	}
-
-	for i := 0; i < attempts; i++ {
-		if _, err := os.Stat(localPath); err == nil {
-			fmt.Printf("📂 Media already exists locally for %s, skipping download (attempt %d).\n", id, i+1)
-			return fmt.Sprintf("/uploads/media/%s", filename), mimeType, nil
-		}
-		if isFromMe && i < attempts-1 {
-			fmt.Printf("⏳ Outgoing media not found yet, waiting... (attempt %d)\n", i+1)
-			time.Sleep(500 * time.Millisecond)
-		}
-	}
-
-	// 4. Download if not exists
-	fmt.Printf("🎬 Start downloading media for msg %s\n", id)
-	var payload []byte
-	var err error
-
-	if msg.ImageMessage != nil {
-		payload, err = client.WAClient.Download(context.Background(), msg.ImageMessage)
-	} else if msg.DocumentMessage != nil {
-		payload, err = client.WAClient.Download(context.Background(), msg.DocumentMessage)
-	}
-
-	if err != nil {
-		fmt.Printf("❌ Error downloading media %s: %v\n", id, err)
-		return "", "", err
-	}
-
-	fmt.Printf("✅ Media downloaded successfully for %s, saving to disk...\n", id)
-
-	if err := os.WriteFile(localPath, payload, 0644); err != nil {
-		return "", "", err
-	}
-
-	// Return URL relative to server root
-	return fmt.Sprintf("/uploads/media/%s", filename), mimeType, nil
+	waMsg.MediaType = media.MimeType
+	waMsg.MediaURL = "/get-media/" + waMsg.MessageID
+	waMsg.MediaFileName = media.FileName
+	waMsg.MediaDirectPath = media.DirectPath
+	waMsg.MediaKey = media.MediaKey
+	waMsg.MediaFileSHA256 = media.FileSHA256
+	waMsg.MediaFileEncSHA256 = media.FileEncSHA256
+	waMsg.MediaFileLength = media.FileLength
 }
 
 // truncate shortens a string for logging
@@ -12,22 +12,52 @@ type Manager struct {
 	clients    map[string]*Client
 	Repo       *firestore.ChatsRepository
 	LabelStore *LabelStore
-	mu         sync.RWMutex
-	qrChan     chan QRImageEvent
-	statusChan chan StatusUpdate
-	msgChan    chan NewMessageEvent
+
+	// DefaultClientID is the session used when a request does not name one
+	DefaultClientID string
+	dataDir         string
+
+	mu           sync.RWMutex
+	qrChan       chan QRImageEvent
+	pairCodeChan chan PairCodeEvent
+	statusChan   chan StatusUpdate
+	msgChan      chan NewMessageEvent
+	ackChan      chan MessageAckEvent
+	chatChan     chan ChatUpdateEvent
+
+	// Text messages from registered senders, see HandleCommands
+	cmdMu    sync.RWMutex
+	commands map[string]CommandHandler
+
+	// Shutdown: event channels are never closed (senders may still be running);
+	// done tells readers and blocked senders to stop instead
+	done      chan struct{}
+	closeOnce sync.Once
+	closing   bool
+	writes    sync.WaitGroup
 }
 
-// NewManager creates a new client manager
-func NewManager(repo *firestore.ChatsRepository) *Manager {
-	return &Manager{
-		clients:    make(map[string]*Client),
-		Repo:       repo,
-		LabelStore: NewLabelStore(),
-		qrChan:     make(chan QRImageEvent, 10),
-		statusChan: make(chan StatusUpdate, 10),
-		msgChan:    make(chan NewMessageEvent, 100),
+// NewManager creates a new client manager. Session stores are kept in dataDir.
+func NewManager(repo *firestore.ChatsRepository, dataDir string, defaultClientID string) *Manager {
+	m := &Manager{
+		clients:         make(map[string]*Client),
+		Repo:            repo,
+		LabelStore:      NewLabelStore(),
+		DefaultClientID: defaultClientID,
+		dataDir:         dataDir,
+		qrChan:          make(chan QRImageEvent, 10),
+		pairCodeChan:    make(chan PairCodeEvent, 10),
+		statusChan:      make(chan StatusUpdate, 10),
+		msgChan:         make(chan NewMessageEvent, 100),
+		ackChan:         make(chan MessageAckEvent, 100),
+		chatChan:        make(chan ChatUpdateEvent, 100),
+		commands:        make(map[string]CommandHandler),
+		done:            make(chan struct{}),
 	}
+	if repo != nil {
+		repo.OnChatUpdate(m.emitChatUpdate)
+	}
+	return m
 }
 
 // CreateClient creates and registers a new WhatsApp client
@@ -76,17 +106,20 @@ func (m *Manager) Connect(ctx context.Context, clientID string) error {
 	qrChan := make(chan string, 5)
 	statusChan := make(chan StatusUpdate, 5)
 
-	// Forward QR events
+	// Forward QR events (client.Connect closes qrChan when pairing is over)
 	go func() {
 		for qr := range qrChan {
-			m.qrChan <- QRImageEvent{Client: clientID, URL: qr}
+			m.emitQR(QRImageEvent{Client: clientID, URL: qr})
 		}
 	}()
 
 	// Connect client
 	err := client.Connect(ctx, qrChan, statusChan)
 	if err != nil {
-		m.statusChan <- StatusUpdate{Client: clientID, Ready: false, Error: err.Error()}
+		client.recordError(err)
+		m.emitStatus(StatusUpdate{Client: clientID, Ready: false, Error: err.Error()})
+		// A paired session that can't reach WhatsApp (e.g. network down at boot) keeps retrying
+		m.superviseReconnect(clientID, client, "connect_failed")
 		return err
 	}
 
@@ -114,7 +147,7 @@ func (m *Manager) Disconnect(clientID string) error {
 		return fmt.Errorf("client %s not found", clientID)
 	}
 	client.Disconnect()
-	m.statusChan <- StatusUpdate{Client: clientID, Ready: false, Reason: "disconnected"}
+	m.emitStatus(StatusUpdate{Client: clientID, Ready: false, Reason: "disconnected"})
 	return nil
 }
 
@@ -138,6 +171,11 @@ func (m *Manager) QRChannel() <-chan QRImageEvent {
 	return m.qrChan
 }
 
+// PairCodeChannel returns the channel for pairing code events
+func (m *Manager) PairCodeChannel() <-chan PairCodeEvent {
+	return m.pairCodeChan
+}
+
 // StatusChannel returns the channel for status events
 func (m *Manager) StatusChannel() <-chan StatusUpdate {
 	return m.statusChan
@@ -148,15 +186,40 @@ func (m *Manager) MessageChannel() <-chan NewMessageEvent {
 	return m.msgChan
 }
 
+// AckChannel returns the channel for message ack events
+func (m *Manager) AckChannel() <-chan MessageAckEvent {
+	return m.ackChan
+}
+
 // BroadcastMessage allows external packages to broadcast messages via WebSocket
 func (m *Manager) BroadcastMessage(evt NewMessageEvent) {
 	select {
 	case m.msgChan <- evt:
+	case <-m.done:
 	default:
 		fmt.Println("⚠️ Message channel full, dropping broadcast")
 	}
 }
 
+// ChatUpdateChannel returns the channel for chat update events (tags, read state)
+func (m *Manager) ChatUpdateChannel() <-chan ChatUpdateEvent {
+	return m.chatChan
+}
+
+// emitChatUpdate forwards a change reported by the chat repository
+func (m *Manager) emitChatUpdate(u firestore.ChatUpdate) {
+	client := u.Session
+	if client == "" {
+		client = m.DefaultClientID
+	}
+	select {
+	case m.chatChan <- ChatUpdateEvent{Client: client, ID: u.JID, Fields: u.Fields}:
+	case <-m.done:
+	default:
+		fmt.Println("⚠️ Chat update channel full, dropping broadcast")
+	}
+}
+
 // GetAllStatus returns status of all clients
 func (m *Manager) GetAllStatus() map[string]interface{} {
 	m.mu.RLock()
@@ -165,24 +228,88 @@ func (m *Manager) GetAllStatus() map[string]interface{} {
 	status := make(map[string]interface{})
 	for id, client := range m.clients {
 		status[id] = map[string]interface{}{
-			"ready": client.IsReady(),
-			"qr":    client.GetQRCode(),
+			"ready":  client.IsReady(),
+			"qr":     client.GetQRCode(),
+			"health": client.Health(),
 		}
 	}
 	return status
 }
 
-// Close cleans up all clients
-func (m *Manager) Close() {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+// Go runs fn in the background (repository writes, broadcasts) and lets Close wait for it
+func (m *Manager) Go(fn func()) {
+	m.mu.RLock()
+	if m.closing {
+		// Close is already waiting: run inline so the work is not lost
+		m.mu.RUnlock()
+		fn()
+		return
+	}
+	m.writes.Add(1)
+	m.mu.RUnlock()
 
-	for _, client := range m.clients {
-		client.Close()
+	go func() {
+		defer m.writes.Done()
+		fn()
+	}()
+}
+
+// Done is closed once the manager has shut down
+func (m *Manager) Done() <-chan struct{} {
+	return m.done
+}
+
+func (m *Manager) emitQR(evt QRImageEvent) {
+	select {
+	case m.qrChan <- evt:
+	case <-m.done:
+	}
+}
+
+func (m *Manager) emitStatus(update StatusUpdate) {
+	select {
+	case m.statusChan <- update:
+	case <-m.done:
 	}
+}
+
+func (m *Manager) emitMessage(evt NewMessageEvent) {
+	select {
+	case m.msgChan <- evt:
+	case <-m.done:
+	}
+}
+
+// Close disconnects all clients, waits (until ctx expires) for background writes started
+// with Go, then closes the session stores and signals Done
+func (m *Manager) Close(ctx context.Context) error {
+	m.mu.Lock()
+	m.closing = true
+	clients := m.clients
 	m.clients = make(map[string]*Client)
+	m.mu.Unlock()
+
+	// Stop new events before waiting for the ones in flight
+	for _, client := range clients {
+		client.Disconnect()
+	}
+
+	waited := make(chan struct{})
+	go func() {
+		m.writes.Wait()
+		close(waited)
+	}()
+
+	var err error
+	select {
+	case <-waited:
+	case <-ctx.Done():
+		err = fmt.Errorf("gave up waiting for background writes: %w", ctx.Err())
+	}
 
-	close(m.qrChan)
-	close(m.statusChan)
-	close(m.msgChan)
+	for _, client := range clients {
+		client.Close()
+	}
+	m.closeOnce.Do(func() { close(m.done) })
+	return err
 }
@@ -0,0 +1,189 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"mime"
+
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/proto/waE2E"
+)
+
+// MediaInfo holds everything needed to download and decrypt an attachment later
+type MediaInfo struct {
+	Type          string // image, video, audio, document, sticker
+	MimeType      string
+	FileName      string
+	Caption       string
+	DirectPath    string
+	MediaKey      []byte
+	FileSHA256    []byte
+	FileEncSHA256 []byte
+	FileLength    int64
+}
+
+// ExtractMedia returns the attachment of a message, or nil if it has none
+func ExtractMedia(msg *waProto.Message) *MediaInfo {
+	if msg == nil {
+		return nil
+	}
+
+	switch {
+	case msg.ImageMessage != nil:
+		m := msg.ImageMessage
+		return &MediaInfo{Type: "image", MimeType: m.GetMimetype(), Caption: m.GetCaption(),
+			DirectPath: m.GetDirectPath(), MediaKey: m.GetMediaKey(), FileSHA256: m.GetFileSHA256(),
+			FileEncSHA256: m.GetFileEncSHA256(), FileLength: int64(m.GetFileLength())}
+	case msg.VideoMessage != nil:
+		m := msg.VideoMessage
+		return &MediaInfo{Type: "video", MimeType: m.GetMimetype(), Caption: m.GetCaption(),
+			DirectPath: m.GetDirectPath(), MediaKey: m.GetMediaKey(), FileSHA256: m.GetFileSHA256(),
+			FileEncSHA256: m.GetFileEncSHA256(), FileLength: int64(m.GetFileLength())}
+	case msg.AudioMessage != nil:
+		m := msg.AudioMessage
+		return &MediaInfo{Type: "audio", MimeType: m.GetMimetype(),
+			DirectPath: m.GetDirectPath(), MediaKey: m.GetMediaKey(), FileSHA256: m.GetFileSHA256(),
+			FileEncSHA256: m.GetFileEncSHA256(), FileLength: int64(m.GetFileLength())}
+	case msg.StickerMessage != nil:
+		m := msg.StickerMessage
+		return &MediaInfo{Type: "sticker", MimeType: m.GetMimetype(),
+			DirectPath: m.GetDirectPath(), MediaKey: m.GetMediaKey(), FileSHA256: m.GetFileSHA256(),
+			FileEncSHA256: m.GetFileEncSHA256(), FileLength: int64(m.GetFileLength())}
+	case msg.DocumentMessage != nil:
+		return documentInfo(msg.DocumentMessage)
+	case msg.DocumentWithCaptionMessage.GetMessage().GetDocumentMessage() != nil:
+		// Documents sent with a caption are wrapped in a FutureProofMessage
+		return documentInfo(msg.DocumentWithCaptionMessage.GetMessage().GetDocumentMessage())
+	}
+	return nil
+}
+
+func documentInfo(m *waProto.DocumentMessage) *MediaInfo {
+	return &MediaInfo{Type: "document", MimeType: m.GetMimetype(), FileName: m.GetFileName(), Caption: m.GetCaption(),
+		DirectPath: m.GetDirectPath(), MediaKey: m.GetMediaKey(), FileSHA256: m.GetFileSHA256(),
+		FileEncSHA256: m.GetFileEncSHA256(), FileLength: int64(m.GetFileLength())}
+}
+
+// MessageBody returns the text shown for a message in chat lists and history
+func MessageBody(msg *waProto.Message) string {
+	if msg.Conversation != nil {
+		return *msg.Conversation
+	}
+	if msg.ExtendedTextMessage != nil && msg.ExtendedTextMessage.Text != nil {
+		return *msg.ExtendedTextMessage.Text
+	}
+
+	media := ExtractMedia(msg)
+	if media == nil {
+		return ""
+	}
+	switch media.Type {
+	case "image":
+		return "[Image] " + media.Caption
+	case "video":
+		return "[Video] " + media.Caption
+	case "audio":
+		return "[Audio]"
+	case "sticker":
+		return "[Sticker]"
+	default:
+		return "[Document] " + media.FileName
+	}
+}
+
+// mediaExtensions lists the content types attachments are stored and served as, with their
+// file extension. Both the file name and the MIME type of a message are chosen by the sender,
+// so anything else (HTML, SVG, ...) is kept as an opaque .bin file.
+var mediaExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+	"image/gif":  ".gif",
+	"video/mp4":  ".mp4",
+	"video/3gpp": ".3gp",
+	"audio/ogg":  ".ogg",
+	"audio/mpeg": ".mp3",
+	"audio/mp4":  ".m4a",
+	"audio/aac":  ".aac",
+	"audio/amr":  ".amr",
+
+	"application/pdf":               ".pdf",
+	"application/msword":            ".doc",
+	"application/vnd.ms-excel":      ".xls",
+	"application/vnd.ms-powerpoint": ".ppt",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document":   ".docx",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         ".xlsx",
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": ".pptx",
+	"application/zip": ".zip",
+	"text/plain":      ".txt",
+	"text/csv":        ".csv",
+}
+
+// defaultMediaTypes is assumed for attachments whose MIME type is missing or not allowed
+var defaultMediaTypes = map[string]string{
+	"image":   "image/jpeg",
+	"sticker": "image/webp",
+	"video":   "video/mp4",
+	"audio":   "audio/ogg",
+}
+
+// ContentType returns the attachment's MIME type if it is an allowed media type,
+// otherwise a default for the attachment type (application/octet-stream for documents)
+func (mi *MediaInfo) ContentType() string {
+	return MediaContentType(mi.MimeType, mi.Type)
+}
+
+// Extension returns the file extension to store the attachment under. It follows
+// ContentType and never the sender's file name.
+func (mi *MediaInfo) Extension() string {
+	return MediaExtension(mi.ContentType())
+}
+
+// MediaContentType returns mimeType if it is an allowed media type, otherwise a default for
+// the message type (image, video, ...; application/octet-stream for documents).
+// Incoming and outgoing media are stored under the same rules.
+func MediaContentType(mimeType, msgType string) string {
+	if mt, _, err := mime.ParseMediaType(mimeType); err == nil {
+		if _, ok := mediaExtensions[mt]; ok {
+			return mt
+		}
+	}
+	if mt, ok := defaultMediaTypes[msgType]; ok {
+		return mt
+	}
+	return "application/octet-stream"
+}
+
+// MediaExtension returns the file extension of an allowed media type, .bin for anything else
+func MediaExtension(contentType string) string {
+	if ext, ok := mediaExtensions[contentType]; ok {
+		return ext
+	}
+	return ".bin"
+}
+
+func (mi *MediaInfo) whatsmeowType() whatsmeow.MediaType {
+	switch mi.Type {
+	case "image", "sticker":
+		return whatsmeow.MediaImage
+	case "video":
+		return whatsmeow.MediaVideo
+	case "audio":
+		return whatsmeow.MediaAudio
+	default:
+		return whatsmeow.MediaDocument
+	}
+}
+
+// DownloadMedia fetches and decrypts an attachment from the WhatsApp media servers
+func (c *Client) DownloadMedia(ctx context.Context, info *MediaInfo) ([]byte, error) {
+	if info.DirectPath == "" || len(info.MediaKey) == 0 {
+		return nil, fmt.Errorf("message has no downloadable media")
+	}
+	length := int(info.FileLength)
+	if length <= 0 {
+		length = -1
+	}
+	return c.WAClient.DownloadMediaWithPath(ctx, info.DirectPath, info.FileEncSHA256, info.FileSHA256,
+		info.MediaKey, length, info.whatsmeowType(), "")
+}
@@ -0,0 +1,183 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"wa-server-go/internal/firestore"
+)
+
+// ErrAlreadyPaired is returned when pairing a session that is already linked to an account
+var ErrAlreadyPaired = errors.New("session is already paired")
+
+// validSessionID limits session IDs to characters that are safe in file and collection names
+var validSessionID = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,32}$`)
+
+// ValidSessionID reports whether id can be used as a session ID
+func ValidSessionID(id string) bool {
+	return validSessionID.MatchString(id)
+}
+
+// SessionPath returns the SQLite store path of a session
+func (m *Manager) SessionPath(clientID string) string {
+	return filepath.Join(m.dataDir, "session-"+clientID+".db")
+}
+
+// RepoFor returns the chat repository of a session. The default session keeps the
+// original collections; other sessions get their own so departments don't see each other's chats.
+func (m *Manager) RepoFor(clientID string) *firestore.ChatsRepository {
+	if m.Repo == nil || clientID == m.DefaultClientID {
+		return m.Repo
+	}
+	return m.Repo.ForSession(clientID)
+}
+
+// ClientIDs returns the IDs of all loaded clients, sorted
+func (m *Manager) ClientIDs() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ids := make([]string, 0, len(m.clients))
+	for id := range m.clients {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// StartSession loads a session (creating its store if needed), wires its events and connects it
+func (m *Manager) StartSession(ctx context.Context, clientID string) error {
+	if !ValidSessionID(clientID) {
+		return fmt.Errorf("invalid session ID %q", clientID)
+	}
+	if err := m.CreateClient(ctx, clientID, m.SessionPath(clientID)); err != nil {
+		return err
+	}
+	if err := m.SetupEventHandlers(clientID); err != nil {
+		return err
+	}
+
+	go func() {
+		if err := m.Connect(context.Background(), clientID); err != nil {
+			log.Printf("❌ [%s] Failed to connect: %v", clientID, err)
+		}
+	}()
+	return nil
+}
+
+// RestoreSessions starts every session store found in the data directory, except the
+// already loaded ones and those listed in skip (e.g. on-demand clients)
+func (m *Manager) RestoreSessions(ctx context.Context, skip ...string) {
+	matches, err := filepath.Glob(filepath.Join(m.dataDir, "session-*.db"))
+	if err != nil {
+		log.Printf("⚠️ Failed to scan for sessions: %v", err)
+		return
+	}
+
+	for _, path := range matches {
+		id := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), "session-"), ".db")
+		if !ValidSessionID(id) || containsString(skip, id) {
+			continue
+		}
+		if _, loaded := m.GetClient(id); loaded {
+			continue
+		}
+
+		if err := m.StartSession(ctx, id); err != nil {
+			log.Printf("⚠️ [%s] Failed to restore session: %v", id, err)
+			continue
+		}
+		log.Printf("🔁 [%s] Session restored", id)
+	}
+}
+
+// PairPhone starts phone-number pairing for an unpaired session and returns the code to
+// enter on the phone (WhatsApp > Linked devices > Link with phone number instead)
+func (m *Manager) PairPhone(ctx context.Context, clientID string, phone string) (string, error) {
+	client, ok := m.GetClient(clientID)
+	if !ok {
+		return "", fmt.Errorf("client %s not found", clientID)
+	}
+	if client.IsLoggedIn() {
+		return "", ErrAlreadyPaired
+	}
+
+	// Pairing codes ride on the same connection as the QR flow. Not tied to ctx:
+	// the QR channel disconnects the client when its context ends.
+	if !client.WAClient.IsConnected() {
+		if err := m.Connect(context.Background(), clientID); err != nil {
+			return "", err
+		}
+	}
+
+	code, err := client.PairPhone(ctx, phone)
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Printf("🔢 [%s] Pairing code generated for %s\n", clientID, phone)
+	select {
+	case m.pairCodeChan <- PairCodeEvent{Client: clientID, Phone: phone, Code: code}:
+	case <-m.done:
+	default:
+		fmt.Println("⚠️ Pair code channel full, dropping broadcast")
+	}
+	return code, nil
+}
+
+// Logout unlinks a session from its WhatsApp account and resets it to a fresh, unpaired store
+func (m *Manager) Logout(ctx context.Context, clientID string) error {
+	client, ok := m.GetClient(clientID)
+	if !ok {
+		return fmt.Errorf("client %s not found", clientID)
+	}
+
+	// Keep the supervisor and the LoggedOut handler out of the way
+	client.setStopped(true)
+
+	if client.IsLoggedIn() && client.WAClient.IsConnected() {
+		if err := client.WAClient.Logout(ctx); err != nil {
+			return err
+		}
+	}
+	// Offline sessions are forgotten locally by resetSession; the phone will show the device as inactive
+	if err := m.resetSession(ctx, clientID); err != nil {
+		return err
+	}
+
+	m.emitStatus(StatusUpdate{Client: clientID, Ready: false, Reason: "logged_out"})
+	return nil
+}
+
+// DeleteSession disconnects a session and removes its SQLite store
+func (m *Manager) DeleteSession(clientID string) error {
+	if err := m.DestroyClient(clientID); err != nil {
+		return err
+	}
+
+	path := m.SessionPath(clientID)
+	for _, p := range []string{path, path + "-wal", path + "-shm"} {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", p, err)
+		}
+	}
+
+	m.emitStatus(StatusUpdate{Client: clientID, Ready: false, Reason: "deleted"})
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
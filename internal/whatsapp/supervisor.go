@@ -0,0 +1,222 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+)
+
+const (
+	minReconnectDelay = 2 * time.Second
+	maxReconnectDelay = 5 * time.Minute
+
+	// keepAliveMaxFail mirrors whatsmeow's KeepAliveMaxFailTime: after this long without a
+	// successful keepalive the socket is considered dead and is replaced
+	keepAliveMaxFail = 3 * time.Minute
+)
+
+// ClientHealth is the connection history of a client, reported by /status
+type ClientHealth struct {
+	LastConnectedAt      *time.Time `json:"lastConnectedAt"`
+	LastDisconnectedAt   *time.Time `json:"lastDisconnectedAt"`
+	LastDisconnectReason string     `json:"lastDisconnectReason,omitempty"`
+	ReconnectCount       int        `json:"reconnectCount"`    // successful reconnects since startup
+	ReconnectAttempts    int        `json:"reconnectAttempts"` // failed attempts in the current outage
+	Reconnecting         bool       `json:"reconnecting"`
+	NextReconnectAt      *time.Time `json:"nextReconnectAt,omitempty"`
+	LastError            string     `json:"lastError,omitempty"`
+}
+
+// superviseReconnect brings a dropped, paired client back with exponential backoff.
+// whatsmeow's own auto-reconnect is disabled so there is only one reconnect loop per client.
+func (m *Manager) superviseReconnect(clientID string, client *Client, reason string) {
+	if !client.beginReconnect() {
+		return
+	}
+
+	go func() {
+		defer client.endReconnect()
+
+		for {
+			delay := client.nextReconnectDelay()
+			fmt.Printf("🔄 [%s] Reconnecting in %s (%s)\n", clientID, delay, reason)
+			time.Sleep(delay)
+
+			if client.isStopped() || !m.isCurrent(clientID, client) || !client.IsLoggedIn() {
+				fmt.Printf("🛑 [%s] Reconnect cancelled\n", clientID)
+				return
+			}
+
+			err := client.WAClient.Connect()
+			if err == nil || errors.Is(err, whatsmeow.ErrAlreadyConnected) {
+				// The Connected event resets the backoff; if the handshake fails,
+				// a new Disconnected event starts the next round with a longer delay
+				return
+			}
+
+			client.recordReconnectFailure(err)
+			fmt.Printf("⚠️ [%s] Reconnect failed: %v\n", clientID, err)
+		}
+	}()
+}
+
+// handleLoggedOut resets a session whose device was unlinked from the phone (or banned).
+// This is permanent: the device store is wiped and a fresh QR is offered instead of reconnecting.
+func (m *Manager) handleLoggedOut(clientID string, client *Client, reason string) {
+	if client.isStopped() || !m.isCurrent(clientID, client) {
+		// Logout requested through the API; Manager.Logout does the reset itself
+		return
+	}
+
+	client.recordError(fmt.Errorf("logged out: %s", reason))
+	if err := m.resetSession(context.Background(), clientID); err != nil {
+		fmt.Printf("❌ [%s] Failed to reset session after logout: %v\n", clientID, err)
+		return
+	}
+	fmt.Printf("🧹 [%s] Session store wiped after logout, waiting to be paired again\n", clientID)
+
+	if err := m.Connect(context.Background(), clientID); err != nil {
+		fmt.Printf("❌ [%s] Failed to start pairing after logout: %v\n", clientID, err)
+	}
+}
+
+// resetSession deletes the paired device of a session and reloads it with a fresh, unpaired store
+func (m *Manager) resetSession(ctx context.Context, clientID string) error {
+	client, ok := m.GetClient(clientID)
+	if !ok {
+		return fmt.Errorf("client %s not found", clientID)
+	}
+
+	if client.IsLoggedIn() {
+		if err := client.Device.Delete(ctx); err != nil {
+			return fmt.Errorf("failed to delete device: %w", err)
+		}
+	}
+
+	if err := m.DestroyClient(clientID); err != nil {
+		return err
+	}
+	if err := m.CreateClient(ctx, clientID, m.SessionPath(clientID)); err != nil {
+		return err
+	}
+	return m.SetupEventHandlers(clientID)
+}
+
+// isCurrent reports whether client is still the registered client for clientID
+func (m *Manager) isCurrent(clientID string, client *Client) bool {
+	current, ok := m.GetClient(clientID)
+	return ok && current == client
+}
+
+// Health returns a snapshot of the client's connection history
+func (c *Client) Health() ClientHealth {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	h := ClientHealth{
+		LastDisconnectReason: c.lastDisconnectReason,
+		ReconnectCount:       c.reconnectCount,
+		ReconnectAttempts:    c.reconnectAttempts,
+		Reconnecting:         c.reconnecting,
+		LastError:            c.lastError,
+	}
+	if !c.lastConnectedAt.IsZero() {
+		t := c.lastConnectedAt
+		h.LastConnectedAt = &t
+	}
+	if !c.lastDisconnectedAt.IsZero() {
+		t := c.lastDisconnectedAt
+		h.LastDisconnectedAt = &t
+	}
+	if c.reconnecting && !c.nextReconnectAt.IsZero() {
+		t := c.nextReconnectAt
+		h.NextReconnectAt = &t
+	}
+	return h
+}
+
+// recordConnected resets the backoff once a connection is established
+func (c *Client) recordConnected() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.lastConnectedAt.IsZero() {
+		c.reconnectCount++
+	}
+	c.lastConnectedAt = time.Now()
+	c.reconnectAttempts = 0
+	c.reconnectDelay = 0
+}
+
+// recordDisconnected notes when and why the connection dropped
+func (c *Client) recordDisconnected(reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastDisconnectedAt = time.Now()
+	c.lastDisconnectReason = reason
+}
+
+func (c *Client) recordError(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastError = err.Error()
+}
+
+func (c *Client) recordReconnectFailure(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reconnectAttempts++
+	c.lastError = err.Error()
+}
+
+// beginReconnect claims the reconnect loop; false if one is running or reconnecting makes no sense
+func (c *Client) beginReconnect() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.reconnecting || c.stopped || c.WAClient.Store.ID == nil {
+		return false
+	}
+	c.reconnecting = true
+	return true
+}
+
+func (c *Client) endReconnect() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reconnecting = false
+	c.nextReconnectAt = time.Time{}
+}
+
+// nextReconnectDelay returns the delay before the next attempt and doubles it for the one after
+func (c *Client) nextReconnectDelay() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.reconnectDelay < minReconnectDelay {
+		c.reconnectDelay = minReconnectDelay
+	}
+	delay := c.reconnectDelay
+	c.reconnectDelay *= 2
+	if c.reconnectDelay > maxReconnectDelay {
+		c.reconnectDelay = maxReconnectDelay
+	}
+	c.nextReconnectAt = time.Now().Add(delay)
+	return delay
+}
+
+// setStopped marks a client as intentionally offline, which stops the reconnect loop
+func (c *Client) setStopped(stopped bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stopped = stopped
+}
+
+func (c *Client) isStopped() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.stopped
+}
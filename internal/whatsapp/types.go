@@ -2,6 +2,7 @@ package whatsapp
 
 import (
 	"encoding/base64"
+	"encoding/json"
 
 	waProto "go.mau.fi/whatsmeow/proto/waE2E"
 )
@@ -20,6 +21,13 @@ type QRImageEvent struct {
 	URL    string `json:"url"`
 }
 
+// PairCodeEvent represents a phone-number pairing code to be entered on the phone
+type PairCodeEvent struct {
+	Client string `json:"client"`
+	Phone  string `json:"phone"`
+	Code   string `json:"code"`
+}
+
 // NewMessageEvent represents an incoming message event
 type NewMessageEvent struct {
 	Client    string `json:"client"`
@@ -35,6 +43,33 @@ type NewMessageEvent struct {
 	Type      string `json:"type"`
 }
 
+// MessageAckEvent represents a delivery/read receipt for messages we sent
+type MessageAckEvent struct {
+	Client    string   `json:"client"`
+	ChatID    string   `json:"chatId"`
+	IDs       []string `json:"ids"`
+	Ack       int      `json:"ack"`
+	Timestamp int64    `json:"timestamp"`
+}
+
+// ChatUpdateEvent reports changed fields of a chat (e.g. tags)
+type ChatUpdateEvent struct {
+	Client string
+	ID     string // chat JID
+	Fields map[string]interface{}
+}
+
+// MarshalJSON puts the changed fields next to client and id, like the other chat-update payloads
+func (e ChatUpdateEvent) MarshalJSON() ([]byte, error) {
+	out := make(map[string]interface{}, len(e.Fields)+2)
+	for k, v := range e.Fields {
+		out[k] = v
+	}
+	out["client"] = e.Client
+	out["id"] = e.ID
+	return json.Marshal(out)
+}
+
 // Helper function to encode bytes to base64
 func encodeBase64(data []byte) string {
 	return base64.StdEncoding.EncodeToString(data)